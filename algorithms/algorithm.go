@@ -23,4 +23,7 @@ type Workload struct {
 
 	// Memory represents Workloads' necessary Memory resources Nodes must at least have available
 	Memory int64
+
+	// Priority orders pending workloads in the scheduling queue, higher priorities are scheduled first
+	Priority int32
 }
@@ -0,0 +1,114 @@
+package algorithms
+
+import (
+	"github.com/geolocate-orchestration/gountries"
+	"github.com/geolocate-orchestration/scheduler/labels"
+	"github.com/geolocate-orchestration/scheduler/locations"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+	"k8s.io/klog/v2"
+	"strings"
+)
+
+// LocationCoordinates returns the centroid of a country or city, or the coordinates of a catalog site,
+// sites without coordinates are located at the centroid of their country
+func LocationCoordinates(catalog *locations.Catalog, name string) (locations.Coordinates, bool) {
+	name = strings.TrimSpace(name)
+
+	if coordinates, ok := countryCoordinates(name); ok {
+		return coordinates, true
+	}
+
+	if coordinates, ok := cityCoordinates(name); ok {
+		return coordinates, true
+	}
+
+	return siteCoordinates(catalog, name)
+}
+
+// NodeCoordinates returns the node latitude and longitude labels,
+// or the centroid of the node city or country, or its site coordinates, when they are not set
+func NodeCoordinates(catalog *locations.Catalog, node *nodes.Node) (locations.Coordinates, bool) {
+	latitude, longitude := node.Labels[labels.NodeLatitude], node.Labels[labels.NodeLongitude]
+
+	if latitude != "" || longitude != "" {
+		coordinates, err := locations.ParseLatLong(latitude, longitude)
+		if err == nil {
+			return coordinates, true
+		}
+
+		klog.Errorf("node %s coordinates labels ignored: %s\n", node.Name, err)
+	}
+
+	if coordinates, ok := cityCoordinates(node.Labels[labels.NodeCity]); ok {
+		return coordinates, true
+	}
+
+	if coordinates, ok := siteCoordinates(catalog, node.Labels[labels.NodeCity]); ok {
+		return coordinates, true
+	}
+
+	if coordinates, ok := countryCoordinates(node.Labels[labels.NodeCountry]); ok {
+		return coordinates, true
+	}
+
+	return siteCoordinates(catalog, node.Labels[labels.NodeCountry])
+}
+
+func cityCoordinates(cityName string) (locations.Coordinates, bool) {
+	if cityName == "" {
+		return locations.Coordinates{}, false
+	}
+
+	city, err := gountries.New().FindSubdivisionByName(cityName)
+	if err != nil || !hasCoordinates(city.Coordinates) {
+		return locations.Coordinates{}, false
+	}
+
+	return toCoordinates(city.Coordinates), true
+}
+
+func countryCoordinates(countryID string) (locations.Coordinates, bool) {
+	if countryID == "" {
+		return locations.Coordinates{}, false
+	}
+
+	query := gountries.New()
+
+	country, err := query.FindCountryByName(countryID)
+	if err != nil {
+		country, err = query.FindCountryByAlpha(countryID)
+	}
+
+	if err != nil || !hasCoordinates(country.Coordinates) {
+		return locations.Coordinates{}, false
+	}
+
+	return toCoordinates(country.Coordinates), true
+}
+
+// siteCoordinates returns the coordinates of a catalog site, or the centroid of its country when it has none
+func siteCoordinates(catalog *locations.Catalog, name string) (locations.Coordinates, bool) {
+	if catalog == nil || name == "" {
+		return locations.Coordinates{}, false
+	}
+
+	site, ok := catalog.Find(name)
+	if !ok {
+		return locations.Coordinates{}, false
+	}
+
+	if coordinates, ok := site.Coordinates(); ok {
+		return coordinates, true
+	}
+
+	return countryCoordinates(site.Country)
+}
+
+// hasCoordinates reports whether gountries knows the coordinates of a location, missing ones are zero
+func hasCoordinates(coordinates gountries.Coordinates) bool {
+	return coordinates.Latitude != 0 || coordinates.Longitude != 0
+}
+
+func toCoordinates(coordinates gountries.Coordinates) locations.Coordinates {
+	return locations.Coordinates{Latitude: coordinates.Latitude, Longitude: coordinates.Longitude}
+}
@@ -0,0 +1,144 @@
+package algorithms
+
+import (
+	"github.com/geolocate-orchestration/scheduler/nodes"
+)
+
+// Level identifies the location fallback level an algorithm used to find candidate nodes
+type Level string
+
+const (
+	// LevelAny is used when the workload has no location constraints
+	LevelAny Level = "any"
+
+	// LevelCity matches nodes in the requested cities
+	LevelCity Level = "city"
+
+	// LevelCountry matches nodes in the requested countries
+	LevelCountry Level = "country"
+
+	// LevelContinent matches nodes in the requested continents
+	LevelContinent Level = "continent"
+
+	// LevelExpression matches nodes satisfying a boolean location expression
+	LevelExpression Level = "expression"
+
+	// LevelSimilar matches nodes in the countries and continents containing the requested locations
+	LevelSimilar Level = "similar"
+
+	// LevelDistance matches nodes ranked by their distance to the workload origin
+	LevelDistance Level = "distance"
+
+	// LevelLatency matches nodes ranked by their expected latency from the workload client region
+	LevelLatency Level = "latency"
+
+	// LevelRandom matches any node after all location levels failed
+	LevelRandom Level = "random"
+)
+
+// Explainer is implemented by algorithms able to describe how a node was selected
+type Explainer interface {
+	// GetNodeWithDecision works as GetNode but also returns the decision record, even on error
+	GetNodeWithDecision(workload *Workload) (*nodes.Node, *Decision, error)
+}
+
+// Decision records how an algorithm selected, or failed to select, a node for a workload
+type Decision struct {
+	// Algorithm is the name of the algorithm that took the decision
+	Algorithm string
+
+	// Node is the name of the selected node, empty on failure
+	Node string
+
+	// Level is the fallback level the selected node matched
+	Level Level
+
+	// Steps lists every level tried, in order
+	Steps []Step
+
+	// Rejected lists nodes matching a tried level but lacking resources for the workload
+	Rejected []RejectedNode
+
+	// Reason explains the outcome in human readable form
+	Reason string
+
+	// Unknown lists the location names that could not be resolved and were ignored, the ones preventing
+	// a required location from being satisfied are returned in a RequiredLocationError instead
+	Unknown []string
+}
+
+// Step records the candidates found at one fallback level
+type Step struct {
+	// Level is the fallback level tried
+	Level Level
+
+	// Candidates is the number of nodes matching the level
+	Candidates int
+
+	// Feasible is the number of candidates with enough resources for the workload
+	Feasible int
+}
+
+// RejectedNode is a node matching a fallback level that could not fit the workload
+type RejectedNode struct {
+	// Node is the rejected node name
+	Node string
+
+	// Level is the fallback level the node matched
+	Level Level
+
+	// Reasons lists the missing resources, "cpu" and/or "memory", or "forbidden" for nodes in forbidden locations,
+	// or the reasons given by a framework filter plugin
+	Reasons []string
+}
+
+// ReasonForbidden is the RejectedNode reason of nodes in the workload forbidden locations
+const ReasonForbidden = "forbidden"
+
+// NewDecision creates an empty decision record for the given algorithm
+func NewDecision(algorithm string) *Decision {
+	return &Decision{
+		Algorithm: algorithm,
+		Steps:     make([]Step, 0),
+		Rejected:  make([]RejectedNode, 0),
+		Unknown:   make([]string, 0),
+	}
+}
+
+// Reject records a node matching the given level that can't run the workload and why
+func (d *Decision) Reject(node *nodes.Node, level Level, reasons []string) {
+	d.Rejected = append(d.Rejected, RejectedNode{Node: node.Name, Level: level, Reasons: reasons})
+}
+
+// RecordStep records the number of candidates found at the given level and how many of them were feasible
+func (d *Decision) RecordStep(level Level, candidates int, feasible int) {
+	d.Steps = append(d.Steps, Step{Level: level, Candidates: candidates, Feasible: feasible})
+}
+
+// AddUnknown records location names that could not be resolved, once each
+func (d *Decision) AddUnknown(names []string) {
+	for _, name := range names {
+		known := false
+		for _, unknown := range d.Unknown {
+			known = known || unknown == name
+		}
+
+		if !known {
+			d.Unknown = append(d.Unknown, name)
+		}
+	}
+}
+
+// Select records the selected node and the reason it was selected
+func (d *Decision) Select(node *nodes.Node, level Level, reason string) {
+	d.Node = node.Name
+	d.Level = level
+	d.Reason = reason
+}
+
+// Fail records the reason no node was selected
+func (d *Decision) Fail(err error) {
+	d.Node = ""
+	d.Level = ""
+	d.Reason = err.Error()
+}
@@ -0,0 +1,35 @@
+package algorithms
+
+import (
+	"errors"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDecisionRecordStep(t *testing.T) {
+	decision := NewDecision("test")
+	decision.Reject(&nodes.Node{Name: "Node1"}, LevelCountry, []string{"cpu"})
+	decision.Reject(&nodes.Node{Name: "Node2"}, LevelCountry, []string{"cpu", "memory"})
+	decision.RecordStep(LevelCountry, 3, 1)
+
+	assert.Equal(t, []Step{{Level: LevelCountry, Candidates: 3, Feasible: 1}}, decision.Steps)
+	assert.Equal(t, []RejectedNode{
+		{Node: "Node1", Level: LevelCountry, Reasons: []string{"cpu"}},
+		{Node: "Node2", Level: LevelCountry, Reasons: []string{"cpu", "memory"}},
+	}, decision.Rejected)
+}
+
+func TestDecisionSelectAndFail(t *testing.T) {
+	decision := NewDecision("test")
+
+	decision.Select(&nodes.Node{Name: "Node0"}, LevelCity, "matched")
+	assert.Equal(t, "Node0", decision.Node)
+	assert.Equal(t, LevelCity, decision.Level)
+	assert.Equal(t, "matched", decision.Reason)
+
+	decision.Fail(errors.New("failed"))
+	assert.Equal(t, "", decision.Node)
+	assert.Equal(t, Level(""), decision.Level)
+	assert.Equal(t, "failed", decision.Reason)
+}
@@ -0,0 +1,114 @@
+package distance
+
+import (
+	"fmt"
+	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/algorithms/framework"
+	"github.com/geolocate-orchestration/scheduler/labels"
+	"github.com/geolocate-orchestration/scheduler/locations"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+	"k8s.io/klog/v2"
+	"strings"
+)
+
+// Name is the name under which the distance algorithm is registered
+const Name = "distance"
+
+func init() {
+	algorithms.MustRegister(Name, func(inodes nodes.INodes, _ algorithms.Options) (algorithms.Algorithm, error) {
+		return New(inodes), nil
+	})
+}
+
+// New creates new distance algorithm
+func New(inodes nodes.INodes) algorithms.Algorithm {
+	return framework.New(inodes, Profile())
+}
+
+// Profile returns the plugins of the distance algorithm, it selects the feasible node nearest to the workload
+// origin label
+func Profile() framework.Profile {
+	return framework.Profile{
+		Name:       Name,
+		PreFilter:  []framework.PreFilterPlugin{framework.ForbiddenLocation{}},
+		Filter:     []framework.FilterPlugin{framework.ForbiddenLocation{}, framework.NodeResources{}},
+		Candidates: Plugin{},
+		Score:      []framework.WeightedScorePlugin{{Plugin: framework.LowestCost{}, Weight: 1}},
+	}
+}
+
+// Plugin finds the nodes with coordinates and costs them by their distance to the workload origin,
+// nodes without coordinates are only candidates when no located node is feasible
+type Plugin struct{}
+
+// Name returns the plugin name
+func (Plugin) Name() string {
+	return "Distance"
+}
+
+// Candidates returns the located nodes, or the unlocated ones, it fails if the origin can't be resolved
+func (Plugin) Candidates(state *framework.CycleState, filter framework.FilterFunc) (*framework.Tier, error) {
+	label := state.Label(labels.WorkloadOrigin)
+	if label == "" {
+		return &framework.Tier{
+			Level:  algorithms.LevelAny,
+			Nodes:  filter(algorithms.LevelAny, state.Lister.GetAllNodes()),
+			Reason: "workload has no origin, selected a random node",
+		}, nil
+	}
+
+	catalog := state.Lister.GetCatalog()
+
+	origin, err := getOrigin(catalog, label)
+	if err != nil {
+		return nil, err
+	}
+
+	klog.Infof("looking for the node nearest to %s\n", origin)
+
+	located := make([]*nodes.Node, 0)
+	unlocated := make([]*nodes.Node, 0)
+	distances := make(map[string]float64)
+
+	for _, node := range state.Lister.GetAllNodes() {
+		if coordinates, ok := algorithms.NodeCoordinates(catalog, node); ok {
+			located = append(located, node)
+			distances[node.Name] = origin.DistanceTo(coordinates)
+		} else {
+			unlocated = append(unlocated, node)
+		}
+	}
+
+	if options := filter(algorithms.LevelDistance, located); len(options) > 0 {
+		return &framework.Tier{
+			Level: algorithms.LevelDistance,
+			Nodes: options,
+			Costs: distances,
+			Explain: func(node *nodes.Node) string {
+				return fmt.Sprintf("selected the nearest node, %.1f km from the workload origin", distances[node.Name])
+			},
+		}, nil
+	}
+
+	// nodes without coordinates can't be ranked so they are only used when no located node fits the workload
+	return &framework.Tier{
+		Level:  algorithms.LevelRandom,
+		Nodes:  filter(algorithms.LevelRandom, unlocated),
+		Reason: "no node with coordinates fits the workload, selected a random node",
+	}, nil
+}
+
+// getOrigin resolves the workload origin label, written as coordinates or as a city, country or site name
+func getOrigin(catalog *locations.Catalog, label string) (locations.Coordinates, error) {
+	if locations.IsCoordinates(label) {
+		return locations.ParseCoordinates(label)
+	}
+
+	name := strings.TrimSpace(label)
+
+	if coordinates, ok := algorithms.LocationCoordinates(catalog, name); ok {
+		return coordinates, nil
+	}
+
+	return locations.Coordinates{}, &locations.UnknownLocationError{Names: []string{name}}
+}
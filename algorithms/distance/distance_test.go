@@ -0,0 +1,159 @@
+package distance
+
+import (
+	"errors"
+	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/labels"
+	"github.com/geolocate-orchestration/scheduler/locations"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newTestNode(name string, nodeLabels map[string]string) *nodes.Node {
+	nodeLabels[labels.Node] = ""
+
+	return &nodes.Node{
+		Name:   name,
+		Labels: nodeLabels,
+		CPU:    20000,
+		Memory: 20000,
+	}
+}
+
+func newTestNodes() nodes.INodes {
+	inodes := nodes.New()
+	inodes.AddNode(newTestNode("Braga", map[string]string{labels.NodeLatitude: "41.5454", labels.NodeLongitude: "-8.4265"}))
+	inodes.AddNode(newTestNode("Madrid", map[string]string{labels.NodeCity: "Madrid", labels.NodeCountry: "Spain"}))
+	inodes.AddNode(newTestNode("Paris", map[string]string{labels.NodeCountry: "France"}))
+	return inodes
+}
+
+func newTestWorkload(origin string) *algorithms.Workload {
+	return &algorithms.Workload{
+		Labels: map[string]string{labels.WorkloadOrigin: origin},
+		CPU:    10000,
+		Memory: 10000,
+	}
+}
+
+func TestGetName(t *testing.T) {
+	assert.Equal(t, "distance", New(nodes.New()).GetName())
+}
+
+func TestGetNodeEmpty(t *testing.T) {
+	_, err := New(nodes.New()).GetNode(newTestWorkload("Porto"))
+	assert.True(t, errors.Is(err, algorithms.ErrNoNodes))
+}
+
+func TestGetNodeNearest(t *testing.T) {
+	tests := map[string]string{
+		"41.15,-8.61": "Braga",
+		"40.4, -3.7":  "Madrid",
+		"Porto":       "Braga",
+		"Lisboa":      "Braga",
+		"Barcelona":   "Madrid",
+		"Spain":       "Madrid",
+		"DE":          "Paris",
+		"51.5,-0.12":  "Paris",
+	}
+
+	for origin, expected := range tests {
+		node, decision, err := New(newTestNodes()).(algorithms.Explainer).GetNodeWithDecision(newTestWorkload(origin))
+		assert.NoError(t, err, origin)
+		assert.Equal(t, expected, node.Name, origin)
+		assert.Equal(t, algorithms.LevelDistance, decision.Level, origin)
+	}
+}
+
+func TestGetNodeNearestFeasible(t *testing.T) {
+	inodes := newTestNodes()
+	assert.NoError(t, inodes.AssumeWorkload("Existing", "Braga", nodes.Resources{CPU: 15000}))
+
+	node, decision, err := New(inodes).(algorithms.Explainer).GetNodeWithDecision(newTestWorkload("Porto"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Madrid", node.Name)
+	assert.Equal(t, []algorithms.RejectedNode{
+		{Node: "Braga", Level: algorithms.LevelDistance, Reasons: []string{"cpu"}},
+	}, decision.Rejected)
+}
+
+func TestGetNodeForbidden(t *testing.T) {
+	workload := newTestWorkload("Madrid")
+	workload.Labels[labels.WorkloadForbiddenLocation] = "-ES-"
+
+	node, err := New(newTestNodes()).GetNode(workload)
+	assert.NoError(t, err)
+	assert.Equal(t, "Braga", node.Name)
+}
+
+func TestGetNodeWithoutCoordinates(t *testing.T) {
+	inodes := nodes.New()
+	inodes.AddNode(newTestNode("Unlocated", map[string]string{labels.NodeContinent: "Europe"}))
+	inodes.AddNode(newTestNode("Invalid", map[string]string{labels.NodeLatitude: "91", labels.NodeLongitude: "0"}))
+
+	node, decision, err := New(inodes).(algorithms.Explainer).GetNodeWithDecision(newTestWorkload("Porto"))
+	assert.NoError(t, err)
+	assert.Contains(t, []string{"Unlocated", "Invalid"}, node.Name)
+	assert.Equal(t, algorithms.LevelRandom, decision.Level)
+}
+
+func TestGetNodeWithoutOrigin(t *testing.T) {
+	_, decision, err := New(newTestNodes()).(algorithms.Explainer).GetNodeWithDecision(&algorithms.Workload{})
+	assert.NoError(t, err)
+	assert.Equal(t, algorithms.LevelAny, decision.Level)
+}
+
+func TestGetNodeInvalidOrigin(t *testing.T) {
+	_, err := New(newTestNodes()).GetNode(newTestWorkload("41.15,"))
+	assert.True(t, errors.Is(err, algorithms.ErrInvalidLocation))
+
+	_, err = New(newTestNodes()).GetNode(newTestWorkload("91,0"))
+	assert.True(t, errors.Is(err, algorithms.ErrInvalidLocation))
+
+	_, err = New(newTestNodes()).GetNode(newTestWorkload("Atlantis"))
+	assert.True(t, errors.Is(err, algorithms.ErrUnknownLocation))
+
+	var unknownErr *locations.UnknownLocationError
+	assert.True(t, errors.As(err, &unknownErr))
+	assert.Equal(t, []string{"Atlantis"}, unknownErr.Names)
+}
+
+func TestGetNodeCatalogSites(t *testing.T) {
+	catalog := locations.NewCatalog()
+	latitude, longitude := 48.85, 2.35
+	catalog.MustRegister(locations.Site{Name: "edge-site-42", Country: "France", Latitude: &latitude, Longitude: &longitude})
+	catalog.MustRegister(locations.Site{Name: "ship-1", Country: "Spain"})
+
+	inodes := nodes.NewWithCatalog(nodes.DefaultTopology(), catalog)
+	inodes.AddNode(newTestNode("Braga", map[string]string{labels.NodeLatitude: "41.5454", labels.NodeLongitude: "-8.4265"}))
+	inodes.AddNode(newTestNode("Edge", map[string]string{labels.NodeCity: "edge-site-42", labels.NodeCountry: "Portugal"}))
+
+	// site coordinates are used for both the origin and the nodes
+	node, err := New(inodes).GetNode(newTestWorkload("edge-site-42"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Edge", node.Name)
+
+	// sites without coordinates are located at their country centroid
+	node, err = New(inodes).GetNode(newTestWorkload("ship-1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Braga", node.Name)
+}
+
+func TestGetRankedNodes(t *testing.T) {
+	inodes := newTestNodes()
+	inodes.AddNode(newTestNode("Unlocated", map[string]string{}))
+
+	ranked, err := New(inodes).(algorithms.Ranker).GetRankedNodes(newTestWorkload("Porto"), 4)
+	assert.NoError(t, err)
+
+	names := make([]string, 0)
+	for _, node := range ranked {
+		names = append(names, node.Node.Name)
+	}
+
+	assert.Equal(t, []string{"Braga", "Madrid", "Paris", "Unlocated"}, names)
+	assert.Equal(t, algorithms.LevelDistance, ranked[2].Level)
+	assert.Equal(t, algorithms.LevelRandom, ranked[3].Level)
+	assert.True(t, ranked[0].Score > ranked[1].Score)
+}
@@ -0,0 +1,158 @@
+package algorithms
+
+import (
+	"errors"
+	"fmt"
+	"github.com/geolocate-orchestration/scheduler/locations"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+)
+
+// ErrNoNodes is returned when the nodes cache is empty
+var ErrNoNodes = nodes.ErrNoNodes
+
+// ErrInsufficientResources is returned when nodes exist but none has enough resources for the workload
+var ErrInsufficientResources = nodes.ErrInsufficientResources
+
+// ErrForbiddenLocation is returned when every node left is in the workload forbidden locations
+var ErrForbiddenLocation = errors.New("all available nodes are in forbidden locations")
+
+// ErrMaxLatencyUnsatisfied is returned when no node is known to be within the workload maximum latency
+var ErrMaxLatencyUnsatisfied = errors.New("no nodes satisfy the maximum latency")
+
+// ErrRequiredLocationUnsatisfied is returned when no node matches the workload required locations
+var ErrRequiredLocationUnsatisfied = errors.New("no nodes match given locations")
+
+// ErrInvalidLocation is returned when a workload location label is malformed, see locations.ParseError
+var ErrInvalidLocation = locations.ErrInvalidLocation
+
+// ErrUnknownLocation is returned when workload location names can't be resolved
+var ErrUnknownLocation = locations.ErrUnknownLocation
+
+// UnknownLocationError lists the workload location names that could not be resolved
+type UnknownLocationError = locations.UnknownLocationError
+
+// InsufficientResourcesError lists the nodes rejected for lacking resources for the workload
+// It matches ErrInsufficientResources with errors.Is
+type InsufficientResourcesError struct {
+	// Rejected lists the nodes that could not fit the workload
+	Rejected []RejectedNode
+}
+
+func (e *InsufficientResourcesError) Error() string {
+	return fmt.Sprintf("%s: %d nodes rejected", ErrInsufficientResources, len(e.Rejected))
+}
+
+// Is reports whether target is ErrInsufficientResources
+func (e *InsufficientResourcesError) Is(target error) bool {
+	return target == ErrInsufficientResources
+}
+
+// RequiredLocationError is returned when no node satisfies the workload required locations
+// It matches ErrRequiredLocationUnsatisfied with errors.Is, and its causes with errors.Is and errors.As
+type RequiredLocationError struct {
+	// Locations is the required locations label value
+	Locations string
+
+	// Unknown is set when some of the required locations could not be resolved
+	Unknown *UnknownLocationError
+
+	// Resources is set when nodes matched the required locations but lacked resources
+	Resources *InsufficientResourcesError
+}
+
+func (e *RequiredLocationError) Error() string {
+	message := fmt.Sprintf("%s: %q", ErrRequiredLocationUnsatisfied, e.Locations)
+
+	for _, cause := range e.causes() {
+		message += "; " + cause.Error()
+	}
+
+	return message
+}
+
+// Is reports whether target is ErrRequiredLocationUnsatisfied or matches any of the causes
+func (e *RequiredLocationError) Is(target error) bool {
+	if target == ErrRequiredLocationUnsatisfied {
+		return true
+	}
+
+	for _, cause := range e.causes() {
+		if errors.Is(cause, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// As finds the first cause matching target
+func (e *RequiredLocationError) As(target interface{}) bool {
+	for _, cause := range e.causes() {
+		if errors.As(cause, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *RequiredLocationError) causes() []error {
+	causes := make([]error, 0, 2)
+
+	if e.Unknown != nil {
+		causes = append(causes, e.Unknown)
+	}
+
+	if e.Resources != nil {
+		causes = append(causes, e.Resources)
+	}
+
+	return causes
+}
+
+// NewRequiredLocationError creates a RequiredLocationError with the causes found while taking the decision
+func NewRequiredLocationError(locations string, unknown []string, decision *Decision) *RequiredLocationError {
+	err := &RequiredLocationError{Locations: locations}
+
+	if len(unknown) > 0 {
+		err.Unknown = &UnknownLocationError{Names: unknown}
+	}
+
+	if decision != nil && len(resourceRejections(decision)) > 0 {
+		err.Resources = NewInsufficientResourcesError(decision)
+	}
+
+	return err
+}
+
+// NewInsufficientResourcesError creates an InsufficientResourcesError with the nodes rejected in the decision
+// for lacking resources
+func NewInsufficientResourcesError(decision *Decision) *InsufficientResourcesError {
+	return &InsufficientResourcesError{Rejected: resourceRejections(decision)}
+}
+
+// NewNoFeasibleNodeError explains why no node was left after all steps of the decision
+// It is an InsufficientResourcesError if any node lacked resources, ErrForbiddenLocation otherwise
+func NewNoFeasibleNodeError(decision *Decision) error {
+	if len(resourceRejections(decision)) > 0 {
+		return NewInsufficientResourcesError(decision)
+	}
+
+	if len(decision.Rejected) > 0 {
+		return ErrForbiddenLocation
+	}
+
+	return ErrNoNodes
+}
+
+func resourceRejections(decision *Decision) []RejectedNode {
+	rejected := make([]RejectedNode, 0, len(decision.Rejected))
+
+	for _, rejection := range decision.Rejected {
+		if len(rejection.Reasons) > 0 && rejection.Reasons[0] != ReasonForbidden {
+			rejected = append(rejected, rejection)
+		}
+	}
+
+	return rejected
+}
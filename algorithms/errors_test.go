@@ -0,0 +1,62 @@
+package algorithms
+
+import (
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestUnknownLocationError(t *testing.T) {
+	var err error = &UnknownLocationError{Names: []string{"Bragaa", "Portugall"}}
+
+	assert.True(t, errors.Is(err, ErrUnknownLocation))
+	assert.False(t, errors.Is(err, ErrRequiredLocationUnsatisfied))
+	assert.Equal(t, "unknown location: Bragaa, Portugall", err.Error())
+}
+
+func TestInsufficientResourcesError(t *testing.T) {
+	decision := NewDecision("test")
+	decision.Rejected = append(decision.Rejected, RejectedNode{Node: "Node0", Reasons: []string{"cpu"}})
+
+	var err error = NewInsufficientResourcesError(decision)
+	decision.Rejected[0].Node = "Node1"
+
+	var resourcesErr *InsufficientResourcesError
+	assert.True(t, errors.Is(err, ErrInsufficientResources))
+	assert.True(t, errors.As(err, &resourcesErr))
+	assert.Equal(t, "Node0", resourcesErr.Rejected[0].Node)
+}
+
+func TestRequiredLocationError(t *testing.T) {
+	decision := NewDecision("test")
+	decision.Rejected = append(decision.Rejected, RejectedNode{Node: "Node0", Reasons: []string{"cpu"}})
+
+	err := fmt.Errorf("wrapped: %w", NewRequiredLocationError("Bragaa-PT-", []string{"Bragaa"}, decision))
+
+	var unknownErr *UnknownLocationError
+	var resourcesErr *InsufficientResourcesError
+	var locationErr *RequiredLocationError
+
+	assert.True(t, errors.Is(err, ErrRequiredLocationUnsatisfied))
+	assert.True(t, errors.Is(err, ErrUnknownLocation))
+	assert.True(t, errors.Is(err, ErrInsufficientResources))
+	assert.False(t, errors.Is(err, ErrNoNodes))
+
+	assert.True(t, errors.As(err, &unknownErr))
+	assert.Equal(t, []string{"Bragaa"}, unknownErr.Names)
+	assert.True(t, errors.As(err, &resourcesErr))
+	assert.True(t, errors.As(err, &locationErr))
+	assert.Equal(t, "Bragaa-PT-", locationErr.Locations)
+}
+
+func TestRequiredLocationErrorWithoutCauses(t *testing.T) {
+	err := NewRequiredLocationError("Braga-PT-", nil, NewDecision("test"))
+
+	var unknownErr *UnknownLocationError
+	assert.True(t, errors.Is(err, ErrRequiredLocationUnsatisfied))
+	assert.False(t, errors.Is(err, ErrUnknownLocation))
+	assert.False(t, errors.Is(err, ErrInsufficientResources))
+	assert.False(t, errors.As(err, &unknownErr))
+	assert.Equal(t, `no nodes match given locations: "Braga-PT-"`, err.Error())
+}
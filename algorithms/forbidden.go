@@ -0,0 +1,40 @@
+package algorithms
+
+import (
+	"github.com/geolocate-orchestration/scheduler/labels"
+	"github.com/geolocate-orchestration/scheduler/locations"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+)
+
+// ForbiddenNodes returns the names of the nodes located in the workload 'forbiddenLocation' label locations
+// It fails if the label is malformed or has unknown locations, as nodes in them couldn't be excluded
+func ForbiddenNodes(lister nodes.NodeLister, workload *Workload) (map[string]bool, error) {
+	forbidden := make(map[string]bool)
+
+	if workload == nil || workload.Labels[labels.WorkloadForbiddenLocation] == "" {
+		return forbidden, nil
+	}
+
+	expression, err := locations.ParseWithLevels(
+		workload.Labels[labels.WorkloadForbiddenLocation], lister.GetTopology().ExpressionLevels(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	condition := expression.AnyOf()
+	if condition == nil {
+		return forbidden, nil
+	}
+
+	matching, err := lister.GetNodesMatching(condition)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, node := range matching {
+		forbidden[node.Name] = true
+	}
+
+	return forbidden, nil
+}
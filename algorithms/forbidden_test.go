@@ -0,0 +1,68 @@
+package algorithms
+
+import (
+	"errors"
+	"github.com/geolocate-orchestration/scheduler/labels"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newTestForbiddenNodes() nodes.INodes {
+	inodes := nodes.New()
+
+	for _, country := range []string{"Portugal", "Spain"} {
+		inodes.AddNode(&nodes.Node{
+			Name:   "Node" + country,
+			Labels: map[string]string{labels.NodeCountry: country, labels.NodeContinent: "Europe"},
+		})
+	}
+
+	return inodes
+}
+
+func newTestForbiddenWorkload(value string) *Workload {
+	return &Workload{Labels: map[string]string{labels.WorkloadForbiddenLocation: value}}
+}
+
+func TestForbiddenNodes(t *testing.T) {
+	forbidden, err := ForbiddenNodes(newTestForbiddenNodes(), newTestForbiddenWorkload("-ES-"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"NodeSpain": true}, forbidden)
+
+	forbidden, err = ForbiddenNodes(newTestForbiddenNodes(), newTestForbiddenWorkload("continent = Europe and country != ES"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"NodePortugal": true}, forbidden)
+}
+
+func TestForbiddenNodesEmpty(t *testing.T) {
+	for _, workload := range []*Workload{nil, {}, newTestForbiddenWorkload("--")} {
+		forbidden, err := ForbiddenNodes(newTestForbiddenNodes(), workload)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, len(forbidden))
+	}
+}
+
+func TestForbiddenNodesErrors(t *testing.T) {
+	_, err := ForbiddenNodes(newTestForbiddenNodes(), newTestForbiddenWorkload("Spain"))
+	assert.True(t, errors.Is(err, ErrInvalidLocation))
+
+	_, err = ForbiddenNodes(newTestForbiddenNodes(), newTestForbiddenWorkload("-Spainn-"))
+	assert.True(t, errors.Is(err, ErrUnknownLocation))
+}
+
+func TestNoFeasibleNodeForbidden(t *testing.T) {
+	decision := NewDecision("test")
+	decision.Reject(&nodes.Node{Name: "NodePortugal"}, LevelAny, []string{ReasonForbidden})
+
+	assert.True(t, errors.Is(NewNoFeasibleNodeError(decision), ErrForbiddenLocation))
+	assert.Equal(t, 0, len(NewInsufficientResourcesError(decision).Rejected))
+}
+
+func TestNewNoFeasibleNodeError(t *testing.T) {
+	decision := NewDecision("test")
+	assert.True(t, errors.Is(NewNoFeasibleNodeError(decision), ErrNoNodes))
+
+	decision.Reject(&nodes.Node{Name: "Node0"}, LevelAny, []string{"cpu"})
+	assert.True(t, errors.Is(NewNoFeasibleNodeError(decision), ErrInsufficientResources))
+}
@@ -0,0 +1,63 @@
+package framework
+
+import (
+	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+)
+
+// CycleState holds the state of a single scheduling cycle, shared by the plugins it runs
+// A cycle runs on a single goroutine so it isn't safe for concurrent use
+type CycleState struct {
+	// Lister is the snapshot of the nodes cache the cycle works on
+	Lister nodes.NodeLister
+
+	// Workload is the workload being scheduled, it may be nil
+	Workload *algorithms.Workload
+
+	// Decision records how the node is selected
+	Decision *algorithms.Decision
+
+	// Tier is the tier of feasible nodes, set once the candidates plugin found it
+	Tier *Tier
+
+	data map[string]interface{}
+}
+
+// NewCycleState creates the state of a scheduling cycle
+func NewCycleState(lister nodes.NodeLister, workload *algorithms.Workload, decision *algorithms.Decision) *CycleState {
+	return &CycleState{
+		Lister:   lister,
+		Workload: workload,
+		Decision: decision,
+		data:     make(map[string]interface{}),
+	}
+}
+
+// Read returns the data a plugin wrote under the given key
+func (s *CycleState) Read(key string) (interface{}, bool) {
+	value, ok := s.data[key]
+	return value, ok
+}
+
+// Write stores plugin data under the given key, plugins use their name as key
+func (s *CycleState) Write(key string, value interface{}) {
+	s.data[key] = value
+}
+
+// Resources returns the resources requested by the workload
+func (s *CycleState) Resources() nodes.Resources {
+	if s.Workload == nil {
+		return nodes.Resources{}
+	}
+
+	return nodes.Resources{CPU: s.Workload.CPU, Memory: s.Workload.Memory}
+}
+
+// Label returns the value of a workload label, empty when the workload has none
+func (s *CycleState) Label(name string) string {
+	if s.Workload == nil {
+		return ""
+	}
+
+	return s.Workload.Labels[name]
+}
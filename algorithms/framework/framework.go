@@ -0,0 +1,258 @@
+package framework
+
+import (
+	"fmt"
+	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+	"math/rand"
+	"sort"
+)
+
+// Framework is an algorithm running the plugins of a profile
+// Each scheduling cycle runs PreFilter, then Candidates, filtering the nodes at every fallback level it tries,
+// then Score and NormalizeScore on the tier it found and finally Select
+type Framework struct {
+	nodes   nodes.INodes
+	profile Profile
+}
+
+// New creates an algorithm running the plugins of the given profile on the nodes cache
+func New(inodes nodes.INodes, profile Profile) *Framework {
+	if profile.Candidates == nil {
+		profile.Candidates = AllNodes{}
+	}
+
+	if profile.Select == nil {
+		profile.Select = HighestScore{}
+	}
+
+	return &Framework{
+		nodes:   inodes,
+		profile: profile,
+	}
+}
+
+// GetName returns the profile name
+func (f *Framework) GetName() string {
+	return f.profile.Name
+}
+
+// GetProfile returns the plugins the framework runs
+func (f *Framework) GetProfile() Profile {
+	return f.profile
+}
+
+// GetNode runs a scheduling cycle and returns the selected node
+func (f *Framework) GetNode(workload *algorithms.Workload) (*nodes.Node, error) {
+	node, _, err := f.GetNodeWithDecision(workload)
+	return node, err
+}
+
+// GetNodeWithDecision works as GetNode but also returns how the node was selected
+// Every call works on its own snapshot of the nodes cache
+func (f *Framework) GetNodeWithDecision(workload *algorithms.Workload) (*nodes.Node, *algorithms.Decision, error) {
+	state := NewCycleState(f.nodes.Snapshot(), workload, algorithms.NewDecision(f.profile.Name))
+
+	node, err := f.runCycle(state)
+	if err != nil {
+		state.Decision.Fail(err)
+		return nil, state.Decision, err
+	}
+
+	state.Decision.Select(node, state.Tier.Level, state.Tier.reason(node))
+	return node, state.Decision, nil
+}
+
+// GetRankedNodes returns up to n feasible nodes ordered from best to worst
+// Nodes of the tier a cycle finds are ordered by score, randomly among ties, then cycles are run again
+// without the ranked nodes to find the following tiers, until n nodes are ranked or no tier is left
+// The scores of every tier are raised above the ones of the following tiers, so they decrease along the ranking
+// It only fails if no node is feasible at all, the Select plugin is not run
+func (f *Framework) GetRankedNodes(workload *algorithms.Workload, n int) ([]algorithms.RankedNode, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("number of ranked nodes must be positive, got %d", n)
+	}
+
+	snapshot := f.nodes.Snapshot()
+	ranked := make([]algorithms.RankedNode, 0, n)
+	excluded := make(map[string]bool)
+	tiers := make([]int, 0)
+
+	for len(ranked) < n {
+		state := NewCycleState(snapshot, workload, algorithms.NewDecision(f.profile.Name))
+
+		scores, err := f.runScoring(state, excluded)
+		if err != nil {
+			if len(ranked) == 0 {
+				return nil, err
+			}
+
+			break
+		}
+
+		tiers = append(tiers, len(ranked))
+		for _, i := range rankOrder(scores) {
+			node := state.Tier.Nodes[i]
+			excluded[node.Name] = true
+
+			if len(ranked) < n {
+				ranked = append(ranked, algorithms.RankedNode{
+					Node:   node,
+					Score:  scores[i].Score,
+					Level:  state.Tier.Level,
+					Reason: state.Tier.reason(node),
+				})
+			}
+		}
+	}
+
+	stackTiers(ranked, tiers)
+	return ranked, nil
+}
+
+func (f *Framework) runCycle(state *CycleState) (*nodes.Node, error) {
+	scores, err := f.runScoring(state, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.profile.Select.Select(state, scores)
+}
+
+// runScoring runs every extension point up to NormalizeScore, the excluded nodes are never candidates
+func (f *Framework) runScoring(state *CycleState, excluded map[string]bool) (NodeScoreList, error) {
+	if state.Lister.CountNodes() == 0 {
+		return nil, algorithms.ErrNoNodes
+	}
+
+	for _, plugin := range f.profile.PreFilter {
+		if err := plugin.PreFilter(state); err != nil {
+			return nil, err
+		}
+	}
+
+	tier, err := f.profile.Candidates.Candidates(state, f.filterFunc(state, excluded))
+	if err != nil {
+		return nil, err
+	}
+
+	if tier == nil || len(tier.Nodes) == 0 {
+		return nil, algorithms.NewNoFeasibleNodeError(state.Decision)
+	}
+
+	state.Tier = tier
+	return f.score(state)
+}
+
+// filterFunc runs the filter plugins, the first one rejecting a node gives its rejection reasons
+// Excluded nodes are dropped from the candidates without being recorded
+func (f *Framework) filterFunc(state *CycleState, excluded map[string]bool) FilterFunc {
+	return func(level algorithms.Level, candidates []*nodes.Node) []*nodes.Node {
+		feasible := make([]*nodes.Node, 0, len(candidates))
+		found := 0
+
+		for _, node := range candidates {
+			if excluded[node.Name] {
+				continue
+			}
+
+			found++
+			if reasons := f.filter(state, node); len(reasons) > 0 {
+				state.Decision.Reject(node, level, reasons)
+			} else {
+				feasible = append(feasible, node)
+			}
+		}
+
+		state.Decision.RecordStep(level, found, len(feasible))
+		return feasible
+	}
+}
+
+func (f *Framework) filter(state *CycleState, node *nodes.Node) []string {
+	for _, plugin := range f.profile.Filter {
+		if reasons := plugin.Filter(state, node); len(reasons) > 0 {
+			return reasons
+		}
+	}
+
+	return nil
+}
+
+// score sums the weighted normalized scores of every score plugin for each node of the tier
+func (f *Framework) score(state *CycleState) (NodeScoreList, error) {
+	total := make(NodeScoreList, len(state.Tier.Nodes))
+	for i, node := range state.Tier.Nodes {
+		total[i] = NodeScore{Name: node.Name}
+	}
+
+	for _, weighted := range f.profile.Score {
+		plugin := weighted.Plugin
+		scores := make(NodeScoreList, len(state.Tier.Nodes))
+
+		for i, node := range state.Tier.Nodes {
+			score, err := plugin.Score(state, node)
+			if err != nil {
+				return nil, fmt.Errorf("%s plugin failed scoring node %s: %w", plugin.Name(), node.Name, err)
+			}
+
+			scores[i] = NodeScore{Name: node.Name, Score: score}
+		}
+
+		if extensions := plugin.ScoreExtensions(); extensions != nil {
+			if err := extensions.NormalizeScore(state, scores); err != nil {
+				return nil, fmt.Errorf("%s plugin failed normalizing scores: %w", plugin.Name(), err)
+			}
+		}
+
+		for i := range scores {
+			if scores[i].Score < MinNodeScore || scores[i].Score > MaxNodeScore {
+				return nil, fmt.Errorf("%s plugin scored node %s %d, out of the %d..%d range",
+					plugin.Name(), scores[i].Name, scores[i].Score, MinNodeScore, MaxNodeScore)
+			}
+
+			total[i].Score += scores[i].Score * weighted.Weight
+		}
+	}
+
+	return total, nil
+}
+
+// reason describes why the given node of the tier was selected
+func (t *Tier) reason(node *nodes.Node) string {
+	if t.Explain != nil {
+		return t.Explain(node)
+	}
+
+	return t.Reason
+}
+
+// stackTiers raises the scores of the ranked nodes of each tier, starting at the given indexes, just above the highest
+// score of the following tiers, keeping the differences between nodes of the same tier
+func stackTiers(ranked []algorithms.RankedNode, tiers []int) {
+	ceiling := int64(MinNodeScore - 1)
+	end := len(ranked)
+
+	for t := len(tiers) - 1; t >= 0; t-- {
+		tier := ranked[tiers[t]:end]
+		if shift := ceiling + 1 - tier[len(tier)-1].Score; shift > 0 {
+			for i := range tier {
+				tier[i].Score += shift
+			}
+		}
+
+		ceiling = tier[0].Score
+		end = tiers[t]
+	}
+}
+
+// rankOrder returns the indexes of the scores from the highest to the lowest, ties in random order
+func rankOrder(scores NodeScoreList) []int {
+	order := rand.Perm(len(scores))
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return scores[order[i]].Score > scores[order[j]].Score
+	})
+
+	return order
+}
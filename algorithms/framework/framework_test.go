@@ -0,0 +1,275 @@
+package framework
+
+import (
+	"errors"
+	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/labels"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newTestNodes() nodes.INodes {
+	inodes := nodes.New()
+
+	for _, node := range []struct{ name, country string }{{"Node0", "PT"}, {"Node1", "ES"}, {"Node2", "FR"}} {
+		inodes.AddNode(&nodes.Node{
+			Name:   node.name,
+			Labels: map[string]string{labels.NodeCountry: node.country},
+			CPU:    1000,
+			Memory: 1000,
+		})
+	}
+
+	return inodes
+}
+
+// scoreByName scores nodes by the given raw scores
+type scoreByName map[string]int64
+
+func (s scoreByName) Name() string {
+	return "ScoreByName"
+}
+
+func (s scoreByName) Score(_ *CycleState, node *nodes.Node) (int64, error) {
+	if score, ok := s[node.Name]; ok {
+		return score, nil
+	}
+
+	return 0, errors.New("unscored node")
+}
+
+func (s scoreByName) ScoreExtensions() ScoreExtensions {
+	return nil
+}
+
+// fixedTier returns the given nodes costed as given
+type fixedTier map[string]float64
+
+func (f fixedTier) Name() string {
+	return "FixedTier"
+}
+
+func (f fixedTier) Candidates(state *CycleState, filter FilterFunc) (*Tier, error) {
+	candidates := make([]*nodes.Node, 0)
+	for _, node := range state.Lister.GetAllNodes() {
+		if _, ok := f[node.Name]; ok {
+			candidates = append(candidates, node)
+		}
+	}
+
+	return &Tier{Level: algorithms.LevelDistance, Nodes: filter(algorithms.LevelDistance, candidates), Costs: f, Reason: "cheapest"}, nil
+}
+
+// tieredNodes returns the first tier with feasible nodes, the first one at city level and the following ones
+// at distance level, costed by their order in the tier
+type tieredNodes [][]string
+
+func (t tieredNodes) Name() string {
+	return "TieredNodes"
+}
+
+func (t tieredNodes) Candidates(state *CycleState, filter FilterFunc) (*Tier, error) {
+	level := algorithms.LevelCity
+
+	for _, names := range t {
+		costs := make(map[string]float64)
+		for i, name := range names {
+			costs[name] = float64(i)
+		}
+
+		candidates := make([]*nodes.Node, 0)
+		for _, node := range state.Lister.GetAllNodes() {
+			if _, ok := costs[node.Name]; ok {
+				candidates = append(candidates, node)
+			}
+		}
+
+		if feasible := filter(level, candidates); len(feasible) > 0 {
+			return &Tier{Level: level, Nodes: feasible, Costs: costs}, nil
+		}
+
+		level = algorithms.LevelDistance
+	}
+
+	return nil, nil
+}
+
+func TestDefaultProfile(t *testing.T) {
+	f := New(newTestNodes(), Profile{Name: "test"})
+	assert.Equal(t, "test", f.GetName())
+
+	node, decision, err := f.GetNodeWithDecision(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, node.Name, decision.Node)
+	assert.Equal(t, algorithms.LevelAny, decision.Level)
+	assert.Equal(t, "selected a random node", decision.Reason)
+	assert.Equal(t, []algorithms.Step{{Level: algorithms.LevelAny, Candidates: 3, Feasible: 3}}, decision.Steps)
+}
+
+func TestNoNodes(t *testing.T) {
+	_, err := New(nodes.New(), Profile{Name: "test"}).GetNode(nil)
+	assert.True(t, errors.Is(err, algorithms.ErrNoNodes))
+}
+
+func TestFilters(t *testing.T) {
+	f := New(newTestNodes(), Profile{
+		Name:      "test",
+		PreFilter: []PreFilterPlugin{ForbiddenLocation{}},
+		Filter:    []FilterPlugin{ForbiddenLocation{}, NodeResources{}},
+	})
+
+	workload := &algorithms.Workload{
+		Labels: map[string]string{labels.WorkloadForbiddenLocation: "-PT-"},
+		CPU:    2000,
+	}
+
+	_, decision, err := f.GetNodeWithDecision(workload)
+	assert.True(t, errors.Is(err, algorithms.ErrInsufficientResources))
+	assert.Equal(t, "", decision.Node)
+
+	// the first rejecting filter gives the reasons
+	assert.Equal(t, []algorithms.RejectedNode{
+		{Node: "Node0", Level: algorithms.LevelAny, Reasons: []string{algorithms.ReasonForbidden}},
+		{Node: "Node1", Level: algorithms.LevelAny, Reasons: []string{"cpu"}},
+		{Node: "Node2", Level: algorithms.LevelAny, Reasons: []string{"cpu"}},
+	}, decision.Rejected)
+
+	workload.CPU = 100
+	for i := 0; i < 10; i++ {
+		node, err := f.GetNode(workload)
+		assert.NoError(t, err)
+		assert.NotEqual(t, "Node0", node.Name)
+	}
+}
+
+func TestPreFilterError(t *testing.T) {
+	f := New(newTestNodes(), Profile{Name: "test", PreFilter: []PreFilterPlugin{ForbiddenLocation{}}})
+
+	_, err := f.GetNode(&algorithms.Workload{Labels: map[string]string{labels.WorkloadForbiddenLocation: "-PT"}})
+	assert.True(t, errors.Is(err, algorithms.ErrInvalidLocation))
+}
+
+func TestScore(t *testing.T) {
+	scores := scoreByName{"Node0": 10, "Node1": 30, "Node2": 20}
+	f := New(newTestNodes(), Profile{Name: "test", Score: []WeightedScorePlugin{{Plugin: scores, Weight: 1}}})
+
+	for i := 0; i < 10; i++ {
+		node, err := f.GetNode(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "Node1", node.Name)
+	}
+}
+
+func TestScoreWeights(t *testing.T) {
+	f := New(newTestNodes(), Profile{
+		Name:       "test",
+		Candidates: fixedTier{"Node0": 100, "Node1": 10, "Node2": 50},
+		Score: []WeightedScorePlugin{
+			{Plugin: LowestCost{}, Weight: 1},
+			{Plugin: scoreByName{"Node0": MaxNodeScore, "Node1": 0, "Node2": 0}, Weight: 2},
+		},
+	})
+
+	node, decision, err := f.GetNodeWithDecision(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Node0", node.Name)
+	assert.Equal(t, algorithms.LevelDistance, decision.Level)
+	assert.Equal(t, "cheapest", decision.Reason)
+}
+
+func TestScoreErrors(t *testing.T) {
+	f := New(newTestNodes(), Profile{Name: "test", Score: []WeightedScorePlugin{{Plugin: scoreByName{}, Weight: 1}}})
+	_, err := f.GetNode(nil)
+	assert.EqualError(t, err, "ScoreByName plugin failed scoring node Node0: unscored node")
+
+	f = New(newTestNodes(), Profile{Name: "test", Score: []WeightedScorePlugin{
+		{Plugin: scoreByName{"Node0": MaxNodeScore + 1, "Node1": 0, "Node2": 0}, Weight: 1},
+	}})
+	_, err = f.GetNode(nil)
+	assert.Error(t, err)
+}
+
+func TestLowestCost(t *testing.T) {
+	f := New(newTestNodes(), Profile{
+		Name:       "test",
+		Candidates: fixedTier{"Node0": 2.001, "Node1": 2.003},
+		Score:      []WeightedScorePlugin{{Plugin: LowestCost{}, Weight: 1}},
+	})
+
+	for i := 0; i < 10; i++ {
+		node, err := f.GetNode(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "Node0", node.Name)
+	}
+}
+
+func TestNormalizeMinMax(t *testing.T) {
+	scores := NodeScoreList{{"a", 10}, {"b", 20}, {"c", 30}, {"d", -1}}
+	NormalizeMinMax(scores, false)
+	assert.Equal(t, NodeScoreList{{"a", 0}, {"b", MaxNodeScore / 2}, {"c", MaxNodeScore}, {"d", 0}}, scores)
+
+	scores = NodeScoreList{{"a", 10}, {"b", 20}, {"c", 30}}
+	NormalizeMinMax(scores, true)
+	assert.Equal(t, NodeScoreList{{"a", MaxNodeScore}, {"b", MaxNodeScore / 2}, {"c", 0}}, scores)
+
+	scores = NodeScoreList{{"a", 5}, {"b", 5}}
+	NormalizeMinMax(scores, true)
+	assert.Equal(t, NodeScoreList{{"a", MaxNodeScore}, {"b", MaxNodeScore}}, scores)
+}
+
+func TestGetRankedNodes(t *testing.T) {
+	f := New(newTestNodes(), Profile{
+		Name:       "test",
+		Candidates: fixedTier{"Node0": 30, "Node1": 10, "Node2": 20},
+		Score:      []WeightedScorePlugin{{Plugin: LowestCost{}, Weight: 1}},
+	})
+
+	ranked, err := f.GetRankedNodes(nil, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(ranked))
+
+	names := make([]string, 0)
+	for _, node := range ranked {
+		names = append(names, node.Node.Name)
+		assert.Equal(t, algorithms.LevelDistance, node.Level)
+		assert.Equal(t, "cheapest", node.Reason)
+	}
+
+	assert.Equal(t, []string{"Node1", "Node2", "Node0"}, names)
+	assert.Equal(t, []int64{MaxNodeScore, MaxNodeScore / 2, 0}, []int64{ranked[0].Score, ranked[1].Score, ranked[2].Score})
+
+	ranked, err = f.GetRankedNodes(nil, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(ranked))
+	assert.Equal(t, "Node2", ranked[1].Node.Name)
+
+	_, err = f.GetRankedNodes(nil, 0)
+	assert.Error(t, err)
+
+	_, err = New(nodes.New(), Profile{Name: "test"}).GetRankedNodes(nil, 1)
+	assert.True(t, errors.Is(err, algorithms.ErrNoNodes))
+}
+
+func TestGetRankedNodesAcrossTiers(t *testing.T) {
+	f := New(newTestNodes(), Profile{
+		Name:       "test",
+		Candidates: tieredNodes{{"Node0", "Node1"}, {"Node2"}},
+		Score:      []WeightedScorePlugin{{Plugin: LowestCost{}, Weight: 1}},
+	})
+
+	ranked, err := f.GetRankedNodes(nil, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(ranked))
+	assert.Equal(t, "Node0", ranked[0].Node.Name)
+	assert.Equal(t, "Node1", ranked[1].Node.Name)
+	assert.Equal(t, algorithms.LevelDistance, ranked[2].Level)
+
+	// the city tier keeps its score differences, above the distance tier best score
+	assert.Equal(t, []int64{2*MaxNodeScore + 1, MaxNodeScore + 1, MaxNodeScore},
+		[]int64{ranked[0].Score, ranked[1].Score, ranked[2].Score})
+
+	ranked, err = f.GetRankedNodes(nil, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(MaxNodeScore), ranked[0].Score)
+}
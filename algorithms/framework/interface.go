@@ -0,0 +1,121 @@
+package framework
+
+import (
+	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+)
+
+// MaxNodeScore is the highest score a NormalizeScore extension gives a node
+// It is fine grained so ranking nodes by distance or latency stays exact to a few meters or microseconds
+const MaxNodeScore int64 = 1000000
+
+// MinNodeScore is the lowest score a NormalizeScore extension gives a node
+const MinNodeScore int64 = 0
+
+// Plugin is the parent type of every framework plugin
+type Plugin interface {
+	// Name identifies the plugin in logs and errors
+	Name() string
+}
+
+// PreFilterPlugin prepares the cycle state before any node is filtered, an error aborts the scheduling cycle
+type PreFilterPlugin interface {
+	Plugin
+	PreFilter(state *CycleState) error
+}
+
+// FilterPlugin rejects the nodes that can't run the workload
+type FilterPlugin interface {
+	Plugin
+	// Filter returns why the node can't run the workload, no reasons means it can
+	Filter(state *CycleState, node *nodes.Node) []string
+}
+
+// FilterFunc runs every filter plugin on the candidates found at a fallback level,
+// records them in the decision and returns the feasible ones
+type FilterFunc func(level algorithms.Level, candidates []*nodes.Node) []*nodes.Node
+
+// CandidatesPlugin walks the fallback levels of an algorithm, such as the locations containing the requested ones,
+// and returns the tier of feasible nodes the workload is placed in
+// The walk uses the given FilterFunc so filter plugins apply at every level, fallbacks included
+type CandidatesPlugin interface {
+	Plugin
+	Candidates(state *CycleState, filter FilterFunc) (*Tier, error)
+}
+
+// ScorePlugin ranks the nodes of the selected tier
+type ScorePlugin interface {
+	Plugin
+	// Score returns the raw score of a node, its ScoreExtensions map raw scores to MinNodeScore..MaxNodeScore
+	Score(state *CycleState, node *nodes.Node) (int64, error)
+
+	// ScoreExtensions returns the NormalizeScore extension of the plugin, nil if its scores are already normalized
+	ScoreExtensions() ScoreExtensions
+}
+
+// ScoreExtensions is implemented by score plugins whose raw scores need normalizing
+type ScoreExtensions interface {
+	// NormalizeScore maps the raw scores of the tier nodes to MinNodeScore..MaxNodeScore in place
+	NormalizeScore(state *CycleState, scores NodeScoreList) error
+}
+
+// WeightedScorePlugin is a score plugin and the weight its normalized scores are multiplied by
+type WeightedScorePlugin struct {
+	Plugin ScorePlugin
+	Weight int64
+}
+
+// SelectPlugin picks the node the workload is placed on from the scored tier
+type SelectPlugin interface {
+	Plugin
+	Select(state *CycleState, scores NodeScoreList) (*nodes.Node, error)
+}
+
+// NodeScore is the score of a node
+type NodeScore struct {
+	Name  string
+	Score int64
+}
+
+// NodeScoreList lists the scores of the nodes of a tier, in the tier order
+type NodeScoreList []NodeScore
+
+// Tier is the set of feasible nodes found at a fallback level
+type Tier struct {
+	// Level is the fallback level the nodes matched
+	Level algorithms.Level
+
+	// Nodes are the feasible nodes
+	Nodes []*nodes.Node
+
+	// Reason explains why a node of the tier is selected
+	Reason string
+
+	// Costs are optional costs of placing the workload on each node, such as a distance or a latency,
+	// LowestCost scores nodes by them
+	Costs map[string]float64
+
+	// Explain optionally describes why the given node was selected, overriding Reason
+	Explain func(node *nodes.Node) string
+}
+
+// Profile is the set of plugins an algorithm is made of
+type Profile struct {
+	// Name is the algorithm name recorded in decisions
+	Name string
+
+	// PreFilter plugins run in order before any node is filtered
+	PreFilter []PreFilterPlugin
+
+	// Filter plugins run in order on every candidate, the first one rejecting a node gives the rejection reasons
+	Filter []FilterPlugin
+
+	// Candidates finds the tier of feasible nodes, AllNodes when nil
+	Candidates CandidatesPlugin
+
+	// Score plugins rank the tier nodes, nodes are equally ranked when empty
+	Score []WeightedScorePlugin
+
+	// Select picks the node from the scored tier, HighestScore when nil
+	Select SelectPlugin
+}
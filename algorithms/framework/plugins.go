@@ -0,0 +1,159 @@
+package framework
+
+import (
+	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+	"math"
+)
+
+// ForbiddenLocation rejects the nodes in the workload 'forbiddenLocation' label locations, fallbacks included
+type ForbiddenLocation struct{}
+
+// Name returns the plugin name
+func (ForbiddenLocation) Name() string {
+	return "ForbiddenLocation"
+}
+
+// PreFilter finds the nodes in the forbidden locations, it fails if the label is malformed or has unknown locations
+func (p ForbiddenLocation) PreFilter(state *CycleState) error {
+	forbidden, err := algorithms.ForbiddenNodes(state.Lister, state.Workload)
+	if err != nil {
+		return err
+	}
+
+	state.Write(p.Name(), forbidden)
+	return nil
+}
+
+// Filter rejects the node if it is in a forbidden location
+func (p ForbiddenLocation) Filter(state *CycleState, node *nodes.Node) []string {
+	if forbidden, ok := state.Read(p.Name()); ok && forbidden.(map[string]bool)[node.Name] {
+		return []string{algorithms.ReasonForbidden}
+	}
+
+	return nil
+}
+
+// NodeResources rejects the nodes without enough allocatable CPU or memory for the workload
+type NodeResources struct{}
+
+// Name returns the plugin name
+func (NodeResources) Name() string {
+	return "NodeResources"
+}
+
+// Filter rejects the node with the missing resources, "cpu" and/or "memory"
+func (NodeResources) Filter(state *CycleState, node *nodes.Node) []string {
+	return nodes.InsufficientResources(node, state.Resources())
+}
+
+// AllNodes is the default candidates plugin, any feasible node is a candidate
+type AllNodes struct {
+	// Reason explains the selection, "selected a random node" when empty
+	Reason string
+}
+
+// Name returns the plugin name
+func (AllNodes) Name() string {
+	return "AllNodes"
+}
+
+// Candidates returns the feasible nodes of the cache at the any level
+func (p AllNodes) Candidates(state *CycleState, filter FilterFunc) (*Tier, error) {
+	reason := p.Reason
+	if reason == "" {
+		reason = "selected a random node"
+	}
+
+	return &Tier{
+		Level:  algorithms.LevelAny,
+		Nodes:  filter(algorithms.LevelAny, state.Lister.GetAllNodes()),
+		Reason: reason,
+	}, nil
+}
+
+// LowestCost scores the nodes of a tier with costs, such as distances or latencies, the cheapest highest
+// Tiers without costs score every node equally
+type LowestCost struct{}
+
+// Name returns the plugin name
+func (LowestCost) Name() string {
+	return "LowestCost"
+}
+
+// Score returns the node cost in thousandths, or -1 if the tier has no cost for it
+func (LowestCost) Score(state *CycleState, node *nodes.Node) (int64, error) {
+	cost, ok := state.Tier.Costs[node.Name]
+	if !ok {
+		return -1, nil
+	}
+
+	return int64(math.Round(cost * 1000)), nil
+}
+
+// ScoreExtensions returns the plugin itself as it normalizes its scores
+func (p LowestCost) ScoreExtensions() ScoreExtensions {
+	return p
+}
+
+// NormalizeScore gives the cheapest node MaxNodeScore and the most expensive one MinNodeScore,
+// nodes without a cost score MinNodeScore too
+func (LowestCost) NormalizeScore(_ *CycleState, scores NodeScoreList) error {
+	NormalizeMinMax(scores, true)
+	return nil
+}
+
+// HighestScore is the default select plugin, it selects the node with the highest score, randomly among ties
+type HighestScore struct{}
+
+// Name returns the plugin name
+func (HighestScore) Name() string {
+	return "HighestScore"
+}
+
+// Select returns a random node among the best scored ones
+func (HighestScore) Select(state *CycleState, scores NodeScoreList) (*nodes.Node, error) {
+	best := make([]*nodes.Node, 0)
+	top := int64(math.MinInt64)
+
+	for i, node := range state.Tier.Nodes {
+		if scores[i].Score > top {
+			top = scores[i].Score
+			best = []*nodes.Node{node}
+		} else if scores[i].Score == top {
+			best = append(best, node)
+		}
+	}
+
+	return nodes.GetRandomFromList(best)
+}
+
+// NormalizeMinMax scales the non negative scores to MinNodeScore..MaxNodeScore, the lowest to MinNodeScore,
+// or to MaxNodeScore when reverse, negative scores are unknown and set to MinNodeScore
+// Equal scores are all set to MaxNodeScore
+func NormalizeMinMax(scores NodeScoreList, reverse bool) {
+	lowest, highest := int64(math.MaxInt64), int64(-1)
+
+	for _, score := range scores {
+		if score.Score >= 0 && score.Score < lowest {
+			lowest = score.Score
+		}
+
+		if score.Score > highest {
+			highest = score.Score
+		}
+	}
+
+	for i := range scores {
+		switch {
+		case scores[i].Score < 0:
+			scores[i].Score = MinNodeScore
+		case highest == lowest:
+			scores[i].Score = MaxNodeScore
+		case reverse:
+			scores[i].Score = MaxNodeScore * (highest - scores[i].Score) / (highest - lowest)
+		default:
+			scores[i].Score = MaxNodeScore * (scores[i].Score - lowest) / (highest - lowest)
+		}
+	}
+}
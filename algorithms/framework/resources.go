@@ -0,0 +1,175 @@
+package framework
+
+import (
+	"fmt"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+	"math"
+)
+
+// ScoringStrategyType names how NodeResourcesScore ranks nodes by their utilization
+type ScoringStrategyType string
+
+const (
+	// MostAllocated favors the most utilized nodes, packing workloads onto fewer nodes so others can power down
+	MostAllocated ScoringStrategyType = "MostAllocated"
+
+	// LeastAllocated favors the least utilized nodes, spreading the load
+	LeastAllocated ScoringStrategyType = "LeastAllocated"
+
+	// RequestedToCapacityRatio scores nodes by a user supplied curve of their utilization
+	RequestedToCapacityRatio ScoringStrategyType = "RequestedToCapacityRatio"
+)
+
+// MaxShapeScore is the highest score of a RequestedToCapacityRatio curve point, it is scaled to MaxNodeScore
+const MaxShapeScore int64 = 100
+
+// UtilizationShapePoint is a point of a RequestedToCapacityRatio curve
+type UtilizationShapePoint struct {
+	// Utilization is the percentage of the node capacity requested once the workload is placed, 0 to 100
+	Utilization int64
+
+	// Score is the score of nodes at that utilization, 0 to MaxShapeScore
+	Score int64
+}
+
+// ScoringStrategy configures NodeResourcesScore
+type ScoringStrategy struct {
+	// Type is MostAllocated, LeastAllocated or RequestedToCapacityRatio
+	Type ScoringStrategyType
+
+	// CPUWeight and MemoryWeight weigh each resource score, both resources weigh 1 when both are zero
+	CPUWeight    int64
+	MemoryWeight int64
+
+	// Shape is the RequestedToCapacityRatio curve, points by increasing utilization,
+	// scores are interpolated between points and flat before the first and after the last one
+	Shape []UtilizationShapePoint
+}
+
+// Validate checks the strategy type, weights and curve
+func (s ScoringStrategy) Validate() error {
+	if s.CPUWeight < 0 || s.MemoryWeight < 0 {
+		return fmt.Errorf("%s scoring strategy weights must not be negative", s.Type)
+	}
+
+	switch s.Type {
+	case MostAllocated, LeastAllocated:
+		if len(s.Shape) > 0 {
+			return fmt.Errorf("%s scoring strategy takes no shape", s.Type)
+		}
+
+		return nil
+	case RequestedToCapacityRatio:
+		return validateShape(s.Shape)
+	default:
+		return fmt.Errorf("unknown scoring strategy %q, must be %q, %q or %q",
+			s.Type, MostAllocated, LeastAllocated, RequestedToCapacityRatio)
+	}
+}
+
+func validateShape(shape []UtilizationShapePoint) error {
+	if len(shape) == 0 {
+		return fmt.Errorf("%s scoring strategy needs at least one shape point", RequestedToCapacityRatio)
+	}
+
+	for i, point := range shape {
+		if point.Utilization < 0 || point.Utilization > 100 {
+			return fmt.Errorf("shape point utilization must be between 0 and 100, got %d", point.Utilization)
+		}
+
+		if point.Score < 0 || point.Score > MaxShapeScore {
+			return fmt.Errorf("shape point score must be between 0 and %d, got %d", MaxShapeScore, point.Score)
+		}
+
+		if i > 0 && point.Utilization <= shape[i-1].Utilization {
+			return fmt.Errorf("shape points must be sorted by strictly increasing utilization")
+		}
+	}
+
+	return nil
+}
+
+// NodeResourcesScore scores nodes by the CPU and memory they would have requested once the workload is placed
+// on them, against their capacity
+type NodeResourcesScore struct {
+	strategy ScoringStrategy
+}
+
+// NewNodeResourcesScore creates the plugin scoring nodes with the given strategy
+// An invalid strategy fails every cycle the plugin scores in, see ScoringStrategy.Validate
+func NewNodeResourcesScore(strategy ScoringStrategy) *NodeResourcesScore {
+	if strategy.CPUWeight == 0 && strategy.MemoryWeight == 0 {
+		strategy.CPUWeight, strategy.MemoryWeight = 1, 1
+	}
+
+	return &NodeResourcesScore{strategy: strategy}
+}
+
+// Name returns the plugin name
+func (p *NodeResourcesScore) Name() string {
+	return "NodeResourcesScore"
+}
+
+// Score returns the weighted average of the CPU and memory scores of the node
+func (p *NodeResourcesScore) Score(state *CycleState, node *nodes.Node) (int64, error) {
+	if err := p.strategy.Validate(); err != nil {
+		return 0, err
+	}
+
+	resources := state.Resources()
+	cpu := p.resourceScore(node.Requested.CPU+resources.CPU, node.CPU)
+	memory := p.resourceScore(node.Requested.Memory+resources.Memory, node.Memory)
+
+	weights := p.strategy.CPUWeight + p.strategy.MemoryWeight
+	return (cpu*p.strategy.CPUWeight + memory*p.strategy.MemoryWeight) / weights, nil
+}
+
+// ScoreExtensions returns nil as scores are already normalized
+func (p *NodeResourcesScore) ScoreExtensions() ScoreExtensions {
+	return nil
+}
+
+// resourceScore scores a resource of the node, nodes without capacity for it score MinNodeScore
+func (p *NodeResourcesScore) resourceScore(requested int64, capacity int64) int64 {
+	if capacity <= 0 {
+		return MinNodeScore
+	}
+
+	if requested > capacity {
+		requested = capacity
+	}
+
+	switch p.strategy.Type {
+	case MostAllocated:
+		return utilization(requested, capacity)
+	case LeastAllocated:
+		return MaxNodeScore - utilization(requested, capacity)
+	default:
+		return shapeScore(p.strategy.Shape, utilization(requested, capacity))
+	}
+}
+
+// utilization returns the requested fraction of the capacity in MinNodeScore..MaxNodeScore units
+// The ratio is taken in float64 as milli-unit memory times MaxNodeScore overflows int64
+func utilization(requested int64, capacity int64) int64 {
+	return int64(math.Round(float64(requested) / float64(capacity) * float64(MaxNodeScore)))
+}
+
+// shapeScore interpolates the curve at the given utilization, both in MinNodeScore..MaxNodeScore units
+func shapeScore(shape []UtilizationShapePoint, utilization int64) int64 {
+	x := func(point UtilizationShapePoint) int64 { return point.Utilization * MaxNodeScore / 100 }
+	y := func(point UtilizationShapePoint) int64 { return point.Score * MaxNodeScore / MaxShapeScore }
+
+	if utilization <= x(shape[0]) {
+		return y(shape[0])
+	}
+
+	for i := 1; i < len(shape); i++ {
+		if utilization <= x(shape[i]) {
+			previous := shape[i-1]
+			return y(previous) + (y(shape[i])-y(previous))*(utilization-x(previous))/(x(shape[i])-x(previous))
+		}
+	}
+
+	return y(shape[len(shape)-1])
+}
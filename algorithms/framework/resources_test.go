@@ -0,0 +1,99 @@
+package framework
+
+import (
+	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newTestResourcesState() *CycleState {
+	return NewCycleState(nodes.New(), &algorithms.Workload{CPU: 100, Memory: 100}, algorithms.NewDecision("test"))
+}
+
+func scoreResources(t *testing.T, strategy ScoringStrategy, node *nodes.Node) int64 {
+	score, err := NewNodeResourcesScore(strategy).Score(newTestResourcesState(), node)
+	assert.NoError(t, err)
+	return score
+}
+
+func TestNodeResourcesScoreAllocated(t *testing.T) {
+	// 50% cpu and 25% memory requested once the workload is placed
+	node := &nodes.Node{Name: "Node0", CPU: 1000, Memory: 2000, Requested: nodes.Resources{CPU: 400, Memory: 400}}
+
+	assert.Equal(t, MaxNodeScore*3/8, scoreResources(t, ScoringStrategy{Type: MostAllocated}, node))
+	assert.Equal(t, MaxNodeScore*5/8, scoreResources(t, ScoringStrategy{Type: LeastAllocated}, node))
+	assert.Equal(t, MaxNodeScore/2, scoreResources(t, ScoringStrategy{Type: MostAllocated, CPUWeight: 1}, node))
+	assert.Equal(t, MaxNodeScore*3/4, scoreResources(t, ScoringStrategy{Type: LeastAllocated, MemoryWeight: 1}, node))
+
+	// nodes without capacity and overcommitted nodes
+	assert.Equal(t, MinNodeScore, scoreResources(t, ScoringStrategy{Type: MostAllocated}, &nodes.Node{Name: "Node1"}))
+	full := &nodes.Node{Name: "Node2", CPU: 100, Memory: 100, Requested: nodes.Resources{CPU: 100, Memory: 100}}
+	assert.Equal(t, MaxNodeScore, scoreResources(t, ScoringStrategy{Type: MostAllocated}, full))
+}
+
+func TestNodeResourcesScoreMilliMemory(t *testing.T) {
+	// memory in milli-bytes, a 10 GiB workload on a 16 GiB node
+	const gibibyte = int64(1) << 30 * 1000
+	state := NewCycleState(nodes.New(), &algorithms.Workload{Memory: 10 * gibibyte}, algorithms.NewDecision("test"))
+	node := &nodes.Node{Name: "Node0", CPU: 4000, Memory: 16 * gibibyte}
+
+	shape := []UtilizationShapePoint{{Utilization: 0, Score: 0}, {Utilization: 100, Score: 100}}
+	for _, test := range []struct {
+		strategy ScoringStrategy
+		expected int64
+	}{
+		{ScoringStrategy{Type: MostAllocated, MemoryWeight: 1}, MaxNodeScore * 5 / 8},
+		{ScoringStrategy{Type: LeastAllocated, MemoryWeight: 1}, MaxNodeScore * 3 / 8},
+		{ScoringStrategy{Type: RequestedToCapacityRatio, MemoryWeight: 1, Shape: shape}, MaxNodeScore * 5 / 8},
+	} {
+		score, err := NewNodeResourcesScore(test.strategy).Score(state, node)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, score, test.strategy.Type)
+	}
+}
+
+func TestNodeResourcesScoreRequestedToCapacityRatio(t *testing.T) {
+	// favors nodes around 50% utilization
+	strategy := ScoringStrategy{
+		Type:      RequestedToCapacityRatio,
+		CPUWeight: 1,
+		Shape:     []UtilizationShapePoint{{Utilization: 20, Score: 0}, {Utilization: 50, Score: 100}, {Utilization: 100, Score: 0}},
+	}
+
+	// the workload adds 10% to the requested utilization
+	tests := map[int64]int64{0: 0, 100: 0, 250: MaxNodeScore / 2, 400: MaxNodeScore, 650: MaxNodeScore / 2, 900: 0}
+	for requested, expected := range tests {
+		node := &nodes.Node{Name: "Node0", CPU: 1000, Requested: nodes.Resources{CPU: requested}}
+		assert.Equal(t, expected, scoreResources(t, strategy, node), "requested %d", requested)
+	}
+}
+
+func TestScoringStrategyValidate(t *testing.T) {
+	valid := []ScoringStrategy{
+		{Type: MostAllocated},
+		{Type: LeastAllocated, CPUWeight: 2, MemoryWeight: 1},
+		{Type: RequestedToCapacityRatio, Shape: []UtilizationShapePoint{{Utilization: 0, Score: 100}}},
+	}
+
+	for _, strategy := range valid {
+		assert.NoError(t, strategy.Validate())
+	}
+
+	invalid := []ScoringStrategy{
+		{Type: "BinPacking"},
+		{Type: MostAllocated, CPUWeight: -1},
+		{Type: MostAllocated, Shape: []UtilizationShapePoint{{Utilization: 0, Score: 100}}},
+		{Type: RequestedToCapacityRatio},
+		{Type: RequestedToCapacityRatio, Shape: []UtilizationShapePoint{{Utilization: 101, Score: 0}}},
+		{Type: RequestedToCapacityRatio, Shape: []UtilizationShapePoint{{Utilization: 0, Score: 101}}},
+		{Type: RequestedToCapacityRatio, Shape: []UtilizationShapePoint{{Utilization: 50, Score: 0}, {Utilization: 50, Score: 10}}},
+	}
+
+	for _, strategy := range invalid {
+		assert.Error(t, strategy.Validate())
+
+		_, err := NewNodeResourcesScore(strategy).Score(newTestResourcesState(), &nodes.Node{Name: "Node0"})
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,207 @@
+package latency
+
+import (
+	"fmt"
+	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/algorithms/framework"
+	"github.com/geolocate-orchestration/scheduler/labels"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+	"k8s.io/klog/v2"
+	"strconv"
+)
+
+// Name is the name under which the latency algorithm is registered
+const Name = "latency"
+
+// OptionMatrix is the option holding the *Matrix used by the algorithm, to update it with probe samples
+const OptionMatrix = "matrix"
+
+// OptionFile is the option holding the path of a JSON file to load the matrix from, see Matrix.Load
+const OptionFile = "file"
+
+// OptionAlpha is the option holding the float64 weight of new samples in the estimates of a loaded or new matrix
+const OptionAlpha = "alpha"
+
+func init() {
+	algorithms.MustRegister(Name, func(inodes nodes.INodes, options algorithms.Options) (algorithms.Algorithm, error) {
+		matrix, err := newMatrixFromOptions(options)
+		if err != nil {
+			return nil, err
+		}
+
+		return New(inodes, matrix), nil
+	})
+}
+
+// New creates new latency algorithm selecting nodes by the latencies of the given matrix
+func New(inodes nodes.INodes, matrix *Matrix) algorithms.Algorithm {
+	return framework.New(inodes, Profile(matrix))
+}
+
+// Profile returns the plugins of the latency algorithm, it selects the feasible node with the lowest expected
+// latency from the workload client region
+func Profile(matrix *Matrix) framework.Profile {
+	return framework.Profile{
+		Name:       Name,
+		PreFilter:  []framework.PreFilterPlugin{framework.ForbiddenLocation{}},
+		Filter:     []framework.FilterPlugin{framework.ForbiddenLocation{}, framework.NodeResources{}},
+		Candidates: NewPlugin(matrix),
+		Score:      []framework.WeightedScorePlugin{{Plugin: framework.LowestCost{}, Weight: 1}},
+	}
+}
+
+// Plugin finds the nodes with a known latency from the workload client region and costs them by it,
+// nodes without latency estimates are only candidates when no measured node is feasible
+type Plugin struct {
+	matrix *Matrix
+}
+
+// NewPlugin creates the latency plugin looking up latencies in the given matrix
+func NewPlugin(matrix *Matrix) *Plugin {
+	return &Plugin{matrix: matrix}
+}
+
+// Name returns the plugin name
+func (l *Plugin) Name() string {
+	return "Latency"
+}
+
+// Candidates returns the measured nodes within the workload maximum latency, or the unmeasured ones when it has none
+// It fails if no node is known to satisfy the workload maximum latency
+func (l *Plugin) Candidates(state *framework.CycleState, filter framework.FilterFunc) (*framework.Tier, error) {
+	region, maxLatency, err := getLatencyLabels(state.Workload)
+	if err != nil {
+		return nil, err
+	}
+
+	if region == "" {
+		if maxLatency > 0 {
+			return nil, fmt.Errorf("%w: workload has no %s label", algorithms.ErrMaxLatencyUnsatisfied, labels.WorkloadClientRegion)
+		}
+
+		return &framework.Tier{
+			Level:  algorithms.LevelAny,
+			Nodes:  filter(algorithms.LevelAny, state.Lister.GetAllNodes()),
+			Reason: "workload has no client region, selected a random node",
+		}, nil
+	}
+
+	known := make([]*nodes.Node, 0)
+	unknown := make([]*nodes.Node, 0)
+	latencies := make(map[string]float64)
+
+	for _, node := range state.Lister.GetAllNodes() {
+		if rtt, ok := l.getNodeLatency(state.Lister, region, node); ok && (maxLatency == 0 || rtt <= maxLatency) {
+			known = append(known, node)
+			latencies[node.Name] = rtt
+		} else if !ok {
+			unknown = append(unknown, node)
+		}
+	}
+
+	if options := filter(algorithms.LevelLatency, known); len(options) > 0 {
+		return &framework.Tier{
+			Level: algorithms.LevelLatency,
+			Nodes: options,
+			Costs: latencies,
+			Explain: func(node *nodes.Node) string {
+				return fmt.Sprintf("selected the node with the lowest expected latency, %.1f ms from client region %q",
+					latencies[node.Name], region)
+			},
+		}, nil
+	}
+
+	if maxLatency > 0 {
+		if len(known) > 0 {
+			// nodes satisfy the bound but can't take the workload
+			return nil, algorithms.NewNoFeasibleNodeError(state.Decision)
+		}
+
+		return nil, fmt.Errorf("%w: %g ms from client region %q", algorithms.ErrMaxLatencyUnsatisfied, maxLatency, region)
+	}
+
+	// nodes without latency estimates are only used when no measured node fits the workload
+	return &framework.Tier{
+		Level:  algorithms.LevelRandom,
+		Nodes:  filter(algorithms.LevelRandom, unknown),
+		Reason: "no node with known latency fits the workload, selected a random node",
+	}, nil
+}
+
+// getNodeLatency looks up the latency to the node, then to its location at every topology level from the lowest,
+// by label value and by resolved location code
+func (l *Plugin) getNodeLatency(lister nodes.NodeLister, region string, node *nodes.Node) (float64, bool) {
+	targets := []string{node.Name}
+
+	for _, level := range lister.GetTopology().Levels {
+		value := node.Labels[level.Label]
+		if value == "" {
+			continue
+		}
+
+		targets = append(targets, value)
+		if code, err := lister.ResolveLocation(level.Name, value); err == nil && key(code) != key(value) {
+			targets = append(targets, code)
+		}
+	}
+
+	for _, target := range targets {
+		if rtt, ok := l.matrix.Latency(region, target); ok {
+			return rtt, true
+		}
+	}
+
+	return 0, false
+}
+
+// Helpers
+
+func getLatencyLabels(workload *algorithms.Workload) (string, float64, error) {
+	if workload == nil {
+		return "", 0, nil
+	}
+
+	region := workload.Labels[labels.WorkloadClientRegion]
+	value := workload.Labels[labels.WorkloadMaxLatency]
+
+	if value == "" {
+		return region, 0, nil
+	}
+
+	maxLatency, err := strconv.ParseFloat(value, 64)
+	if err != nil || !(maxLatency > 0) {
+		return "", 0, fmt.Errorf("%s label must be a positive number of milliseconds, got %q", labels.WorkloadMaxLatency, value)
+	}
+
+	return region, maxLatency, nil
+}
+
+func newMatrixFromOptions(options algorithms.Options) (*Matrix, error) {
+	alpha := DefaultAlpha
+	if value, ok := options[OptionAlpha]; ok {
+		if alpha, ok = value.(float64); !ok {
+			return nil, fmt.Errorf("latency algorithm %q option must be a float64", OptionAlpha)
+		}
+	}
+
+	if value, ok := options[OptionMatrix]; ok {
+		matrix, ok := value.(*Matrix)
+		if !ok || matrix == nil {
+			return nil, fmt.Errorf("latency algorithm %q option must be a *latency.Matrix", OptionMatrix)
+		}
+
+		return matrix, nil
+	}
+
+	if value, ok := options[OptionFile]; ok {
+		path, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("latency algorithm %q option must be a string", OptionFile)
+		}
+
+		klog.Infof("loading latency matrix from %s\n", path)
+		return LoadMatrix(path, alpha)
+	}
+
+	return NewMatrix(alpha)
+}
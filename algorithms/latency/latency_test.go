@@ -0,0 +1,286 @@
+package latency
+
+import (
+	"errors"
+	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/labels"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestMatrix(t *testing.T, estimates string) *Matrix {
+	matrix, err := NewMatrix(DefaultAlpha)
+	assert.NoError(t, err)
+	assert.NoError(t, matrix.Load(strings.NewReader(estimates)))
+	return matrix
+}
+
+func newTestNodes() nodes.INodes {
+	inodes := nodes.New()
+
+	for _, location := range [][]string{{"Node0", "Porto", "Portugal"}, {"Node1", "Madrid", "Spain"}, {"Node2", "Paris", "France"}} {
+		inodes.AddNode(&nodes.Node{
+			Name: location[0],
+			Labels: map[string]string{
+				labels.NodeCity:      location[1],
+				labels.NodeCountry:   location[2],
+				labels.NodeContinent: "Europe",
+			},
+			CPU:    20000,
+			Memory: 20000,
+		})
+	}
+
+	return inodes
+}
+
+func newTestWorkload(region string, maxLatency string) *algorithms.Workload {
+	workload := &algorithms.Workload{Labels: map[string]string{}, CPU: 10000, Memory: 10000}
+
+	if region != "" {
+		workload.Labels[labels.WorkloadClientRegion] = region
+	}
+
+	if maxLatency != "" {
+		workload.Labels[labels.WorkloadMaxLatency] = maxLatency
+	}
+
+	return workload
+}
+
+func TestMatrixObserve(t *testing.T) {
+	matrix, err := NewMatrix(0.5)
+	assert.NoError(t, err)
+
+	_, ok := matrix.Latency("Porto", "Node0")
+	assert.False(t, ok)
+
+	matrix.Observe("Porto", "Node0", 10*time.Millisecond)
+	rtt, ok := matrix.Latency("porto", "NODE0")
+	assert.True(t, ok)
+	assert.Equal(t, 10.0, rtt)
+
+	matrix.Observe("Porto", "Node0", 20*time.Millisecond)
+	matrix.Observe("Porto", "Node0", 40*time.Millisecond)
+	rtt, _ = matrix.Latency("Porto", "Node0")
+	assert.Equal(t, 27.5, rtt)
+
+	assert.Error(t, matrix.Observe("Porto", "Node0", -time.Millisecond))
+	assert.Error(t, matrix.Observe("Porto", "Node1", -time.Millisecond))
+	rtt, _ = matrix.Latency("Porto", "Node0")
+	assert.Equal(t, 27.5, rtt)
+
+	_, ok = matrix.Latency("Porto", "Node1")
+	assert.False(t, ok)
+}
+
+func TestNewMatrixInvalidAlpha(t *testing.T) {
+	for _, alpha := range []float64{0, -1, 1.5} {
+		_, err := NewMatrix(alpha)
+		assert.Error(t, err)
+	}
+}
+
+func TestMatrixLoad(t *testing.T) {
+	matrix := newTestMatrix(t, `{"Porto": {"Node0": 4.5, "Spain": 12}}`)
+
+	rtt, ok := matrix.Latency("Porto", "spain")
+	assert.True(t, ok)
+	assert.Equal(t, 12.0, rtt)
+
+	assert.Error(t, matrix.Load(strings.NewReader(`{"Porto": {"Node0": -1}}`)))
+	assert.Error(t, matrix.Load(strings.NewReader(`{"Porto": 1}`)))
+
+	rtt, _ = matrix.Latency("Porto", "Node0")
+	assert.Equal(t, 4.5, rtt)
+
+	// loading again merges the estimates
+	assert.NoError(t, matrix.Load(strings.NewReader(`{"Porto": {"Node0": 6}, "Berlin": {"Node1": 20}}`)))
+	for _, pair := range []struct {
+		from, to string
+		rtt      float64
+	}{{"Porto", "Node0", 6}, {"Porto", "Spain", 12}, {"Berlin", "Node1", 20}} {
+		rtt, ok = matrix.Latency(pair.from, pair.to)
+		assert.True(t, ok)
+		assert.Equal(t, pair.rtt, rtt)
+	}
+}
+
+func TestLoadMatrix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "latencies.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"Porto": {"Node1": 8}}`), 0600))
+
+	s, err := algorithms.New(Name, newTestNodes(), algorithms.Options{OptionFile: path})
+	assert.NoError(t, err)
+
+	node, err := s.GetNode(newTestWorkload("Porto", "10"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Node1", node.Name)
+
+	_, err = LoadMatrix(filepath.Join(t.TempDir(), "missing.json"), DefaultAlpha)
+	assert.Error(t, err)
+}
+
+func TestFactoryOptions(t *testing.T) {
+	matrix := newTestMatrix(t, `{}`)
+
+	for _, options := range []algorithms.Options{nil, {OptionMatrix: matrix}, {OptionAlpha: 0.5}} {
+		_, err := algorithms.New(Name, newTestNodes(), options)
+		assert.NoError(t, err)
+	}
+
+	for _, options := range []algorithms.Options{{OptionMatrix: "matrix"}, {OptionFile: 1}, {OptionAlpha: 2.0}, {OptionAlpha: "0.5"}} {
+		_, err := algorithms.New(Name, newTestNodes(), options)
+		assert.Error(t, err)
+	}
+}
+
+func TestGetName(t *testing.T) {
+	assert.Equal(t, "latency", New(nodes.New(), newTestMatrix(t, `{}`)).GetName())
+}
+
+func TestGetNodeEmpty(t *testing.T) {
+	_, err := New(nodes.New(), newTestMatrix(t, `{}`)).GetNode(newTestWorkload("Porto", ""))
+	assert.True(t, errors.Is(err, algorithms.ErrNoNodes))
+}
+
+func TestGetNodeLowestLatency(t *testing.T) {
+	matrix := newTestMatrix(t, `{"Porto": {"Node0": 30, "Spain": 20, "Europe": 50}, "Berlin": {"Europe": 40, "Node2": 10}}`)
+
+	tests := map[string]string{"Porto": "Node1", "Berlin": "Node2"}
+
+	for region, expected := range tests {
+		node, decision, err := New(newTestNodes(), matrix).(algorithms.Explainer).GetNodeWithDecision(newTestWorkload(region, ""))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, node.Name, region)
+		assert.Equal(t, algorithms.LevelLatency, decision.Level)
+	}
+
+	// probes update the estimates
+	for i := 0; i < 10; i++ {
+		matrix.Observe("Porto", "Node0", 5*time.Millisecond)
+	}
+
+	node, err := New(newTestNodes(), matrix).GetNode(newTestWorkload("Porto", ""))
+	assert.NoError(t, err)
+	assert.Equal(t, "Node0", node.Name)
+}
+
+func TestGetNodeTopologyLatency(t *testing.T) {
+	// countries are looked up by code too
+	matrix := newTestMatrix(t, `{"Porto": {"ES": 20, "Europe": 50}}`)
+
+	node, err := New(newTestNodes(), matrix).GetNode(newTestWorkload("Porto", ""))
+	assert.NoError(t, err)
+	assert.Equal(t, "Node1", node.Name)
+
+	topology, err := nodes.NewTopology(
+		nodes.TopologyLevel{Name: "zone", Label: "node.geolocate.io/zone", Parent: "datacenter"},
+		nodes.TopologyLevel{Name: "datacenter", Label: "node.geolocate.io/datacenter"},
+	)
+	assert.NoError(t, err)
+
+	inodes := nodes.NewWithTopology(topology)
+	for _, location := range [][]string{{"Node0", "zone-a", "dc-1"}, {"Node1", "zone-b", "dc-1"}, {"Node2", "zone-c", "dc-2"}} {
+		inodes.AddNode(&nodes.Node{
+			Name:   location[0],
+			Labels: map[string]string{"node.geolocate.io/zone": location[1], "node.geolocate.io/datacenter": location[2]},
+			CPU:    20000,
+			Memory: 20000,
+		})
+	}
+
+	// the zone of Node1 is looked up before its datacenter
+	matrix = newTestMatrix(t, `{"Porto": {"zone-b": 8, "dc-1": 10, "dc-2": 9}}`)
+
+	node, err = New(inodes, matrix).GetNode(newTestWorkload("Porto", ""))
+	assert.NoError(t, err)
+	assert.Equal(t, "Node1", node.Name)
+}
+
+func TestGetNodeMaxLatency(t *testing.T) {
+	matrix := newTestMatrix(t, `{"Porto": {"Node0": 30, "Node1": 20}}`)
+
+	node, err := New(newTestNodes(), matrix).GetNode(newTestWorkload("Porto", "25"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Node1", node.Name)
+
+	for _, workload := range []*algorithms.Workload{newTestWorkload("Porto", "15"), newTestWorkload("Berlin", "100"), newTestWorkload("", "100")} {
+		_, err = New(newTestNodes(), matrix).GetNode(workload)
+		assert.True(t, errors.Is(err, algorithms.ErrMaxLatencyUnsatisfied), workload.Labels)
+	}
+
+	for _, value := range []string{"fast", "0", "-10"} {
+		_, err = New(newTestNodes(), matrix).GetNode(newTestWorkload("Porto", value))
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, algorithms.ErrMaxLatencyUnsatisfied))
+	}
+}
+
+func TestGetNodeMaxLatencyResources(t *testing.T) {
+	inodes := newTestNodes()
+	assert.NoError(t, inodes.AssumeWorkload("Existing", "Node1", nodes.Resources{CPU: 15000}))
+	matrix := newTestMatrix(t, `{"Porto": {"Node0": 30, "Node1": 20}}`)
+
+	node, err := New(inodes, matrix).GetNode(newTestWorkload("Porto", ""))
+	assert.NoError(t, err)
+	assert.Equal(t, "Node0", node.Name)
+
+	_, err = New(inodes, matrix).GetNode(newTestWorkload("Porto", "25"))
+	assert.True(t, errors.Is(err, algorithms.ErrInsufficientResources))
+}
+
+func TestGetNodeForbidden(t *testing.T) {
+	workload := newTestWorkload("Porto", "")
+	workload.Labels[labels.WorkloadForbiddenLocation] = "-ES-"
+
+	node, err := New(newTestNodes(), newTestMatrix(t, `{"Porto": {"Node0": 30, "Node1": 20}}`)).GetNode(workload)
+	assert.NoError(t, err)
+	assert.Equal(t, "Node0", node.Name)
+}
+
+func TestGetNodeFallbacks(t *testing.T) {
+	matrix := newTestMatrix(t, `{"Porto": {"Node0": 30}}`)
+
+	_, decision, err := New(newTestNodes(), matrix).(algorithms.Explainer).GetNodeWithDecision(newTestWorkload("", ""))
+	assert.NoError(t, err)
+	assert.Equal(t, algorithms.LevelAny, decision.Level)
+
+	_, decision, err = New(newTestNodes(), matrix).(algorithms.Explainer).GetNodeWithDecision(newTestWorkload("Berlin", ""))
+	assert.NoError(t, err)
+	assert.Equal(t, algorithms.LevelRandom, decision.Level)
+}
+
+// TestConcurrentObserve is meant to be run with the race detector enabled
+func TestConcurrentObserve(t *testing.T) {
+	matrix := newTestMatrix(t, `{}`)
+	algorithm := New(newTestNodes(), matrix)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				matrix.Observe("Porto", "Node0", time.Duration(j)*time.Millisecond)
+			}
+		}()
+
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_, err := algorithm.GetNode(newTestWorkload("Porto", ""))
+				assert.NoError(t, err)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
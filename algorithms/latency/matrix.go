@@ -0,0 +1,129 @@
+package latency
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultAlpha is the weight of a new probe sample in the latency estimates
+const DefaultAlpha = 0.3
+
+// Matrix holds the expected round-trip time between client regions and nodes or node locations
+// Estimates are an exponentially weighted moving average of the observed probe samples
+// It is safe for concurrent use
+type Matrix struct {
+	mutex     sync.RWMutex
+	alpha     float64
+	estimates map[string]map[string]float64 // milliseconds by source and target
+}
+
+// NewMatrix creates an empty latency matrix, alpha is the weight in (0, 1] of new samples in the estimates
+func NewMatrix(alpha float64) (*Matrix, error) {
+	if !(alpha > 0 && alpha <= 1) {
+		return nil, fmt.Errorf("latency matrix alpha must be in (0, 1], got %g", alpha)
+	}
+
+	return &Matrix{
+		alpha:     alpha,
+		estimates: make(map[string]map[string]float64),
+	}, nil
+}
+
+// LoadMatrix creates a latency matrix initialized from a JSON file, see Matrix.Load
+func LoadMatrix(path string, alpha float64) (*Matrix, error) {
+	matrix, err := NewMatrix(alpha)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if err := matrix.Load(file); err != nil {
+		return nil, fmt.Errorf("loading latency matrix %s: %w", path, err)
+	}
+
+	return matrix, nil
+}
+
+// Load merges the estimates of the given JSON object, mapping sources to targets to milliseconds:
+// {"Porto": {"node-a": 4.5, "Spain": 12}}
+// The pairs it lists replace their current estimates, the other pairs keep theirs. Nothing is merged if any
+// estimate is negative
+// Sources are client regions, targets are node names or node locations at any topology level, by name or code
+func (m *Matrix) Load(reader io.Reader) error {
+	estimates := make(map[string]map[string]float64)
+
+	if err := json.NewDecoder(reader).Decode(&estimates); err != nil {
+		return err
+	}
+
+	for from, targets := range estimates {
+		for to, rtt := range targets {
+			if rtt < 0 {
+				return fmt.Errorf("latency from %q to %q must not be negative, got %g", from, to, rtt)
+			}
+		}
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	for from, targets := range estimates {
+		for to, rtt := range targets {
+			m.set(from, to, rtt)
+		}
+	}
+
+	return nil
+}
+
+// Observe updates the estimate between a client region and a node or node location with a probe sample
+// The first sample of a pair becomes its estimate, negative samples are rejected and leave it unchanged
+func (m *Matrix) Observe(from string, to string, rtt time.Duration) error {
+	if rtt < 0 {
+		return fmt.Errorf("latency from %q to %q must not be negative, got %s", from, to, rtt)
+	}
+
+	sample := float64(rtt) / float64(time.Millisecond)
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if estimate, ok := m.estimates[key(from)][key(to)]; ok {
+		sample = m.alpha*sample + (1-m.alpha)*estimate
+	}
+
+	m.set(from, to, sample)
+	return nil
+}
+
+// Latency returns the expected round-trip time in milliseconds between a client region and a node or node location
+func (m *Matrix) Latency(from string, to string) (float64, bool) {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	rtt, ok := m.estimates[key(from)][key(to)]
+	return rtt, ok
+}
+
+func (m *Matrix) set(from string, to string, rtt float64) {
+	if m.estimates[key(from)] == nil {
+		m.estimates[key(from)] = make(map[string]float64)
+	}
+
+	m.estimates[key(from)][key(to)] = rtt
+}
+
+// key makes region and location names case insensitive
+func key(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
@@ -3,266 +3,781 @@ package location
 import (
 	"errors"
 	"fmt"
-	"github.com/geolocate-orchestration/gountries"
 	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/algorithms/framework"
 	"github.com/geolocate-orchestration/scheduler/labels"
+	"github.com/geolocate-orchestration/scheduler/locations"
 	"github.com/geolocate-orchestration/scheduler/nodes"
 	"k8s.io/klog/v2"
 	"strings"
 )
 
-type location struct {
-	query      *gountries.Query
-	nodes      nodes.INodes
+// Name is the name under which the location algorithm is registered
+const Name = "location"
+
+// OptionBorderHops is the option holding the int number of land borders the similar locations fallback may cross
+// looking for nodes in countries near the requested ones before widening to their continent, 0 disables it
+const OptionBorderHops = "borderHops"
+
+// OptionFallback is the option holding how a node is selected when no node matches the preferred locations,
+// FallbackNearest or FallbackRandom
+const OptionFallback = "fallback"
+
+// OptionScoring is the option holding how nodes of the matched location level are ranked by their resources,
+// a framework.ScoringStrategy or the name of a strategy without curve, MostAllocated or LeastAllocated
+const OptionScoring = "scoring"
+
+// DefaultBorderHops is the number of land borders crossed when OptionBorderHops isn't set
+const DefaultBorderHops = 1
+
+const (
+	// FallbackNearest selects the node nearest to the preferred locations centroids
+	FallbackNearest = "nearest"
+
+	// FallbackRandom selects a random node
+	FallbackRandom = "random"
+)
+
+// Config tunes how the algorithm falls back when no node matches the preferred locations
+type Config struct {
+	// BorderHops is how many land borders away countries bordering the preferred ones are looked for
+	BorderHops int
+
+	// Fallback is how a node is selected when no similar location has nodes, FallbackNearest or FallbackRandom
+	Fallback string
+
+	// Scoring ranks the nodes of the matched location level by their resources, they are equally ranked when nil
+	Scoring *framework.ScoringStrategy
+}
+
+// DefaultConfig returns the configuration used by New
+func DefaultConfig() Config {
+	return Config{BorderHops: DefaultBorderHops, Fallback: FallbackNearest}
+}
+
+func init() {
+	algorithms.MustRegister(Name, func(inodes nodes.INodes, options algorithms.Options) (algorithms.Algorithm, error) {
+		config, err := ConfigFromOptions(Name, options)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewWithConfig(inodes, config), nil
+	})
+}
+
+// Plugin finds the candidate nodes by the workload location labels, walking the fallback levels from the requested
+// locations to the locations containing them and finally to the nodes nearest to them
+type Plugin struct {
+	config   Config
+	weighted bool
+}
+
+// cycle holds the state of the location plugin in a single scheduling cycle
+type cycle struct {
+	nodes      nodes.NodeLister
+	decision   *algorithms.Decision
+	filter     framework.FilterFunc
 	pod        *algorithms.Workload
-	queryType  string // required or preferred
+	weighted   bool
+	queryType  string          // required or preferred
+	unknown    []string        // location names that could not be resolved
+	within     map[string]bool // nodes matching the required locations, candidates are limited to them when set
+	config     Config
+	similar    string // where the node selected by the similar locations fallback is
+	condition  locations.Condition
 	cities     []string
 	countries  []string
 	continents []string
 }
 
-// New creates new location struct
+// New creates new location algorithm
 func New(nodes nodes.INodes) algorithms.Algorithm {
-	return &location{
-		query:      gountries.New(),
-		nodes:      nodes,
-		pod:        nil,
+	return NewWithConfig(nodes, DefaultConfig())
+}
+
+// NewWithConfig creates new location algorithm falling back as configured when no node matches the preferred locations
+func NewWithConfig(nodes nodes.INodes, config Config) algorithms.Algorithm {
+	return framework.New(nodes, Profile(config))
+}
+
+// Profile returns the plugins of the location algorithm, it selects a node matching the workload location labels
+// with enough resources for it
+// It fails if there are no nodes available and if no node matches an existing 'requiredLocation' label
+func Profile(config Config) framework.Profile {
+	return framework.Profile{
+		Name:       Name,
+		PreFilter:  []framework.PreFilterPlugin{framework.ForbiddenLocation{}},
+		Filter:     []framework.FilterPlugin{framework.ForbiddenLocation{}, framework.NodeResources{}},
+		Candidates: NewPlugin(config),
+		Score:      ScorePlugins(config),
+	}
+}
+
+// ScorePlugins returns the score plugins of the location algorithms, ranking the nodes of the nearest fallback
+// by their distance, then by their resources if configured so
+func ScorePlugins(config Config) []framework.WeightedScorePlugin {
+	plugins := []framework.WeightedScorePlugin{{Plugin: framework.LowestCost{}, Weight: 1}}
+
+	if config.Scoring != nil {
+		plugins = append(plugins, framework.WeightedScorePlugin{Plugin: framework.NewNodeResourcesScore(*config.Scoring), Weight: 1})
+	}
+
+	return plugins
+}
+
+// NewPlugin creates the location plugin, ranking nodes by the weighted preferred locations label too
+func NewPlugin(config Config) *Plugin {
+	return &Plugin{config: config, weighted: true}
+}
+
+// NewUnweightedPlugin creates the location plugin ignoring the weighted preferred locations label
+func NewUnweightedPlugin(config Config) *Plugin {
+	return &Plugin{config: config}
+}
+
+// Name returns the plugin name
+func (p *Plugin) Name() string {
+	return "Location"
+}
+
+// Candidates returns the feasible nodes of the first fallback level matching the workload location labels
+// Nodes in the nearest fallback tier are costed by their distance to the preferred locations
+func (p *Plugin) Candidates(state *framework.CycleState, filter framework.FilterFunc) (*framework.Tier, error) {
+	c := &cycle{
+		nodes:      state.Lister,
+		decision:   state.Decision,
+		filter:     filter,
+		pod:        state.Workload,
+		weighted:   p.weighted,
 		queryType:  "",
+		config:     p.config,
 		cities:     make([]string, 0),
 		countries:  make([]string, 0),
 		continents: make([]string, 0),
 	}
+
+	if c.pod == nil {
+		c.pod = &algorithms.Workload{}
+	}
+
+	return c.getTier()
+}
+
+func (g *cycle) getTier() (*framework.Tier, error) {
+	if g.getLocationLabelType() != "" {
+		return g.getTierByLocation()
+	}
+
+	// Node location labels were set so returning a random node
+	return g.newTier(g.getCandidates(algorithms.LevelAny, g.nodes.GetAllNodes()),
+		algorithms.LevelAny, "workload has no location labels, selected a random node"), nil
+}
+
+// Locations
+
+// getTierByLocation filters the nodes by the required locations and ranks them by the preferred locations
+func (g *cycle) getTierByLocation() (*framework.Tier, error) {
+	var required []*nodes.Node
+	requiredLevel := algorithms.LevelRandom
+
+	if label := g.pod.Labels[labels.WorkloadRequiredLocation]; label != "" {
+		options, level, err := g.getLocationNodes("required", label)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(options) == 0 {
+			// if location is "required" but there are no matching nodes, throw error
+			return nil, algorithms.NewRequiredLocationError(label, g.unknown, g.decision)
+		}
+
+		g.decision.AddUnknown(g.unknown)
+		if !g.hasPreferredLocation() {
+			return g.newTier(options, level, g.getMatchReason(level)), nil
+		}
+
+		// preferred locations are only looked for within the required locations
+		required, requiredLevel = options, level
+		g.within = make(map[string]bool, len(options))
+		for _, node := range options {
+			g.within[node.Name] = true
+		}
+	}
+
+	options, level, reason, err := g.getPreferredNodes()
+	if err != nil {
+		return nil, err
+	}
+
+	// preferred locations are best effort, the unknown ones are only reported
+	g.decision.AddUnknown(g.unknown)
+
+	if len(options) > 0 {
+		return g.newTier(options, level, reason), nil
+	}
+
+	if required != nil {
+		return g.newTier(required, requiredLevel,
+			"no node matches preferred locations, selected a node matching required locations"), nil
+	}
+
+	// when location is "preferred" and there are no matching nodes, return the nearest or a random node
+	return g.selectFallback()
 }
 
-func (g *location) GetName() string {
-	return "location"
+// selectFallback returns the nodes costed by their distance to the preferred locations centroids,
+// or any node when configured so or when they can't be located
+func (g *cycle) selectFallback() (*framework.Tier, error) {
+	reason := "no node matches preferred locations, selected a random node"
+
+	origins := g.getPreferredCoordinates()
+	if g.config.Fallback == FallbackRandom || len(origins) == 0 {
+		return g.newTier(g.getCandidates(algorithms.LevelRandom, g.nodes.GetAllNodes()), algorithms.LevelRandom, reason), nil
+	}
+
+	catalog := g.nodes.GetCatalog()
+	located := make([]*nodes.Node, 0)
+	unlocated := make([]*nodes.Node, 0)
+	distances := make(map[string]float64)
+
+	for _, node := range g.nodes.GetAllNodes() {
+		coordinates, ok := algorithms.NodeCoordinates(catalog, node)
+		if !ok {
+			unlocated = append(unlocated, node)
+			continue
+		}
+
+		located = append(located, node)
+		for i, origin := range origins {
+			if distance := origin.DistanceTo(coordinates); i == 0 || distance < distances[node.Name] {
+				distances[node.Name] = distance
+			}
+		}
+	}
+
+	if options := g.getCandidates(algorithms.LevelDistance, located); len(options) > 0 {
+		return &framework.Tier{
+			Level: algorithms.LevelDistance,
+			Nodes: options,
+			Costs: distances,
+			Explain: func(node *nodes.Node) string {
+				return fmt.Sprintf("no node matches preferred locations, selected the nearest node, %.1f km from them",
+					distances[node.Name])
+			},
+		}, nil
+	}
+
+	// unlocated nodes are the last resort, as in the distance algorithm
+	return g.newTier(g.getCandidates(algorithms.LevelRandom, unlocated), algorithms.LevelRandom, reason), nil
 }
 
-// GetNode select the best node matching the given constraints labels
-// It returns error if there are no nodes available and if no node matches an existing 'requiredLocation' label
-func (g *location) GetNode(pod *algorithms.Workload) (*nodes.Node, error) {
-	var node *nodes.Node
-	var err error
+// getPreferredCoordinates returns the centroids of the preferred locations that can be located
+func (g *cycle) getPreferredCoordinates() []locations.Coordinates {
+	catalog := g.nodes.GetCatalog()
+	origins := make([]locations.Coordinates, 0)
 
-	if g.nodes.CountNodes() == 0 {
-		errMessage := "no nodes are available"
-		return nil, errors.New(errMessage)
+	for _, name := range g.getPreferredNames() {
+		if coordinates, ok := algorithms.LocationCoordinates(catalog, name); ok {
+			origins = append(origins, coordinates)
+		}
 	}
 
-	g.pod = pod
-	if queryType := g.getLocationLabelType(); queryType != "" {
-		g.queryType = queryType
-		node, err = g.getNodeByLocation()
-	} else {
-		// Node location labels were set so returning a random node
-		node, err = nodes.GetRandomFromList(g.nodes.GetNodes(g.buildResourceFilter()))
+	return origins
+}
+
+// getPreferredNames lists the preferred location names
+func (g *cycle) getPreferredNames() []string {
+	if label := g.weightedLabel(); label != "" {
+		preferences, _ := locations.ParsePreferences(label)
+
+		names := make([]string, 0, len(preferences))
+		for _, preference := range preferences {
+			names = append(names, preference.Value)
+		}
+
+		return names
 	}
 
-	return node, err
+	if g.condition == nil {
+		return append(append([]string{}, g.cities...), g.countries...)
+	}
+
+	names := make([]string, 0)
+	for _, match := range locations.PositiveMatches(g.condition) {
+		names = append(names, match.MatchValues()...)
+	}
+
+	return names
 }
 
-// Locations
+// getPreferredNodes returns the best ranked nodes for the preferred locations and the reason they were selected
+func (g *cycle) getPreferredNodes() ([]*nodes.Node, algorithms.Level, string, error) {
+	if label := g.weightedLabel(); label != "" {
+		return g.getWeightedNodes(label)
+	}
 
-func (g *location) buildResourceFilter() *nodes.NodeFilter {
-	return &nodes.NodeFilter{
-		Labels: nil,
-		Resources: nodes.Resources{
-			CPU:    g.pod.CPU,
-			Memory: g.pod.Memory,
-		},
+	options, level, err := g.getLocationNodes("preferred", g.pod.Labels[labels.WorkloadPreferredLocation])
+	if err == nil && len(options) == 0 {
+		options, level = g.getSimilarToLocation(), algorithms.LevelSimilar
 	}
+
+	return options, level, g.getMatchReason(level), err
 }
 
-func (g *location) getNodeByLocation() (*nodes.Node, error) {
-	label := ""
-	switch g.queryType {
-	case "required":
-		label = labels.WorkloadRequiredLocation
-	case "preferred":
-		label = labels.WorkloadPreferredLocation
+// getWeightedNodes scores the nodes with the weight of the best preferred location they are in,
+// nodes in the country or continent of a preferred location but in no preferred location score a fraction of its
+// weight, and returns the best scored nodes
+func (g *cycle) getWeightedNodes(label string) ([]*nodes.Node, algorithms.Level, string, error) {
+	g.queryType = "preferred"
+	g.unknown = nil
+	klog.Infoln("weighted preferred", label)
+
+	preferences, err := locations.ParsePreferences(label)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	scores := make(map[string]float64)
+	matches := make(map[string]preferenceMatch)
+
+	// nodes in a listed location score its weight even when a parent of another listed location would score more,
+	// so "Porto:100, Spain:20" scores Spain 20 rather than a fraction of Porto weight
+	listed := make(map[string]bool)
+
+	for _, preference := range preferences {
+		for _, match := range g.getPreferenceMatches(preference) {
+			direct := match.level != algorithms.LevelSimilar
+
+			for _, node := range g.getCandidates(match.level, match.nodes) {
+				if listed[node.Name] && !direct {
+					continue
+				}
+
+				score := float64(preference.Weight) * match.factor
+				if (direct && !listed[node.Name]) || score > scores[node.Name] {
+					scores[node.Name] = score
+					matches[node.Name] = match
+					listed[node.Name] = listed[node.Name] || direct
+				}
+			}
+		}
 	}
 
-	locations := g.pod.Labels[label]
-	klog.Infoln(label, locations)
+	best := 0.0
+	options := make([]*nodes.Node, 0)
+
+	for _, node := range g.nodes.GetAllNodes() {
+		if score := scores[node.Name]; score > best {
+			best = score
+			options = []*nodes.Node{node}
+		} else if score > 0 && score == best {
+			options = append(options, node)
+		}
+	}
+
+	if len(options) == 0 {
+		return options, "", "", nil
+	}
+
+	match := matches[options[0].Name]
+	return options, match.level, fmt.Sprintf("weighted preferred location %q matched at %s level with score %g",
+		match.name, match.level, best), nil
+}
+
+// getPreferenceMatches lists the nodes in a weighted preferred location and in the locations containing it,
+// each level up scoring a smaller fraction of its weight
+func (g *cycle) getPreferenceMatches(preference locations.Preference) []preferenceMatch {
+	name := preference.Value
+
+	for _, level := range preferenceLevels(g.nodes.GetTopology()) {
+		code, err := g.nodes.ResolveLocation(level, name)
+		if err != nil {
+			continue
+		}
+
+		// custom levels take any name, so names only match the ones holding nodes
+		levelNodes := getLevelNodes(g.nodes, level, []string{code})
+		if !isBuiltInLevel(level) && len(levelNodes) == 0 {
+			continue
+		}
+
+		matches := []preferenceMatch{{name, algorithms.Level(level), 1, levelNodes}}
+		factor := 1.0
+
+		parent, codes := g.nodes.GetParentLocations(level, []string{code})
+		for parent != "" && len(codes) > 0 {
+			factor *= similarWeight
+			matches = append(matches, preferenceMatch{name, algorithms.LevelSimilar, factor, getLevelNodes(g.nodes, parent, codes)})
+			parent, codes = g.nodes.GetParentLocations(parent, codes)
+		}
+
+		return matches
+	}
+
+	g.unknown = append(g.unknown, name)
+	return nil
+}
+
+// getLocationNodes returns the nodes of the first level matching the given locations label
+func (g *cycle) getLocationNodes(queryType string, label string) ([]*nodes.Node, algorithms.Level, error) {
+	g.queryType = queryType
+	g.unknown = nil
+	klog.Infoln(queryType, label)
 
 	// fill location info from labels in the geo struct
-	g.parseLocations(locations)
+	if err := g.parseLocations(label); err != nil {
+		return nil, "", err
+	}
 
-	if node, err := g.getRequestedLocation(); err == nil {
-		return node, nil
-	} else if g.queryType == "required" {
-		// if location is "required" but there are no matching nodes, throw error
-		return nil, err
+	if g.condition != nil {
+		return g.getByCondition(), algorithms.LevelExpression, nil
+	}
+
+	options, level := g.getRequestedLocation()
+	return options, level, nil
+}
+
+func (g *cycle) getRequestedLocation() ([]*nodes.Node, algorithms.Level) {
+	if options := g.getByCity(); len(options) > 0 {
+		return options, algorithms.LevelCity
 	}
 
-	if node, err := g.getSimilarToRequestedLocation(); err == nil {
-		return node, nil
+	if options := g.getByCountry(); len(options) > 0 {
+		return options, algorithms.LevelCountry
 	}
 
-	// when location is "preferred" and there are no matching nodes, return random node
-	return nodes.GetRandomFromList(g.nodes.GetNodes(g.buildResourceFilter()))
+	return g.getByContinent(), algorithms.LevelContinent
 }
 
-func (g *location) getRequestedLocation() (*nodes.Node, error) {
-	if node, err := g.getByCity(); err == nil {
-		return node, nil
+// getSimilarToLocation walks up the topology from the requested locations and returns the nodes in the closest
+// locations containing them, the countries bordering the requested ones are tried before their parents
+func (g *cycle) getSimilarToLocation() []*nodes.Node {
+	codes := g.getRequestedCodes()
+
+	// parents are listed after their children so a single pass walks every location up to the top level
+	parents := make(map[string][]string)
+	for _, level := range g.nodes.GetTopology().LevelNames() {
+		if len(parents[level]) > 0 {
+			if options := g.getCandidates(algorithms.LevelSimilar, getLevelNodes(g.nodes, level, parents[level])); len(options) > 0 {
+				g.similar = "the same " + level
+				return options
+			}
+		}
+
+		requested := append(codes[level], parents[level]...)
+		if level == nodes.LevelCountry {
+			if options := g.getBorderingNodes(requested); len(options) > 0 {
+				return options
+			}
+		}
+
+		if parent, parentCodes := g.nodes.GetParentLocations(level, requested); parent != "" {
+			parents[parent] = append(parents[parent], parentCodes...)
+		}
 	}
 
-	if node, err := g.getByCountry(); err == nil {
-		return node, nil
+	return nil
+}
+
+// getRequestedCodes resolves the requested locations, or the ones matched by the expression, by level
+func (g *cycle) getRequestedCodes() map[string][]string {
+	codes := make(map[string][]string)
+
+	if g.condition == nil {
+		codes[nodes.LevelCity] = g.resolveAll(nodes.LevelCity, g.cities)
+		codes[nodes.LevelCountry] = g.resolveAll(nodes.LevelCountry, g.countries)
+		return codes
 	}
 
-	if node, err := g.getByContinent(); err == nil {
-		return node, nil
+	for _, match := range locations.PositiveMatches(g.condition) {
+		level := strings.ToLower(string(match.Level))
+		codes[level] = append(codes[level], g.resolveAll(level, match.MatchValues())...)
 	}
 
-	return nil, errors.New("no nodes match given locations")
+	return codes
 }
 
-func (g *location) getSimilarToRequestedLocation() (*nodes.Node, error) {
-	countries := make(map[string]bool)
-	continents := make(map[string]bool)
+// getBorderingNodes returns the nodes in the countries nearest to the given ones by land borders
+func (g *cycle) getBorderingNodes(countries []string) []*nodes.Node {
+	for i, ring := range locations.BorderingCountries(countries, g.config.BorderHops) {
+		if options := g.getCandidates(algorithms.LevelSimilar, getLevelNodes(g.nodes, nodes.LevelCountry, ring)); len(options) > 0 {
+			g.similar = "a bordering country"
+			if i > 0 {
+				g.similar = fmt.Sprintf("a country %d borders away", i+1)
+			}
 
-	g.getCitiesPredecessors(g.cities, &countries, &continents)
-	g.getCountriesPredecessors(g.countries, &continents)
+			return options
+		}
+	}
+
+	return nil
+}
+
+func (g *cycle) getByCondition() []*nodes.Node {
+	matching, err := g.nodes.GetNodesMatching(g.condition)
 
-	if options := getNodes(g.nodes, g.pod, nil, getKeys(countries), getKeys(continents)); len(options) > 0 {
-		return nodes.GetRandomFromList(options)
+	var unknownErr *locations.UnknownLocationError
+	if errors.As(err, &unknownErr) {
+		g.unknown = append(g.unknown, unknownErr.Names...)
 	}
 
-	return nil, errors.New("no nodes match similar location to given locations")
+	return g.getCandidates(algorithms.LevelExpression, matching)
 }
 
 // GetBy
 
-func (g *location) getByCity() (*nodes.Node, error) {
+func (g *cycle) getByCity() []*nodes.Node {
 	cities := make([]string, 0)
 
-	for _, city := range g.cities {
-		city, err := g.query.FindSubdivisionByName(city)
+	for _, cityName := range g.cities {
+		cityCode, err := g.nodes.ResolveLocation(nodes.LevelCity, cityName)
 		if err != nil {
-			return nil, errors.New(err.Error())
+			g.unknown = append(g.unknown, cityName)
+			continue
 		}
 
-		cityCode := fmt.Sprintf("%s-%s", city.CountryAlpha2, city.Code)
 		cities = append(cities, cityCode)
 	}
 
-	options := getNodes(g.nodes, g.pod, cities, nil, nil)
-	return nodes.GetRandomFromList(options)
+	if len(cities) != len(g.cities) {
+		return g.getCandidates(algorithms.LevelCity, nil)
+	}
+
+	return g.getCandidates(algorithms.LevelCity, getNodes(g.nodes, cities, nil, nil))
 }
 
-func (g *location) getByCountry() (*nodes.Node, error) {
+func (g *cycle) getByCountry() []*nodes.Node {
 	countries := make([]string, 0)
 
 	for _, countryName := range g.countries {
-		country, err := g.findCountry(countryName)
+		countryCode, err := g.nodes.ResolveLocation(nodes.LevelCountry, countryName)
 		if err != nil {
-			return nil, errors.New(err.Error())
+			g.unknown = append(g.unknown, countryName)
+			continue
 		}
 
-		countries = append(countries, country.Alpha2)
+		countries = append(countries, countryCode)
 	}
 
-	options := getNodes(g.nodes, g.pod, nil, countries, nil)
-	return nodes.GetRandomFromList(options)
+	if len(countries) != len(g.countries) {
+		return g.getCandidates(algorithms.LevelCountry, nil)
+	}
+
+	return g.getCandidates(algorithms.LevelCountry, getNodes(g.nodes, nil, countries, nil))
 }
 
-func (g *location) getByContinent() (*nodes.Node, error) {
+func (g *cycle) getByContinent() []*nodes.Node {
 	continents := make([]string, 0)
-	gcont := gountries.NewContinents()
 
 	for _, continentID := range g.continents {
-		if continent, err := gcont.FindContinent(continentID); err == nil {
-			continents = append(continents, continent.Code)
+		if continentCode, err := g.nodes.ResolveLocation(nodes.LevelContinent, continentID); err == nil {
+			continents = append(continents, continentCode)
+		} else {
+			g.unknown = append(g.unknown, continentID)
 		}
 	}
 
-	options := getNodes(g.nodes, g.pod, nil, nil, continents)
-	return nodes.GetRandomFromList(options)
+	return g.getCandidates(algorithms.LevelContinent, getNodes(g.nodes, nil, nil, continents))
 }
 
 // Helpers
 
-func getNodes(inodes nodes.INodes, workload *algorithms.Workload, cities []string, countries []string, continents []string) []*nodes.Node {
+// preferenceLevels lists the topology levels weighted preferred names are resolved at, countries and continents
+// first as their names are often city names too, then the other levels from the most specific
+func preferenceLevels(topology *nodes.Topology) []string {
+	levels := make([]string, 0, len(topology.Levels))
+	for _, level := range []string{nodes.LevelCountry, nodes.LevelContinent} {
+		if _, ok := topology.Level(level); ok {
+			levels = append(levels, level)
+		}
+	}
+
+	for _, level := range topology.LevelNames() {
+		if level != nodes.LevelCountry && level != nodes.LevelContinent {
+			levels = append(levels, level)
+		}
+	}
+
+	return levels
+}
+
+// isBuiltInLevel reports whether locations of the level are resolved through gountries
+func isBuiltInLevel(level string) bool {
+	return level == nodes.LevelCity || level == nodes.LevelCountry || level == nodes.LevelContinent
+}
+
+// similarWeight is the fraction of a weighted preferred location weight scored by nodes one level above it
+const similarWeight = 0.5
+
+// preferenceMatch are the nodes matching a weighted preferred location at a level
+type preferenceMatch struct {
+	name   string
+	level  algorithms.Level
+	factor float64
+	nodes  []*nodes.Node
+}
+
+func (g *cycle) hasPreferredLocation() bool {
+	return g.pod.Labels[labels.WorkloadPreferredLocation] != "" ||
+		g.weightedLabel() != ""
+}
+
+// weightedLabel returns the weighted preferred locations label, empty when the plugin ignores it
+func (g *cycle) weightedLabel() string {
+	if !g.weighted {
+		return ""
+	}
+
+	return g.pod.Labels[labels.WorkloadWeightedPreferredLocation]
+}
+
+func getNodes(inodes nodes.NodeLister, cities []string, countries []string, continents []string) []*nodes.Node {
 	nodeFilter := &nodes.NodeFilter{
 		Locations: nodes.Locations{
 			Cities:     cities,
 			Countries:  countries,
 			Continents: continents,
 		},
-		Resources: nodes.Resources{
-			CPU:    workload.CPU,
-			Memory: workload.Memory,
-		},
 	}
 
 	return inodes.GetNodes(nodeFilter)
 }
 
-func (g *location) getCitiesPredecessors(cities []string, countries *map[string]bool, continents *map[string]bool) {
-	for _, city := range cities {
-		country, err := g.query.FindSubdivisionCountryByName(city)
-		if err != nil {
-			// If subdivision name does not exists skip
-			continue
-		}
-		if _, ok := (*countries)[country.Alpha2]; ok {
-			// If country already processed skip
-			continue
-		}
+// resolveAll returns the codes of the location names known at the given level
+func (g *cycle) resolveAll(level string, names []string) []string {
+	codes := make([]string, 0, len(names))
 
-		(*countries)[country.Alpha2] = true
-		(*continents)[country.Continent] = true
+	for _, name := range names {
+		if code, err := g.nodes.ResolveLocation(level, name); err == nil {
+			codes = append(codes, code)
+		}
 	}
+
+	return codes
 }
 
-func (g *location) getCountriesPredecessors(countries []string, continents *map[string]bool) {
-	for _, country := range countries {
-		country, err := g.findCountry(country)
-		if err != nil {
-			// If country name does not exists skip
-			continue
+// getLevelNodes returns the nodes in the given location codes of a topology level
+func getLevelNodes(inodes nodes.NodeLister, level string, codes []string) []*nodes.Node {
+	return inodes.GetNodes(&nodes.NodeFilter{
+		Locations: nodes.Locations{Levels: map[string][]string{level: codes}},
+	})
+}
+
+// getCandidates records the nodes matching a level in the decision and returns the ones passing the filter plugins
+func (g *cycle) getCandidates(level algorithms.Level, candidates []*nodes.Node) []*nodes.Node {
+	return g.filter(level, g.filterWithin(candidates))
+}
+
+// filterWithin drops the candidates outside the required locations when preferred locations rank them
+func (g *cycle) filterWithin(candidates []*nodes.Node) []*nodes.Node {
+	if g.within == nil {
+		return candidates
+	}
+
+	filtered := make([]*nodes.Node, 0, len(candidates))
+	for _, node := range candidates {
+		if g.within[node.Name] {
+			filtered = append(filtered, node)
 		}
-		(*continents)[country.Continent] = true
+	}
+
+	return filtered
+}
+
+// getMatchReason describes a node selected at the given level
+func (g *cycle) getMatchReason(level algorithms.Level) string {
+	switch level {
+	case algorithms.LevelExpression:
+		return g.queryType + " location expression matched"
+	case algorithms.LevelSimilar:
+		return "no node matches preferred locations, selected a node in " + g.similar
+	default:
+		return fmt.Sprintf("%s location matched at %s level", g.queryType, level)
 	}
 }
 
-func (g *location) getLocationLabelType() string {
+// newTier creates the tier of the nodes found at a level, any of them may be selected
+func (g *cycle) newTier(options []*nodes.Node, level algorithms.Level, reason string) *framework.Tier {
+	return &framework.Tier{Level: level, Nodes: options, Reason: reason}
+}
+
+func (g *cycle) getLocationLabelType() string {
 	if g.pod.Labels[labels.WorkloadRequiredLocation] != "" {
 		return "required"
 	}
 
-	if g.pod.Labels[labels.WorkloadPreferredLocation] != "" {
+	if g.hasPreferredLocation() {
 		return "preferred"
 	}
 
 	return ""
 }
 
-func (g *location) parseLocations(locations string) {
-	divisions := strings.Split(locations, "-")
-	g.cities = strings.Split(divisions[0], "_")
-	g.countries = strings.Split(divisions[1], "_")
-	g.continents = strings.Split(divisions[2], "_")
+func (g *cycle) parseLocations(label string) error {
+	expression, err := locations.ParseWithLevels(label, g.nodes.GetTopology().ExpressionLevels())
+	if err != nil {
+		return err
+	}
+
+	g.condition = expression.Condition
+	g.cities = expression.CityNames()
+	g.countries = expression.CountryNames()
+	g.continents = expression.ContinentNames()
+	return nil
 }
 
-func (g *location) findCountry(countryID string) (gountries.Country, error) {
-	if country, err := g.query.FindCountryByName(countryID); err == nil {
-		return country, nil
+// ConfigFromOptions reads the OptionBorderHops and OptionFallback options of the named algorithm
+func ConfigFromOptions(algorithm string, options algorithms.Options) (Config, error) {
+	config := DefaultConfig()
+
+	if value, ok := options[OptionBorderHops]; ok {
+		if config.BorderHops, ok = value.(int); !ok || config.BorderHops < 0 {
+			return Config{}, fmt.Errorf("%s algorithm %q option must be a non negative int", algorithm, OptionBorderHops)
+		}
 	}
 
-	if country, err := g.query.FindCountryByAlpha(countryID); err == nil {
-		return country, nil
+	if value, ok := options[OptionFallback]; ok {
+		if config.Fallback, ok = value.(string); !ok || (config.Fallback != FallbackNearest && config.Fallback != FallbackRandom) {
+			return Config{}, fmt.Errorf("%s algorithm %q option must be %q or %q", algorithm, OptionFallback, FallbackNearest, FallbackRandom)
+		}
+	}
+
+	if value, ok := options[OptionScoring]; ok {
+		scoring, err := scoringFromOption(value)
+		if err != nil {
+			return Config{}, fmt.Errorf("%s algorithm %q option: %w", algorithm, OptionScoring, err)
+		}
+
+		config.Scoring = scoring
 	}
 
-	return gountries.Country{}, errors.New("given country identifier does not match any country")
+	return config, nil
 }
 
-func getKeys(kvs map[string]bool) []string {
-	keys := make([]string, 0, len(kvs))
-	for k := range kvs {
-		keys = append(keys, k)
+func scoringFromOption(value interface{}) (*framework.ScoringStrategy, error) {
+	var strategy framework.ScoringStrategy
+
+	switch value := value.(type) {
+	case string:
+		strategy = framework.ScoringStrategy{Type: framework.ScoringStrategyType(value)}
+	case framework.ScoringStrategyType:
+		strategy = framework.ScoringStrategy{Type: value}
+	case framework.ScoringStrategy:
+		strategy = value
+	case *framework.ScoringStrategy:
+		if value == nil {
+			return nil, errors.New("scoring strategy must not be nil")
+		}
+
+		strategy = *value
+	default:
+		return nil, fmt.Errorf("must be a framework.ScoringStrategy or a strategy name, got %T", value)
+	}
+
+	if err := strategy.Validate(); err != nil {
+		return nil, err
 	}
-	return keys
+
+	return &strategy, nil
 }
@@ -1,22 +1,25 @@
 package location
 
 import (
+	"errors"
 	"github.com/geolocate-orchestration/gountries"
 	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/algorithms/framework"
 	"github.com/geolocate-orchestration/scheduler/labels"
+	"github.com/geolocate-orchestration/scheduler/locations"
 	"github.com/geolocate-orchestration/scheduler/nodes"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
 
-func newTestGeo(nodes *nodes.Nodes, pod *algorithms.Workload) *location {
+func newTestGeo(nodes *nodes.Nodes, pod *algorithms.Workload) *cycle {
 	if nodes == nil {
 		nodes = newTestNodes(nil, nil, nil, nil)
 	}
 
-	return &location{
-		query:      gountries.New(),
+	return &cycle{
 		nodes:      nodes,
+		decision:   algorithms.NewDecision(Name),
 		pod:        pod,
 		queryType:  "",
 		cities:     make([]string, 0),
@@ -96,7 +99,7 @@ func TestGetNodeRandom(t *testing.T) {
 		[]*nodes.Node{newTestNode("Node0")},
 		nil, nil, nil,
 	)
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	node, _ := geoStruct.GetNode(pod)
 	assert.Equal(t, "Node0", node.Name)
@@ -109,7 +112,7 @@ func TestGetNodeRequiredFail(t *testing.T) {
 		nil, nil, nil,
 	)
 
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	_, err := geoStruct.GetNode(pod)
 	assert.Error(t, err)
@@ -124,7 +127,7 @@ func TestGetNodeRequiredCityHit(t *testing.T) {
 		nil, nil,
 	)
 
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	node, _ := geoStruct.GetNode(pod)
 	assert.Equal(t, "Node0", node.Name)
@@ -139,7 +142,7 @@ func TestGetNodeRequiredCountryHit(t *testing.T) {
 		nil,
 	)
 
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	node, _ := geoStruct.GetNode(pod)
 	assert.Equal(t, "Node0", node.Name)
@@ -153,7 +156,7 @@ func TestGetNodeRequiredContinentHit(t *testing.T) {
 		map[string][]*nodes.Node{"EU": nodeList},
 	)
 
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	node, _ := geoStruct.GetNode(pod)
 	assert.Equal(t, "Node0", node.Name)
@@ -167,7 +170,7 @@ func TestGetNodeRequiredCityDontHitContinent(t *testing.T) {
 		map[string][]*nodes.Node{"EU": nodeList},
 	)
 
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	_, err := geoStruct.GetNode(pod)
 	assert.Error(t, err)
@@ -182,7 +185,7 @@ func TestGetNodePreferredPreferredCityHitCountry(t *testing.T) {
 		nil,
 	)
 
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	node, _ := geoStruct.GetNode(pod)
 	assert.Equal(t, "Node0", node.Name)
@@ -196,7 +199,7 @@ func TestGetNodePreferredPreferredCityHitContinent(t *testing.T) {
 		map[string][]*nodes.Node{"EU": nodeList},
 	)
 
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	node, _ := geoStruct.GetNode(pod)
 	assert.Equal(t, "Node0", node.Name)
@@ -210,7 +213,7 @@ func TestGetNodePreferredCountryHitContinent(t *testing.T) {
 		map[string][]*nodes.Node{"EU": nodeList},
 	)
 
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	node, _ := geoStruct.GetNode(pod)
 	assert.Equal(t, "Node0", node.Name)
@@ -224,7 +227,7 @@ func TestGetNodePreferredNoHit(t *testing.T) {
 		map[string][]*nodes.Node{"EU": nodeList},
 	)
 
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	node, _ := geoStruct.GetNode(pod)
 	assert.Equal(t, "Node0", node.Name)
@@ -252,7 +255,7 @@ func TestGetLocationLabelNone(t *testing.T) {
 func TestParseLocations(t *testing.T) {
 	geoStruct := newTestGeo(nil, nil)
 	locationLabel := "Braga_Porto_Madrid-PT-Europe"
-	geoStruct.parseLocations(locationLabel)
+	assert.NoError(t, geoStruct.parseLocations(locationLabel))
 
 	assert.Equal(t, 3, len(geoStruct.cities))
 	assert.Equal(t, 1, len(geoStruct.countries))
@@ -268,7 +271,7 @@ func TestNotEnoughCPUResources(t *testing.T) {
 		[]*nodes.Node{node},
 		nil, nil, nil,
 	)
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	_, err := geoStruct.GetNode(pod)
 	assert.Error(t, err)
@@ -283,14 +286,827 @@ func TestNotEnoughMemoryResources(t *testing.T) {
 		[]*nodes.Node{node},
 		nil, nil, nil,
 	)
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	_, err := geoStruct.GetNode(pod)
 	assert.Error(t, err)
 }
 
 func TestGetName(t *testing.T) {
-	geoStruct := newTestGeo(nil, nil)
+	geoStruct := New(newTestNodes(nil, nil, nil, nil))
 	name := geoStruct.GetName()
 	assert.Equal(t, "location", name)
 }
+
+func TestGetNodeWithDecisionCountryFallback(t *testing.T) {
+	pod := newTestPod("preferred", "Braga-PT-Europe")
+	full := newTestNode("Node0")
+	full.CPU = 5000
+	free := newTestNode("Node1")
+
+	nodeStruct := newTestNodes(
+		[]*nodes.Node{full, free},
+		map[string][]*nodes.Node{"PT-03": {full}},
+		map[string][]*nodes.Node{"PT": {full, free}},
+		nil,
+	)
+
+	node, decision, err := New(nodeStruct).(algorithms.Explainer).GetNodeWithDecision(pod)
+	assert.NoError(t, err)
+	assert.Equal(t, "Node1", node.Name)
+	assert.Equal(t, "Node1", decision.Node)
+	assert.Equal(t, algorithms.LevelCountry, decision.Level)
+	assert.Equal(t, []algorithms.Step{
+		{Level: algorithms.LevelCity, Candidates: 1, Feasible: 0},
+		{Level: algorithms.LevelCountry, Candidates: 2, Feasible: 1},
+	}, decision.Steps)
+	assert.Equal(t, []algorithms.RejectedNode{
+		{Node: "Node0", Level: algorithms.LevelCity, Reasons: []string{"cpu"}},
+		{Node: "Node0", Level: algorithms.LevelCountry, Reasons: []string{"cpu"}},
+	}, decision.Rejected)
+}
+
+func TestGetNodeWithDecisionSimilar(t *testing.T) {
+	pod := newTestPod("preferred", "Braga--")
+	nodeList := []*nodes.Node{newTestNode("Node0")}
+	nodeStruct := newTestNodes(
+		nodeList, nil, nil,
+		map[string][]*nodes.Node{"EU": nodeList},
+	)
+
+	_, decision, err := New(nodeStruct).(algorithms.Explainer).GetNodeWithDecision(pod)
+	assert.NoError(t, err)
+	assert.Equal(t, algorithms.LevelSimilar, decision.Level)
+}
+
+func TestGetNodeWithDecisionRandom(t *testing.T) {
+	pod := newTestPod("preferred", "Braga--")
+	nodeStruct := newTestNodes([]*nodes.Node{newTestNode("Node0")}, nil, nil, nil)
+
+	_, decision, err := New(nodeStruct).(algorithms.Explainer).GetNodeWithDecision(pod)
+	assert.NoError(t, err)
+	assert.Equal(t, algorithms.LevelRandom, decision.Level)
+	assert.Equal(t, algorithms.LevelRandom, decision.Steps[len(decision.Steps)-1].Level)
+}
+
+func TestGetNodeWithDecisionRequiredFail(t *testing.T) {
+	pod := newTestPod("required", "Braga-PT-Europe")
+	nodeStruct := newTestNodes([]*nodes.Node{newTestNode("Node0")}, nil, nil, nil)
+
+	node, decision, err := New(nodeStruct).(algorithms.Explainer).GetNodeWithDecision(pod)
+	assert.Error(t, err)
+	assert.Nil(t, node)
+	assert.Equal(t, "", decision.Node)
+	assert.Equal(t, err.Error(), decision.Reason)
+	assert.Equal(t, 3, len(decision.Steps))
+}
+
+func TestGetNodeErrNoNodes(t *testing.T) {
+	_, err := New(newTestNodes(nil, nil, nil, nil)).GetNode(newTestPod("nil", ""))
+	assert.True(t, errors.Is(err, algorithms.ErrNoNodes))
+}
+
+func TestGetNodeErrInsufficientResources(t *testing.T) {
+	node := newTestNode("Node0")
+	node.CPU = 5000
+	nodeStruct := newTestNodes([]*nodes.Node{node}, nil, nil, nil)
+
+	for _, pod := range []*algorithms.Workload{newTestPod("nil", ""), newTestPod("preferred", "Braga-PT-Europe")} {
+		_, err := New(nodeStruct).GetNode(pod)
+
+		var resourcesErr *algorithms.InsufficientResourcesError
+		assert.True(t, errors.Is(err, algorithms.ErrInsufficientResources))
+		assert.True(t, errors.As(err, &resourcesErr))
+		assert.Equal(t, "Node0", resourcesErr.Rejected[0].Node)
+	}
+}
+
+func TestGetNodeErrRequiredLocationInsufficientResources(t *testing.T) {
+	pod := newTestPod("required", "Braga-PT-Europe")
+	node := newTestNode("Node0")
+	node.Memory = 5000
+	nodeStruct := newTestNodes(
+		[]*nodes.Node{node}, nil,
+		map[string][]*nodes.Node{"PT": {node}},
+		nil,
+	)
+
+	_, err := New(nodeStruct).GetNode(pod)
+	assert.True(t, errors.Is(err, algorithms.ErrRequiredLocationUnsatisfied))
+	assert.True(t, errors.Is(err, algorithms.ErrInsufficientResources))
+	assert.False(t, errors.Is(err, algorithms.ErrUnknownLocation))
+}
+
+func TestGetNodeErrUnknownLocation(t *testing.T) {
+	pod := newTestPod("required", "Bragaa-Portugall-Europe")
+	nodeStruct := newTestNodes([]*nodes.Node{newTestNode("Node0")}, nil, nil, nil)
+
+	_, err := New(nodeStruct).GetNode(pod)
+
+	var unknownErr *algorithms.UnknownLocationError
+	assert.True(t, errors.Is(err, algorithms.ErrRequiredLocationUnsatisfied))
+	assert.True(t, errors.Is(err, algorithms.ErrUnknownLocation))
+	assert.True(t, errors.As(err, &unknownErr))
+	assert.Equal(t, []string{"Bragaa", "Portugall"}, unknownErr.Names)
+}
+
+func TestGetNodeUnknownLocationFallback(t *testing.T) {
+	pod := newTestPod("required", "Bragaa-PT-")
+	nodeList := []*nodes.Node{newTestNode("Node0")}
+	nodeStruct := newTestNodes(
+		nodeList, nil,
+		map[string][]*nodes.Node{"PT": nodeList},
+		nil,
+	)
+
+	node, err := New(nodeStruct).GetNode(pod)
+	assert.NoError(t, err)
+	assert.Equal(t, "Node0", node.Name)
+}
+
+func TestGetNodeMalformedLocation(t *testing.T) {
+	nodeStruct := newTestNodes([]*nodes.Node{newTestNode("Node0")}, nil, nil, nil)
+
+	for _, label := range []string{"Braga", "Braga-Portugal", "Braga--Europe-Earth", "Braga__Porto--"} {
+		for _, typeString := range []string{"required", "preferred"} {
+			_, err := New(nodeStruct).GetNode(newTestPod(typeString, label))
+
+			var parseErr *locations.ParseError
+			assert.True(t, errors.Is(err, algorithms.ErrInvalidLocation), label)
+			assert.True(t, errors.As(err, &parseErr), label)
+		}
+	}
+}
+
+func newTestExpressionNodes() *nodes.Nodes {
+	lisbon := newTestNode("Node0")
+	madrid := newTestNode("Node1")
+	paris := newTestNode("Node2")
+
+	return newTestNodes(
+		[]*nodes.Node{lisbon, madrid, paris},
+		map[string][]*nodes.Node{"ES-M": {madrid}},
+		map[string][]*nodes.Node{"PT": {lisbon}, "ES": {madrid}, "FR": {paris}},
+		map[string][]*nodes.Node{"EU": {lisbon, madrid, paris}},
+	)
+}
+
+func TestGetNodeRequiredExpression(t *testing.T) {
+	pod := newTestPod("required", "country in (PT, ES) and not city in (Madrid)")
+
+	for i := 0; i < 10; i++ {
+		node, decision, err := New(newTestExpressionNodes()).(algorithms.Explainer).GetNodeWithDecision(pod)
+		assert.NoError(t, err)
+		assert.Equal(t, "Node0", node.Name)
+		assert.Equal(t, algorithms.LevelExpression, decision.Level)
+	}
+}
+
+func TestGetNodeRequiredExpressionFail(t *testing.T) {
+	pod := newTestPod("required", "continent = Europe and not continent = Europe")
+
+	_, err := New(newTestExpressionNodes()).GetNode(pod)
+	assert.True(t, errors.Is(err, algorithms.ErrRequiredLocationUnsatisfied))
+
+	pod = newTestPod("required", "country = Portugall")
+
+	_, err = New(newTestExpressionNodes()).GetNode(pod)
+	assert.True(t, errors.Is(err, algorithms.ErrUnknownLocation))
+}
+
+func TestGetNodePreferredExpressionFallback(t *testing.T) {
+	pod := newTestPod("preferred", "continent = Oceania")
+
+	_, decision, err := New(newTestExpressionNodes()).(algorithms.Explainer).GetNodeWithDecision(pod)
+	assert.NoError(t, err)
+	assert.Equal(t, algorithms.LevelRandom, decision.Level)
+}
+
+func newTestForbiddenPod(typeString string, value string, forbidden string) *algorithms.Workload {
+	pod := newTestPod(typeString, value)
+	pod.Labels[labels.WorkloadForbiddenLocation] = forbidden
+	return pod
+}
+
+func TestGetNodeForbiddenFallbacks(t *testing.T) {
+	pods := []*algorithms.Workload{
+		newTestForbiddenPod("nil", "", "-ES_FR-"),
+		newTestForbiddenPod("preferred", "Madrid-ES-", "-ES_FR-"),
+		newTestForbiddenPod("preferred", "continent = Oceania", "-ES_FR-"),
+		newTestForbiddenPod("required", "--Europe", "Madrid-FR-"),
+	}
+
+	for _, pod := range pods {
+		for i := 0; i < 10; i++ {
+			node, err := New(newTestExpressionNodes()).GetNode(pod)
+			assert.NoError(t, err)
+			assert.Equal(t, "Node0", node.Name)
+		}
+	}
+}
+
+func TestGetNodeForbiddenRequired(t *testing.T) {
+	pod := newTestForbiddenPod("required", "-ES-", "--Europe")
+
+	_, decision, err := New(newTestExpressionNodes()).(algorithms.Explainer).GetNodeWithDecision(pod)
+	assert.True(t, errors.Is(err, algorithms.ErrRequiredLocationUnsatisfied))
+	assert.False(t, errors.Is(err, algorithms.ErrInsufficientResources))
+	assert.Equal(t, algorithms.ReasonForbidden, decision.Rejected[0].Reasons[0])
+}
+
+func TestGetNodeAllForbidden(t *testing.T) {
+	pod := newTestForbiddenPod("preferred", "-ES-", "--Europe")
+
+	_, err := New(newTestExpressionNodes()).GetNode(pod)
+	assert.True(t, errors.Is(err, algorithms.ErrForbiddenLocation))
+}
+
+func TestGetNodeForbiddenMalformed(t *testing.T) {
+	pod := newTestForbiddenPod("nil", "", "Europe")
+
+	_, err := New(newTestExpressionNodes()).GetNode(pod)
+	assert.True(t, errors.Is(err, algorithms.ErrInvalidLocation))
+}
+
+func newTestCombinedPod(required string, preferred string) *algorithms.Workload {
+	pod := newTestPod("required", required)
+	pod.Labels[labels.WorkloadPreferredLocation] = preferred
+	return pod
+}
+
+func TestGetNodeRequiredAndPreferred(t *testing.T) {
+	tests := []struct {
+		required  string
+		preferred string
+		nodes     []string
+		level     algorithms.Level
+	}{
+		// preferred hits within required at every level
+		{"--Europe", "Madrid--", []string{"Node1"}, algorithms.LevelCity},
+		{"--Europe", "-PT-", []string{"Node0"}, algorithms.LevelCountry},
+		{"-ES_FR-", "--Europe", []string{"Node1", "Node2"}, algorithms.LevelContinent},
+		// preferred outside required falls back to similar locations within required
+		{"-ES_FR-", "-PT-", []string{"Node1", "Node2"}, algorithms.LevelSimilar},
+		// preferred without matches within required falls back to required
+		{"-ES-", "-US-", []string{"Node1"}, algorithms.LevelCountry},
+		{"-ES_FR-", "continent = Oceania", []string{"Node1", "Node2"}, algorithms.LevelCountry},
+		{"-PT-", "-Portugall-", []string{"Node0"}, algorithms.LevelCountry},
+		// expressions on either side
+		{"country != FR", "Madrid--", []string{"Node1"}, algorithms.LevelCity},
+		{"--Europe", "country = FR", []string{"Node2"}, algorithms.LevelExpression},
+		{"country in (PT, ES)", "country = ES or country = FR", []string{"Node1"}, algorithms.LevelExpression},
+		{"country in (PT, ES)", "country = FR", []string{"Node0", "Node1"}, algorithms.LevelSimilar},
+	}
+
+	for _, test := range tests {
+		pod := newTestCombinedPod(test.required, test.preferred)
+
+		for i := 0; i < 10; i++ {
+			node, decision, err := New(newTestExpressionNodes()).(algorithms.Explainer).GetNodeWithDecision(pod)
+			assert.NoError(t, err, test.required+" "+test.preferred)
+			assert.Contains(t, test.nodes, node.Name, test.required+" "+test.preferred)
+			assert.Equal(t, test.level, decision.Level, test.required+" "+test.preferred)
+		}
+	}
+}
+
+func TestGetNodeRequiredAndPreferredReasons(t *testing.T) {
+	_, decision, _ := New(newTestExpressionNodes()).(algorithms.Explainer).GetNodeWithDecision(newTestCombinedPod("--Europe", "-PT-"))
+	assert.Equal(t, "preferred location matched at country level", decision.Reason)
+
+	_, decision, _ = New(newTestExpressionNodes()).(algorithms.Explainer).GetNodeWithDecision(newTestCombinedPod("-ES-", "-US-"))
+	assert.Equal(t, "no node matches preferred locations, selected a node matching required locations", decision.Reason)
+}
+
+func TestGetNodeRequiredAndPreferredFail(t *testing.T) {
+	_, err := New(newTestExpressionNodes()).GetNode(newTestCombinedPod("-US-", "-PT-"))
+	assert.True(t, errors.Is(err, algorithms.ErrRequiredLocationUnsatisfied))
+
+	_, err = New(newTestExpressionNodes()).GetNode(newTestCombinedPod("-Portugall-", "-PT-"))
+	assert.True(t, errors.Is(err, algorithms.ErrRequiredLocationUnsatisfied))
+	assert.True(t, errors.Is(err, algorithms.ErrUnknownLocation))
+
+	_, err = New(newTestExpressionNodes()).GetNode(newTestCombinedPod("--Europe", "Madrid"))
+	assert.True(t, errors.Is(err, algorithms.ErrInvalidLocation))
+
+	_, err = New(newTestExpressionNodes()).GetNode(newTestCombinedPod("Madrid", "--Europe"))
+	assert.True(t, errors.Is(err, algorithms.ErrInvalidLocation))
+}
+
+func TestGetNodeRequiredAndPreferredResources(t *testing.T) {
+	nodeStruct := newTestExpressionNodes()
+	nodeStruct.Countries["ES"][0].CPU = 5000
+
+	for i := 0; i < 10; i++ {
+		node, decision, err := New(nodeStruct).(algorithms.Explainer).GetNodeWithDecision(newTestCombinedPod("-PT_ES-", "-ES-"))
+		assert.NoError(t, err)
+		assert.Equal(t, "Node0", node.Name)
+		assert.Equal(t, algorithms.LevelSimilar, decision.Level)
+	}
+}
+
+func newTestWeightedNodes() nodes.INodes {
+	inodes := nodes.New()
+
+	for _, location := range [][]string{{"Porto", "Portugal"}, {"Lisboa", "Portugal"}, {"Madrid", "Spain"}, {"Paris", "France"}} {
+		inodes.AddNode(&nodes.Node{
+			Name: location[0],
+			Labels: map[string]string{
+				labels.NodeCity:      location[0],
+				labels.NodeCountry:   location[1],
+				labels.NodeContinent: "Europe",
+			},
+			CPU:    20000,
+			Memory: 20000,
+		})
+	}
+
+	return inodes
+}
+
+func newTestWeightedPod(value string) *algorithms.Workload {
+	pod := newTestPod("nil", "")
+	pod.Labels[labels.WorkloadWeightedPreferredLocation] = value
+	return pod
+}
+
+func TestGetNodeWeighted(t *testing.T) {
+	tests := []struct {
+		label  string
+		nodes  []string
+		level  algorithms.Level
+		reason string
+	}{
+		{"Porto:100, Lisboa:60, Spain:20", []string{"Porto"}, algorithms.LevelCity,
+			`weighted preferred location "Porto" matched at city level with score 100`},
+		{"Spain:20, Porto:100", []string{"Porto"}, algorithms.LevelCity,
+			`weighted preferred location "Porto" matched at city level with score 100`},
+		{"Madrid, Porto", []string{"Madrid"}, algorithms.LevelCity,
+			`weighted preferred location "Madrid" matched at city level with score 2`},
+		{"France:10, Europe:5", []string{"Paris"}, algorithms.LevelCountry,
+			`weighted preferred location "France" matched at country level with score 10`},
+		{"Europe", []string{"Porto", "Lisboa", "Madrid", "Paris"}, algorithms.LevelContinent,
+			`weighted preferred location "Europe" matched at continent level with score 1`},
+		{"Braga:100, Lisboa:40", []string{"Porto"}, algorithms.LevelSimilar,
+			`weighted preferred location "Braga" matched at similar level with score 50`},
+	}
+
+	for _, test := range tests {
+		for i := 0; i < 10; i++ {
+			node, decision, err := New(newTestWeightedNodes()).(algorithms.Explainer).GetNodeWithDecision(newTestWeightedPod(test.label))
+			assert.NoError(t, err, test.label)
+			assert.Contains(t, test.nodes, node.Name, test.label)
+			assert.Equal(t, test.level, decision.Level, test.label)
+			assert.Equal(t, test.reason, decision.Reason, test.label)
+		}
+	}
+}
+
+func TestGetNodeWeightedResources(t *testing.T) {
+	inodes := newTestWeightedNodes()
+	assert.NoError(t, inodes.AssumeWorkload("Existing", "Porto", nodes.Resources{CPU: 15000}))
+
+	node, decision, err := New(inodes).(algorithms.Explainer).GetNodeWithDecision(newTestWeightedPod("Porto:100, Lisboa:60, Spain:20"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Lisboa", node.Name)
+	assert.Equal(t, algorithms.LevelCity, decision.Level)
+
+	// Lisboa scores half of Porto weight for being in the same country
+	node, err = New(inodes).GetNode(newTestWeightedPod("Porto:100, Spain:40"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Lisboa", node.Name)
+
+	node, err = New(inodes).GetNode(newTestWeightedPod("Porto:100, Spain:60"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Madrid", node.Name)
+}
+
+func TestGetNodeWeightedListedPrecedence(t *testing.T) {
+	inodes := newTestWeightedNodes()
+	assert.NoError(t, inodes.AssumeWorkload("Existing0", "Porto", nodes.Resources{CPU: 15000}))
+	assert.NoError(t, inodes.AssumeWorkload("Existing1", "Lisboa", nodes.Resources{CPU: 15000}))
+
+	// Spain scores its own weight, not the quarter of Porto weight it scores as part of Europe
+	node, decision, err := New(inodes).(algorithms.Explainer).GetNodeWithDecision(newTestWeightedPod("Porto:100, Spain:20, France:22"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Paris", node.Name)
+	assert.Equal(t, `weighted preferred location "France" matched at country level with score 22`, decision.Reason)
+}
+
+func TestGetNodeWeightedUnknown(t *testing.T) {
+	inodes := newTestWeightedNodes()
+	assert.NoError(t, inodes.AssumeWorkload("Existing", "Porto", nodes.Resources{CPU: 15000}))
+
+	node, decision, err := New(inodes).(algorithms.Explainer).GetNodeWithDecision(newTestWeightedPod("Porto:100, Lisbon:60, Spain:20"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Lisboa", node.Name)
+	assert.Equal(t, algorithms.LevelSimilar, decision.Level)
+	assert.Equal(t, []string{"Lisbon"}, decision.Unknown)
+
+	_, decision, err = New(inodes).(algorithms.Explainer).GetNodeWithDecision(newTestWeightedPod("Porto:100, Lisboa:60"))
+	assert.NoError(t, err)
+	assert.Empty(t, decision.Unknown)
+}
+
+func TestGetNodeWeightedFallback(t *testing.T) {
+	_, decision, err := New(newTestWeightedNodes()).(algorithms.Explainer).GetNodeWithDecision(newTestWeightedPod("Atlantis:10, Oceania:5"))
+	assert.NoError(t, err)
+	assert.Equal(t, algorithms.LevelRandom, decision.Level)
+
+	pod := newTestWeightedPod("Madrid:100, Paris:10")
+	pod.Labels[labels.WorkloadRequiredLocation] = "-PT_FR-"
+
+	node, err := New(newTestWeightedNodes()).GetNode(pod)
+	assert.NoError(t, err)
+	assert.NotEqual(t, "Madrid", node.Name)
+}
+
+func TestGetNodeWeightedOverridesPreferred(t *testing.T) {
+	pod := newTestWeightedPod("Madrid")
+	pod.Labels[labels.WorkloadPreferredLocation] = "-FR-"
+
+	node, err := New(newTestWeightedNodes()).GetNode(pod)
+	assert.NoError(t, err)
+	assert.Equal(t, "Madrid", node.Name)
+}
+
+func TestGetNodeWeightedMalformed(t *testing.T) {
+	_, err := New(newTestWeightedNodes()).GetNode(newTestWeightedPod("Porto:abc"))
+	assert.True(t, errors.Is(err, algorithms.ErrInvalidLocation))
+}
+
+// newTestTopologyNodes returns rack, zone and datacenter located nodes, the full ones lack resources
+func newTestTopologyNodes(t *testing.T, full ...string) nodes.INodes {
+	topology, err := nodes.NewTopology(
+		nodes.TopologyLevel{Name: "rack", Label: "node.geolocate.io/rack", Parent: "zone"},
+		nodes.TopologyLevel{Name: "zone", Label: "node.geolocate.io/zone", Parent: "datacenter"},
+		nodes.TopologyLevel{Name: "datacenter", Label: "node.geolocate.io/datacenter"},
+	)
+	assert.NoError(t, err)
+
+	inodes := nodes.NewWithTopology(topology)
+	for _, location := range [][4]string{
+		{"Node0", "r1", "z1", "dc1"},
+		{"Node1", "r2", "z1", "dc1"},
+		{"Node2", "r3", "z2", "dc1"},
+		{"Node3", "r4", "z3", "dc2"},
+	} {
+		node := newTestNode(location[0])
+		for _, name := range full {
+			if name == node.Name {
+				node.CPU, node.Memory = 1, 1
+			}
+		}
+
+		node.Labels = map[string]string{
+			labels.Node:                    "",
+			"node.geolocate.io/rack":       location[1],
+			"node.geolocate.io/zone":       location[2],
+			"node.geolocate.io/datacenter": location[3],
+		}
+		inodes.AddNode(node)
+	}
+
+	return inodes
+}
+
+func TestGetNodeWeightedTopology(t *testing.T) {
+	node, decision, err := New(newTestTopologyNodes(t)).(algorithms.Explainer).GetNodeWithDecision(newTestWeightedPod("z2:10, dc2:5"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Node2", node.Name)
+	assert.Equal(t, algorithms.Level("zone"), decision.Level)
+	assert.Empty(t, decision.Unknown)
+
+	// the other nodes of dc1 score half of z2 weight, more than dc2
+	node, decision, err = New(newTestTopologyNodes(t, "Node2")).(algorithms.Explainer).GetNodeWithDecision(newTestWeightedPod("z2:10, dc2:4"))
+	assert.NoError(t, err)
+	assert.Contains(t, []string{"Node0", "Node1"}, node.Name)
+	assert.Equal(t, algorithms.LevelSimilar, decision.Level)
+
+	_, decision, err = New(newTestTopologyNodes(t)).(algorithms.Explainer).GetNodeWithDecision(newTestWeightedPod("z9:10, r4:5"))
+	assert.NoError(t, err)
+	assert.Equal(t, algorithms.Level("rack"), decision.Level)
+	assert.Equal(t, []string{"z9"}, decision.Unknown)
+}
+
+func TestGetNodeTopologyFallback(t *testing.T) {
+	tests := []struct {
+		label string
+		full  []string
+		nodes []string
+		level algorithms.Level
+	}{
+		{"zone = z2", nil, []string{"Node2"}, algorithms.LevelExpression},
+		// full rack falls back to the other racks of its zone
+		{"rack = r1", []string{"Node0"}, []string{"Node1"}, algorithms.LevelSimilar},
+		// full zone falls back to the other zones of its datacenter
+		{"zone = z2", []string{"Node2"}, []string{"Node0", "Node1"}, algorithms.LevelSimilar},
+		// a full top level location has nowhere to walk up to
+		{"rack = r1", []string{"Node0", "Node1", "Node2"}, []string{"Node3"}, algorithms.LevelRandom},
+		{"datacenter = dc3", nil, []string{"Node0", "Node1", "Node2", "Node3"}, algorithms.LevelRandom},
+	}
+
+	for _, test := range tests {
+		inodes := newTestTopologyNodes(t, test.full...)
+
+		node, decision, err := New(inodes).(algorithms.Explainer).GetNodeWithDecision(newTestPod("preferred", test.label))
+		assert.NoError(t, err, test.label)
+		assert.Contains(t, test.nodes, node.Name, test.label)
+		assert.Equal(t, test.level, decision.Level, test.label)
+	}
+}
+
+func TestGetNodeTopologyRequired(t *testing.T) {
+	_, err := New(newTestTopologyNodes(t)).GetNode(newTestPod("required", "zone = z4"))
+	assert.True(t, errors.Is(err, algorithms.ErrRequiredLocationUnsatisfied))
+
+	_, err = New(newTestTopologyNodes(t)).GetNode(newTestPod("required", "city = Braga"))
+	assert.True(t, errors.Is(err, algorithms.ErrInvalidLocation))
+}
+
+func newTestSiteNodes(full bool) nodes.INodes {
+	catalog := locations.NewCatalog()
+	catalog.MustRegister(locations.Site{Name: "edge-site-42", Country: "Portugal"})
+
+	inodes := nodes.NewWithCatalog(nodes.DefaultTopology(), catalog)
+	for _, location := range [][]string{{"Node0", "edge-site-42", "Portugal"}, {"Node1", "Lisboa", "Portugal"}, {"Node2", "Madrid", "Spain"}} {
+		node := newTestNode(location[0])
+		node.Labels = map[string]string{labels.Node: "", labels.NodeCity: location[1], labels.NodeCountry: location[2]}
+		if full && node.Name == "Node0" {
+			node.CPU = 1
+		}
+		inodes.AddNode(node)
+	}
+
+	return inodes
+}
+
+func TestGetNodeCatalogSites(t *testing.T) {
+	node, decision, err := New(newTestSiteNodes(false)).(algorithms.Explainer).GetNodeWithDecision(newTestPod("required", "city = edge-site-42"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Node0", node.Name)
+	assert.Equal(t, algorithms.LevelExpression, decision.Level)
+
+	node, decision, err = New(newTestSiteNodes(true)).(algorithms.Explainer).GetNodeWithDecision(newTestPod("preferred", "city = edge-site-42"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Node1", node.Name)
+	assert.Equal(t, algorithms.LevelSimilar, decision.Level)
+
+	node, decision, err = New(newTestSiteNodes(false)).(algorithms.Explainer).GetNodeWithDecision(newTestWeightedPod("edge-site-42:10, Spain:5"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Node0", node.Name)
+	assert.Equal(t, algorithms.LevelCity, decision.Level)
+
+	_, err = New(newTestSiteNodes(false)).GetNode(newTestPod("required", "city = edge-site-43"))
+	assert.True(t, errors.Is(err, algorithms.ErrUnknownLocation))
+}
+
+// newTestBorderNodes returns a node named after each country, located in it
+func newTestBorderNodes(countries ...string) nodes.INodes {
+	inodes := nodes.New()
+
+	for _, country := range countries {
+		node := newTestNode(country)
+		node.Labels = map[string]string{labels.Node: "", labels.NodeCountry: country, labels.NodeContinent: "Europe"}
+		inodes.AddNode(node)
+	}
+
+	return inodes
+}
+
+func TestGetNodeBorderingCountries(t *testing.T) {
+	tests := []struct {
+		hops      int
+		label     string
+		countries []string
+		nodes     []string
+		reason    string
+	}{
+		{1, "-PT-", []string{"Spain", "France", "Finland"}, []string{"Spain"}, "a bordering country"},
+		{2, "-PT-", []string{"France", "Finland"}, []string{"France"}, "a country 2 borders away"},
+		{1, "-PT-", []string{"France", "Finland"}, []string{"France", "Finland"}, "the same continent"},
+		{0, "-PT-", []string{"Spain", "Finland"}, []string{"Spain", "Finland"}, "the same continent"},
+		{1, "Porto--", []string{"Portugal", "Spain"}, []string{"Portugal"}, "the same country"},
+		{1, "Porto--", []string{"Spain", "Finland"}, []string{"Spain"}, "a bordering country"},
+		{1, "country = PT or city = Paris", []string{"Spain", "Germany", "Finland"}, []string{"Spain", "Germany"}, "a bordering country"},
+	}
+
+	for _, test := range tests {
+		algorithm := NewWithConfig(newTestBorderNodes(test.countries...), Config{BorderHops: test.hops})
+
+		for i := 0; i < 10; i++ {
+			node, decision, err := algorithm.(algorithms.Explainer).GetNodeWithDecision(newTestPod("preferred", test.label))
+			assert.NoError(t, err, test.label)
+			assert.Contains(t, test.nodes, node.Name, test.label)
+			assert.Equal(t, algorithms.LevelSimilar, decision.Level, test.label)
+			assert.Equal(t, "no node matches preferred locations, selected a node in "+test.reason, decision.Reason, test.label)
+		}
+	}
+}
+
+func TestBorderHopsOption(t *testing.T) {
+	inodes := newTestBorderNodes("France", "Finland")
+
+	algorithm, err := algorithms.New(Name, inodes, algorithms.Options{OptionBorderHops: 2})
+	assert.NoError(t, err)
+
+	node, err := algorithm.GetNode(newTestPod("preferred", "-PT-"))
+	assert.NoError(t, err)
+	assert.Equal(t, "France", node.Name)
+
+	for _, value := range []interface{}{-1, "2", 2.0} {
+		_, err = algorithms.New(Name, inodes, algorithms.Options{OptionBorderHops: value})
+		assert.Error(t, err, value)
+	}
+}
+
+// newTestCountryNodes returns a node named after each country, only labeled with it
+func newTestCountryNodes(countries ...string) nodes.INodes {
+	inodes := nodes.New()
+
+	for _, country := range countries {
+		node := newTestNode(country)
+		node.Labels = map[string]string{labels.Node: "", labels.NodeCountry: country}
+		inodes.AddNode(node)
+	}
+
+	return inodes
+}
+
+func TestGetNodeNearestFallback(t *testing.T) {
+	inodes := newTestCountryNodes("United States", "Morocco", "Japan")
+
+	for _, label := range []string{"-PT-", "Porto--", "country = PT", "city = Porto"} {
+		node, decision, err := New(inodes).(algorithms.Explainer).GetNodeWithDecision(newTestPod("preferred", label))
+		assert.NoError(t, err, label)
+		assert.Equal(t, "Morocco", node.Name, label)
+		assert.Equal(t, algorithms.LevelDistance, decision.Level, label)
+		assert.Contains(t, decision.Reason, "selected the nearest node", label)
+	}
+
+	node, _, err := New(inodes).(algorithms.Explainer).GetNodeWithDecision(newTestWeightedPod("Tokyo:10, Lisboa:5"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Japan", node.Name)
+
+	// preferred locations that can't be located fall back to a random node
+	_, decision, err := New(inodes).(algorithms.Explainer).GetNodeWithDecision(newTestPod("preferred", "--Europe"))
+	assert.NoError(t, err)
+	assert.Equal(t, algorithms.LevelRandom, decision.Level)
+}
+
+func TestGetNodeNearestFallbackUnlocated(t *testing.T) {
+	inodes := newTestCountryNodes("Morocco")
+	unlocated := newTestNode("Unlocated")
+	unlocated.Labels = map[string]string{labels.Node: ""}
+	inodes.AddNode(unlocated)
+
+	node, _ := New(inodes).GetNode(newTestPod("preferred", "-PT-"))
+	assert.Equal(t, "Morocco", node.Name)
+
+	assert.NoError(t, inodes.AssumeWorkload("Existing", "Morocco", nodes.Resources{CPU: 15000}))
+
+	node, decision, err := New(inodes).(algorithms.Explainer).GetNodeWithDecision(newTestPod("preferred", "-PT-"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Unlocated", node.Name)
+	assert.Equal(t, algorithms.LevelRandom, decision.Level)
+}
+
+func TestFallbackOption(t *testing.T) {
+	inodes := newTestCountryNodes("United States", "Morocco", "Japan")
+
+	algorithm, err := algorithms.New(Name, inodes, algorithms.Options{OptionFallback: FallbackRandom})
+	assert.NoError(t, err)
+
+	_, decision, err := algorithm.(algorithms.Explainer).GetNodeWithDecision(newTestPod("preferred", "-PT-"))
+	assert.NoError(t, err)
+	assert.Equal(t, algorithms.LevelRandom, decision.Level)
+
+	_, err = algorithms.New(Name, inodes, algorithms.Options{OptionFallback: "closest"})
+	assert.Error(t, err)
+}
+
+// cheapest scores the nodes by their lowest CPU, as an operator defined score plugin
+type cheapest struct{}
+
+func (cheapest) Name() string {
+	return "Cheapest"
+}
+
+func (cheapest) Score(_ *framework.CycleState, node *nodes.Node) (int64, error) {
+	return node.CPU, nil
+}
+
+func (p cheapest) ScoreExtensions() framework.ScoreExtensions {
+	return p
+}
+
+func (cheapest) NormalizeScore(_ *framework.CycleState, scores framework.NodeScoreList) error {
+	framework.NormalizeMinMax(scores, true)
+	return nil
+}
+
+func TestProfileCustomScore(t *testing.T) {
+	inodes := nodes.New()
+	for _, node := range []struct {
+		city, country string
+		cpu           int64
+	}{{"Porto", "PT", 30000}, {"Lisboa", "PT", 25000}, {"Madrid", "ES", 20000}} {
+		inodes.AddNode(&nodes.Node{
+			Name:   node.city,
+			Labels: map[string]string{labels.NodeCity: node.city, labels.NodeCountry: node.country},
+			CPU:    node.cpu,
+			Memory: 20000,
+		})
+	}
+
+	profile := Profile(DefaultConfig())
+	profile.Name = "location-cheapest"
+	profile.Score = append(profile.Score, framework.WeightedScorePlugin{Plugin: cheapest{}, Weight: 1})
+	algorithm := framework.New(inodes, profile)
+
+	for i := 0; i < 10; i++ {
+		// location filtering still applies, scoring only ranks the nodes of the matching level
+		node, decision, err := algorithm.GetNodeWithDecision(newTestPod("required", "-PT-"))
+		assert.NoError(t, err)
+		assert.Equal(t, "Lisboa", node.Name)
+		assert.Equal(t, "location-cheapest", decision.Algorithm)
+		assert.Equal(t, algorithms.LevelCountry, decision.Level)
+	}
+}
+
+func TestGetRankedNodes(t *testing.T) {
+	inodes := newTestWeightedNodes()
+	ranker := New(inodes).(algorithms.Ranker)
+
+	ranked, err := ranker.GetRankedNodes(newTestPod("preferred", "Porto--"), 10)
+	assert.NoError(t, err)
+
+	levels := make([]algorithms.Level, 0)
+	for _, node := range ranked {
+		levels = append(levels, node.Level)
+	}
+
+	// the preferred city, its country, a bordering country and finally the rest of the continent
+	assert.Equal(t, "Porto", ranked[0].Node.Name)
+	assert.Equal(t, "Lisboa", ranked[1].Node.Name)
+	assert.Equal(t, "Madrid", ranked[2].Node.Name)
+	assert.Equal(t, "Paris", ranked[3].Node.Name)
+	assert.Equal(t, []algorithms.Level{algorithms.LevelCity, algorithms.LevelSimilar, algorithms.LevelSimilar, algorithms.LevelSimilar}, levels)
+	assert.Equal(t, "no node matches preferred locations, selected a node in a bordering country", ranked[2].Reason)
+
+	// required locations are never left
+	ranked, err = ranker.GetRankedNodes(newTestPod("required", "-PT-"), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(ranked))
+
+	_, err = ranker.GetRankedNodes(newTestPod("required", "-DE-"), 10)
+	assert.True(t, errors.Is(err, algorithms.ErrRequiredLocationUnsatisfied))
+}
+
+func TestScoringOption(t *testing.T) {
+	inodes := nodes.New()
+	for _, node := range []struct {
+		name, country string
+		requested     int64
+	}{{"Porto", "PT", 5000}, {"Lisboa", "PT", 10000}, {"Madrid", "ES", 15000}} {
+		inodes.AddNode(&nodes.Node{
+			Name:   node.name,
+			Labels: map[string]string{labels.NodeCountry: node.country},
+			CPU:    20000,
+			Memory: 20000,
+		})
+		assert.NoError(t, inodes.BindWorkload("workload-"+node.name, node.name, nodes.Resources{CPU: node.requested, Memory: node.requested}))
+	}
+
+	pod := newTestPod("preferred", "-PT-")
+	pod.CPU, pod.Memory = 1000, 1000
+
+	tests := []struct {
+		option   interface{}
+		expected string
+	}{
+		{"MostAllocated", "Lisboa"},
+		{framework.LeastAllocated, "Porto"},
+		{framework.ScoringStrategy{Type: framework.MostAllocated}, "Lisboa"},
+		{&framework.ScoringStrategy{
+			Type:  framework.RequestedToCapacityRatio,
+			Shape: []framework.UtilizationShapePoint{{Utilization: 30, Score: 100}, {Utilization: 60, Score: 0}},
+		}, "Porto"},
+	}
+
+	for _, test := range tests {
+		algorithm, err := algorithms.New(Name, inodes, algorithms.Options{OptionScoring: test.option})
+		assert.NoError(t, err)
+
+		for i := 0; i < 10; i++ {
+			// resources only rank the nodes of the matched level, Madrid is never selected
+			node, err := algorithm.GetNode(pod)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expected, node.Name, "%v", test.option)
+		}
+	}
+	for _, option := range []interface{}{"BinPacking", 1, framework.ScoringStrategy{Type: framework.RequestedToCapacityRatio}} {
+		_, err := algorithms.New(Name, inodes, algorithms.Options{OptionScoring: option})
+		assert.Error(t, err)
+	}
+}
@@ -1,30 +1,17 @@
 package naivelocation
 
 import (
+	"errors"
 	"github.com/geolocate-orchestration/gountries"
 	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/algorithms/location"
 	"github.com/geolocate-orchestration/scheduler/labels"
+	"github.com/geolocate-orchestration/scheduler/locations"
 	"github.com/geolocate-orchestration/scheduler/nodes"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
 
-func newTestGeo(nodes *nodes.Nodes, pod *algorithms.Workload) *naivelocation {
-	if nodes == nil {
-		nodes = newTestNodes(nil, nil, nil, nil)
-	}
-
-	return &naivelocation{
-		query:      gountries.New(),
-		nodes:      nodes,
-		pod:        pod,
-		queryType:  "",
-		cities:     make([]string, 0),
-		countries:  make([]string, 0),
-		continents: make([]string, 0),
-	}
-}
-
 func newTestNodes(
 	nodesList []*nodes.Node, citiesList map[string][]*nodes.Node,
 	countriesList map[string][]*nodes.Node, continentList map[string][]*nodes.Node,
@@ -92,7 +79,7 @@ func TestGetNodeRandom(t *testing.T) {
 		[]*nodes.Node{newTestNode("Node0")},
 		nil, nil, nil,
 	)
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	node, _ := geoStruct.GetNode(pod)
 	assert.Equal(t, "Node0", node.Name)
@@ -105,7 +92,7 @@ func TestGetNodeRequiredFail(t *testing.T) {
 		nil, nil, nil,
 	)
 
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	_, err := geoStruct.GetNode(pod)
 	assert.Error(t, err)
@@ -120,7 +107,7 @@ func TestGetNodeRequiredCityHit(t *testing.T) {
 		nil, nil,
 	)
 
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	node, _ := geoStruct.GetNode(pod)
 	assert.Equal(t, "Node0", node.Name)
@@ -135,7 +122,7 @@ func TestGetNodeRequiredCountryHit(t *testing.T) {
 		nil,
 	)
 
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	node, _ := geoStruct.GetNode(pod)
 	assert.Equal(t, "Node0", node.Name)
@@ -149,7 +136,7 @@ func TestGetNodeRequiredContinentHit(t *testing.T) {
 		map[string][]*nodes.Node{"EU": nodeList},
 	)
 
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	node, _ := geoStruct.GetNode(pod)
 	assert.Equal(t, "Node0", node.Name)
@@ -163,7 +150,7 @@ func TestGetNodeRequiredCityDontHitContinent(t *testing.T) {
 		map[string][]*nodes.Node{"EU": nodeList},
 	)
 
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	_, err := geoStruct.GetNode(pod)
 	assert.Error(t, err)
@@ -178,7 +165,7 @@ func TestGetNodePreferredPreferredCityHitCountry(t *testing.T) {
 		nil,
 	)
 
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	node, _ := geoStruct.GetNode(pod)
 	assert.Equal(t, "Node0", node.Name)
@@ -192,7 +179,7 @@ func TestGetNodePreferredPreferredCityHitContinent(t *testing.T) {
 		map[string][]*nodes.Node{"EU": nodeList},
 	)
 
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	node, _ := geoStruct.GetNode(pod)
 	assert.Equal(t, "Node0", node.Name)
@@ -206,7 +193,7 @@ func TestGetNodePreferredCountryHitContinent(t *testing.T) {
 		map[string][]*nodes.Node{"EU": nodeList},
 	)
 
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	node, _ := geoStruct.GetNode(pod)
 	assert.Equal(t, "Node0", node.Name)
@@ -220,43 +207,184 @@ func TestGetNodePreferredNoHit(t *testing.T) {
 		map[string][]*nodes.Node{"EU": nodeList},
 	)
 
-	geoStruct := newTestGeo(nodeStruct, pod)
+	geoStruct := New(nodeStruct)
 
 	node, _ := geoStruct.GetNode(pod)
 	assert.Equal(t, "Node0", node.Name)
 }
 
-func getLocationLabel(t *testing.T, typeString string) {
-	pod := newTestPod(typeString, "1")
-	geoStruct := newTestGeo(nil, pod)
-	r := geoStruct.getLocationLabelType()
-	assert.Equal(t, typeString, r)
+func TestGetName(t *testing.T) {
+	geoStruct := New(newTestNodes(nil, nil, nil, nil))
+	name := geoStruct.GetName()
+	assert.Equal(t, "naivelocation", name)
 }
 
-func TestGetLocationLabelRequired(t *testing.T) {
-	getLocationLabel(t, "required")
+func TestGetNodeWithDecision(t *testing.T) {
+	pod := newTestPod("preferred", "Braga-PT-Europe")
+	nodeList := []*nodes.Node{newTestNode("Node0")}
+	nodeStruct := newTestNodes(
+		nodeList, nil,
+		map[string][]*nodes.Node{"PT": nodeList},
+		nil,
+	)
+
+	node, decision, err := New(nodeStruct).(algorithms.Explainer).GetNodeWithDecision(pod)
+	assert.NoError(t, err)
+	assert.Equal(t, "Node0", node.Name)
+	assert.Equal(t, algorithms.LevelCountry, decision.Level)
+	assert.Equal(t, 2, len(decision.Steps))
 }
 
-func TestGetLocationLabelPreferred(t *testing.T) {
-	getLocationLabel(t, "preferred")
+func TestGetNodeErrors(t *testing.T) {
+	_, err := New(newTestNodes(nil, nil, nil, nil)).GetNode(newTestPod("nil", ""))
+	assert.True(t, errors.Is(err, algorithms.ErrNoNodes))
+
+	nodeStruct := newTestNodes([]*nodes.Node{newTestNode("Node0")}, nil, nil, nil)
+	_, err = New(nodeStruct).GetNode(newTestPod("required", "Bragaa-PT-"))
+
+	var unknownErr *algorithms.UnknownLocationError
+	assert.True(t, errors.Is(err, algorithms.ErrRequiredLocationUnsatisfied))
+	assert.True(t, errors.As(err, &unknownErr))
+	assert.Equal(t, []string{"Bragaa"}, unknownErr.Names)
 }
 
-func TestGetLocationLabelNone(t *testing.T) {
-	getLocationLabel(t, "")
+func TestGetNodeMalformedLocation(t *testing.T) {
+	nodeStruct := newTestNodes([]*nodes.Node{newTestNode("Node0")}, nil, nil, nil)
+
+	for _, label := range []string{"Braga", "Braga-Portugal", "Braga--Europe-Earth", "Braga__Porto--"} {
+		for _, typeString := range []string{"required", "preferred"} {
+			_, err := New(nodeStruct).GetNode(newTestPod(typeString, label))
+
+			var parseErr *locations.ParseError
+			assert.True(t, errors.Is(err, algorithms.ErrInvalidLocation), label)
+			assert.True(t, errors.As(err, &parseErr), label)
+		}
+	}
 }
 
-func TestParseLocations(t *testing.T) {
-	geoStruct := newTestGeo(nil, nil)
-	locationLabel := "Braga_Porto_Madrid-PT-Europe"
-	geoStruct.parseLocations(locationLabel)
+func TestGetNodeForbidden(t *testing.T) {
+	lisbon := newTestNode("Node0")
+	madrid := newTestNode("Node1")
+	nodeStruct := newTestNodes(
+		[]*nodes.Node{lisbon, madrid}, nil,
+		map[string][]*nodes.Node{"PT": {lisbon}, "ES": {madrid}},
+		map[string][]*nodes.Node{"EU": {lisbon, madrid}},
+	)
+
+	pod := newTestPod("preferred", "-FR-")
+	pod.Labels[labels.WorkloadForbiddenLocation] = "-ES-"
 
-	assert.Equal(t, 3, len(geoStruct.cities))
-	assert.Equal(t, 1, len(geoStruct.countries))
-	assert.Equal(t, 1, len(geoStruct.continents))
+	for i := 0; i < 10; i++ {
+		node, err := New(nodeStruct).GetNode(pod)
+		assert.NoError(t, err)
+		assert.Equal(t, "Node0", node.Name)
+	}
 }
 
-func TestGetName(t *testing.T) {
-	geoStruct := newTestGeo(nil, nil)
-	name := geoStruct.GetName()
-	assert.Equal(t, "naivelocation", name)
+func TestGetNodeRequiredAndPreferred(t *testing.T) {
+	lisbon := newTestNode("Node0")
+	madrid := newTestNode("Node1")
+	paris := newTestNode("Node2")
+	nodeStruct := newTestNodes(
+		[]*nodes.Node{lisbon, madrid, paris}, nil,
+		map[string][]*nodes.Node{"PT": {lisbon}, "ES": {madrid}, "FR": {paris}},
+		map[string][]*nodes.Node{"EU": {lisbon, madrid, paris}},
+	)
+
+	pod := newTestPod("required", "-PT_ES-")
+	pod.Labels[labels.WorkloadPreferredLocation] = "-ES-"
+
+	node, err := New(nodeStruct).GetNode(pod)
+	assert.NoError(t, err)
+	assert.Equal(t, "Node1", node.Name)
+
+	pod.Labels[labels.WorkloadPreferredLocation] = "-FR-"
+
+	for i := 0; i < 10; i++ {
+		node, err = New(nodeStruct).GetNode(pod)
+		assert.NoError(t, err)
+		assert.NotEqual(t, "Node2", node.Name)
+	}
+
+	pod.Labels[labels.WorkloadRequiredLocation] = "-US-"
+
+	_, err = New(nodeStruct).GetNode(pod)
+	assert.True(t, errors.Is(err, algorithms.ErrRequiredLocationUnsatisfied))
+}
+
+func TestGetNodeTopologyFallback(t *testing.T) {
+	topology, err := nodes.NewTopology(
+		nodes.TopologyLevel{Name: "zone", Label: "node.geolocate.io/zone", Parent: "region"},
+		nodes.TopologyLevel{Name: "region", Label: "node.geolocate.io/region"},
+	)
+	assert.NoError(t, err)
+
+	inodes := nodes.NewWithTopology(topology)
+	added := make([]*nodes.Node, 0)
+	for _, location := range [][3]string{{"Node0", "z1", "west"}, {"Node1", "z2", "west"}, {"Node2", "z3", "east"}} {
+		node := newTestNode(location[0])
+		node.Labels = map[string]string{labels.Node: "", "node.geolocate.io/zone": location[1], "node.geolocate.io/region": location[2]}
+		inodes.AddNode(node)
+		added = append(added, node)
+	}
+
+	node, decision, err := New(inodes).(algorithms.Explainer).GetNodeWithDecision(newTestPod("preferred", "zone = z4 or region = West"))
+	assert.NoError(t, err)
+	assert.Contains(t, []string{"Node0", "Node1"}, node.Name)
+	assert.Equal(t, algorithms.LevelExpression, decision.Level)
+
+	inodes.DeleteNode(added[0])
+	inodes.DeleteNode(added[1])
+	inodes.AddNode(&nodes.Node{Name: "Node3", Labels: map[string]string{labels.Node: "", "node.geolocate.io/zone": "z1", "node.geolocate.io/region": "east"}})
+
+	// zone z1 is now in the east region
+	node, decision, err = New(inodes).(algorithms.Explainer).GetNodeWithDecision(newTestPod("preferred", "zone = z1 and not region = east"))
+	assert.NoError(t, err)
+	assert.Contains(t, []string{"Node2", "Node3"}, node.Name)
+	assert.Equal(t, algorithms.LevelSimilar, decision.Level)
+}
+
+func TestGetNodeBorderingCountries(t *testing.T) {
+	inodes := nodes.New()
+	for _, country := range []string{"France", "Finland"} {
+		inodes.AddNode(&nodes.Node{Name: country, Labels: map[string]string{labels.NodeCountry: country, labels.NodeContinent: "Europe"}})
+	}
+
+	node, err := NewWithConfig(inodes, location.Config{BorderHops: 2}).GetNode(newTestPod("preferred", "-PT-"))
+	assert.NoError(t, err)
+	assert.Equal(t, "France", node.Name)
+
+	_, err = algorithms.New(Name, inodes, algorithms.Options{location.OptionBorderHops: "2"})
+	assert.Error(t, err)
+}
+
+func TestGetNodeNearestFallback(t *testing.T) {
+	inodes := nodes.New()
+	for _, country := range []string{"United States", "Morocco", "Japan"} {
+		inodes.AddNode(&nodes.Node{Name: country, Labels: map[string]string{labels.NodeCountry: country}})
+	}
+
+	node, decision, err := New(inodes).(algorithms.Explainer).GetNodeWithDecision(newTestPod("preferred", "-PT-"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Morocco", node.Name)
+	assert.Equal(t, algorithms.LevelDistance, decision.Level)
+
+	_, decision, err = NewWithConfig(inodes, location.Config{Fallback: location.FallbackRandom}).(algorithms.Explainer).GetNodeWithDecision(newTestPod("preferred", "-PT-"))
+	assert.NoError(t, err)
+	assert.Equal(t, algorithms.LevelRandom, decision.Level)
+}
+
+func TestGetNodeIgnoresWeighted(t *testing.T) {
+	inodes := nodes.New()
+	for _, country := range []string{"PT", "ES"} {
+		inodes.AddNode(&nodes.Node{Name: country, Labels: map[string]string{labels.NodeCountry: country}})
+	}
+
+	pod := newTestPod("preferred", "-ES-")
+	pod.Labels[labels.WorkloadWeightedPreferredLocation] = "PT:100"
+
+	node, decision, err := New(inodes).(algorithms.Explainer).GetNodeWithDecision(pod)
+	assert.NoError(t, err)
+	assert.Equal(t, "ES", node.Name)
+	assert.Equal(t, algorithms.LevelCountry, decision.Level)
 }
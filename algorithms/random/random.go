@@ -1,41 +1,32 @@
 package random
 
 import (
-	"errors"
 	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/algorithms/framework"
 	"github.com/geolocate-orchestration/scheduler/nodes"
-	"k8s.io/klog/v2"
 )
 
-type random struct {
-	inodes nodes.INodes
-}
-
-// New creates new random struct
-func New(inodes nodes.INodes) algorithms.Algorithm {
-	return &random{
-		inodes: inodes,
-	}
-}
+// Name is the name under which the random algorithm is registered
+const Name = "random"
 
-func (r random) GetName() string {
-	return "random"
+func init() {
+	algorithms.MustRegister(Name, func(inodes nodes.INodes, _ algorithms.Options) (algorithms.Algorithm, error) {
+		return New(inodes), nil
+	})
 }
 
-func (r random) GetNode(*algorithms.Workload) (*nodes.Node, error) {
-	klog.Infoln("getting cached nodes")
-	return getRandomNode(r.inodes)
+// New creates new random algorithm
+// Nodes in the workload forbidden locations or without enough resources for it are never selected
+func New(inodes nodes.INodes) algorithms.Algorithm {
+	return framework.New(inodes, Profile())
 }
 
-// GetRandomNode returns a random
-func getRandomNode(inodes nodes.INodes) (*nodes.Node, error) {
-	allNodes := inodes.GetAllNodes()
-
-	if len(allNodes) == 0 {
-		errMessage := "no nodes are available"
-		return nil, errors.New(errMessage)
+// Profile returns the plugins of the random algorithm
+func Profile() framework.Profile {
+	return framework.Profile{
+		Name:       Name,
+		PreFilter:  []framework.PreFilterPlugin{framework.ForbiddenLocation{}},
+		Filter:     []framework.FilterPlugin{framework.ForbiddenLocation{}, framework.NodeResources{}},
+		Candidates: framework.AllNodes{},
 	}
-
-	klog.Infof("will randomly get 1 node from the %d available\n", len(allNodes))
-	return nodes.GetRandomFromList(allNodes)
 }
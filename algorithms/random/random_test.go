@@ -1,8 +1,10 @@
 package random
 
 import (
+	"errors"
 	"github.com/geolocate-orchestration/gountries"
 	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/labels"
 	"github.com/geolocate-orchestration/scheduler/nodes"
 	"github.com/stretchr/testify/assert"
 	"testing"
@@ -31,7 +33,7 @@ func TestGetNodeEmpty(t *testing.T) {
 }
 
 func TestGetNode(t *testing.T) {
-	node, _ := getRandomNode(newTestRandomWithNode())
+	node, _ := New(newTestRandomWithNode()).GetNode(nil)
 	assert.Equal(t, "Node0", node.Name)
 }
 
@@ -40,3 +42,38 @@ func TestGetName(t *testing.T) {
 	name := randomStruct.GetName()
 	assert.Equal(t, "random", name)
 }
+
+func TestGetNodeWithDecision(t *testing.T) {
+	node, decision, err := New(newTestRandomWithNode()).(algorithms.Explainer).GetNodeWithDecision(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Node0", node.Name)
+	assert.Equal(t, "Node0", decision.Node)
+	assert.Equal(t, algorithms.LevelAny, decision.Level)
+
+	_, decision, err = newTestRandom().(algorithms.Explainer).GetNodeWithDecision(nil)
+	assert.Error(t, err)
+	assert.Equal(t, err.Error(), decision.Reason)
+}
+
+func TestGetNodeErrNoNodes(t *testing.T) {
+	_, err := newTestRandom().GetNode(nil)
+	assert.True(t, errors.Is(err, algorithms.ErrNoNodes))
+}
+
+func TestGetNodeForbidden(t *testing.T) {
+	inodes := nodes.New()
+	inodes.AddNode(&nodes.Node{Name: "Node0", Labels: map[string]string{labels.NodeCountry: "Portugal"}})
+	inodes.AddNode(&nodes.Node{Name: "Node1", Labels: map[string]string{labels.NodeCountry: "Spain"}})
+
+	workload := &algorithms.Workload{Labels: map[string]string{labels.WorkloadForbiddenLocation: "-ES-"}}
+
+	for i := 0; i < 10; i++ {
+		node, err := New(inodes).GetNode(workload)
+		assert.NoError(t, err)
+		assert.Equal(t, "Node0", node.Name)
+	}
+
+	workload.Labels[labels.WorkloadForbiddenLocation] = "-ES_PT-"
+	_, err := New(inodes).GetNode(workload)
+	assert.True(t, errors.Is(err, algorithms.ErrForbiddenLocation))
+}
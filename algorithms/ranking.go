@@ -0,0 +1,27 @@
+package algorithms
+
+import (
+	"github.com/geolocate-orchestration/scheduler/nodes"
+)
+
+// Ranker is implemented by algorithms able to rank the feasible nodes, as alternatives when binding fails
+type Ranker interface {
+	// GetRankedNodes returns up to n feasible nodes for the workload, ordered from best to worst
+	// Nodes of the first fallback level with feasible nodes come first, then the ones of the following levels
+	GetRankedNodes(workload *Workload, n int) ([]RankedNode, error)
+}
+
+// RankedNode is a feasible node and how it ranked
+type RankedNode struct {
+	// Node is the feasible node
+	Node *nodes.Node
+
+	// Score is the node score, higher than the ones of the nodes ranked after it unless they tie in the same level
+	Score int64
+
+	// Level is the fallback level the node matched
+	Level Level
+
+	// Reason explains why the node would be selected
+	Reason string
+}
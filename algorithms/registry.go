@@ -0,0 +1,103 @@
+package algorithms
+
+import (
+	"errors"
+	"fmt"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+	"sort"
+	"sync"
+)
+
+// ErrAlgorithmNotRegistered is returned when no algorithm is registered under the requested name
+var ErrAlgorithmNotRegistered = errors.New("selected algorithm does not exist")
+
+// ErrAlgorithmAlreadyRegistered is returned when registering a name that is already in use
+var ErrAlgorithmAlreadyRegistered = errors.New("algorithm with given name is already registered")
+
+// Options holds algorithm specific configuration handed to its Factory
+type Options map[string]interface{}
+
+// Factory creates a new Algorithm instance backed by the given nodes cache
+type Factory func(inodes nodes.INodes, options Options) (Algorithm, error)
+
+var registry = struct {
+	sync.RWMutex
+	factories map[string]Factory
+}{factories: make(map[string]Factory)}
+
+// Register makes an algorithm available under the given name
+// It returns error if the name is empty, the factory is nil or the name is already registered
+func Register(name string, factory Factory) error {
+	if name == "" {
+		return errors.New("algorithm name must not be empty")
+	}
+
+	if factory == nil {
+		return fmt.Errorf("algorithm %q factory must not be nil", name)
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.factories[name]; ok {
+		return fmt.Errorf("%w: %s", ErrAlgorithmAlreadyRegistered, name)
+	}
+
+	registry.factories[name] = factory
+	return nil
+}
+
+// MustRegister works like Register but panics on error, to be used from package init functions
+func MustRegister(name string, factory Factory) {
+	if err := Register(name, factory); err != nil {
+		panic(err)
+	}
+}
+
+// Registered lists the names of all registered algorithms in alphabetical order
+func Registered() []string {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	names := make([]string, 0, len(registry.factories))
+	for name := range registry.factories {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// IsRegistered reports whether an algorithm is registered under the given name
+func IsRegistered(name string) bool {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	_, ok := registry.factories[name]
+	return ok
+}
+
+// New creates a new instance of the algorithm registered under the given name
+func New(name string, inodes nodes.INodes, options Options) (Algorithm, error) {
+	registry.RLock()
+	factory, ok := registry.factories[name]
+	registry.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrAlgorithmNotRegistered, name)
+	}
+
+	if options == nil {
+		options = Options{}
+	}
+
+	return factory(inodes, options)
+}
+
+// unregister removes the algorithm registered under the given name, so tests leave the registry as they found it
+func unregister(name string) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	delete(registry.factories, name)
+}
@@ -0,0 +1,86 @@
+package algorithms
+
+import (
+	"errors"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type testAlgorithm struct {
+	inodes  nodes.INodes
+	options Options
+}
+
+func (t *testAlgorithm) GetName() string {
+	return "test"
+}
+
+func (t *testAlgorithm) GetNode(*Workload) (*nodes.Node, error) {
+	return nodes.GetRandomFromList(t.inodes.GetAllNodes())
+}
+
+func newTestFactory(inodes nodes.INodes, options Options) (Algorithm, error) {
+	return &testAlgorithm{inodes: inodes, options: options}, nil
+}
+
+// unregisterOnCleanup removes the test algorithms from the global registry once the test ends
+func unregisterOnCleanup(t *testing.T, names ...string) {
+	t.Cleanup(func() {
+		for _, name := range names {
+			unregister(name)
+		}
+	})
+}
+
+func TestRegisterAndNew(t *testing.T) {
+	unregisterOnCleanup(t, "registry-test-new")
+	assert.NoError(t, Register("registry-test-new", newTestFactory))
+	assert.True(t, IsRegistered("registry-test-new"))
+	assert.Contains(t, Registered(), "registry-test-new")
+
+	algorithm, err := New("registry-test-new", nodes.New(), Options{"key": "value"})
+	assert.NoError(t, err)
+	assert.Equal(t, "test", algorithm.GetName())
+	assert.Equal(t, "value", algorithm.(*testAlgorithm).options["key"])
+}
+
+func TestNewNilOptions(t *testing.T) {
+	unregisterOnCleanup(t, "registry-test-nil-options")
+	MustRegister("registry-test-nil-options", newTestFactory)
+
+	algorithm, err := New("registry-test-nil-options", nodes.New(), nil)
+	assert.NoError(t, err)
+	assert.NotNil(t, algorithm.(*testAlgorithm).options)
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	unregisterOnCleanup(t, "registry-test-duplicate")
+	assert.NoError(t, Register("registry-test-duplicate", newTestFactory))
+
+	err := Register("registry-test-duplicate", newTestFactory)
+	assert.True(t, errors.Is(err, ErrAlgorithmAlreadyRegistered))
+	assert.Panics(t, func() { MustRegister("registry-test-duplicate", newTestFactory) })
+}
+
+func TestRegisterInvalid(t *testing.T) {
+	assert.Error(t, Register("", newTestFactory))
+	assert.Error(t, Register("registry-test-nil", nil))
+	assert.False(t, IsRegistered("registry-test-nil"))
+}
+
+func TestNewNotRegistered(t *testing.T) {
+	_, err := New("registry-test-missing", nodes.New(), nil)
+	assert.True(t, errors.Is(err, ErrAlgorithmNotRegistered))
+}
+
+func TestRegisteredSorted(t *testing.T) {
+	unregisterOnCleanup(t, "registry-test-a", "registry-test-b")
+	MustRegister("registry-test-b", newTestFactory)
+	MustRegister("registry-test-a", newTestFactory)
+
+	names := Registered()
+	for i := 1; i < len(names); i++ {
+		assert.True(t, names[i-1] < names[i])
+	}
+}
@@ -0,0 +1,40 @@
+package algorithms
+
+import (
+	"github.com/geolocate-orchestration/scheduler/labels"
+	"github.com/geolocate-orchestration/scheduler/locations"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+)
+
+// RequiredNodes returns the names of the nodes located in any of the workload 'requiredLocation' label locations
+// It returns nil if the workload requires no location, as every node satisfies it, and fails if the label is
+// malformed or has unknown locations
+func RequiredNodes(lister nodes.NodeLister, workload *Workload) (map[string]bool, error) {
+	if workload == nil || workload.Labels[labels.WorkloadRequiredLocation] == "" {
+		return nil, nil
+	}
+
+	expression, err := locations.ParseWithLevels(
+		workload.Labels[labels.WorkloadRequiredLocation], lister.GetTopology().ExpressionLevels(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	condition := expression.AnyOf()
+	if condition == nil {
+		return nil, nil
+	}
+
+	matching, err := lister.GetNodesMatching(condition)
+	if err != nil {
+		return nil, err
+	}
+
+	required := make(map[string]bool, len(matching))
+	for _, node := range matching {
+		required[node.Name] = true
+	}
+
+	return required, nil
+}
@@ -0,0 +1,36 @@
+package algorithms
+
+import (
+	"errors"
+	"github.com/geolocate-orchestration/scheduler/labels"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newTestRequiredWorkload(value string) *Workload {
+	return &Workload{Labels: map[string]string{labels.WorkloadRequiredLocation: value}}
+}
+
+func TestRequiredNodes(t *testing.T) {
+	required, err := RequiredNodes(newTestForbiddenNodes(), newTestRequiredWorkload("-PT_ES-"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"NodePortugal": true, "NodeSpain": true}, required)
+
+	required, err = RequiredNodes(newTestForbiddenNodes(), newTestRequiredWorkload("-FR-"))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{}, required)
+
+	for _, workload := range []*Workload{nil, {}, newTestRequiredWorkload("--")} {
+		required, err = RequiredNodes(newTestForbiddenNodes(), workload)
+		assert.NoError(t, err)
+		assert.Nil(t, required)
+	}
+}
+
+func TestRequiredNodesErrors(t *testing.T) {
+	_, err := RequiredNodes(newTestForbiddenNodes(), newTestRequiredWorkload("Spain"))
+	assert.True(t, errors.Is(err, ErrInvalidLocation))
+
+	_, err = RequiredNodes(newTestForbiddenNodes(), newTestRequiredWorkload("-Spainn-"))
+	assert.True(t, errors.Is(err, ErrUnknownLocation))
+}
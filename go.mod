@@ -5,5 +5,6 @@ go 1.16
 require (
 	github.com/geolocate-orchestration/gountries v0.0.0-20210328164130-bacd2f98d9be
 	github.com/stretchr/testify v1.4.0
+	gopkg.in/yaml.v2 v2.2.2
 	k8s.io/klog/v2 v2.8.0
 )
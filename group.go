@@ -0,0 +1,148 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/geolocate-orchestration/scheduler/algorithms"
+)
+
+// GroupError is returned when a member of a workload group can't be placed, no member is placed then
+// It matches the member scheduling error with errors.Is and errors.As
+type GroupError struct {
+	// Workload is the name of the member that could not be placed
+	Workload string
+
+	// Err is the error scheduling the member
+	Err error
+}
+
+func (e *GroupError) Error() string {
+	return fmt.Sprintf("group member %s could not be placed: %s", e.Workload, e.Err)
+}
+
+// Unwrap returns the error scheduling the member
+func (e *GroupError) Unwrap() error {
+	return e.Err
+}
+
+// ScheduleGroup places every workload of the group, each with its own location labels, or none of them
+// Members are placed in order on their ranked nodes, their resources assumed so the following members account for
+// them. When a member can't be placed, the previous members are moved to their next ranked nodes, until every
+// member is placed or maxGroupAttempts placements were tried. The members placed are then released and a *GroupError
+// naming the member the search could not get past is returned
+// Other workloads are not placed while the group is, so they never see the capacity of a group that is rolled back
+func (s *Scheduler) ScheduleGroup(workloads []*algorithms.Workload) ([]Placement, error) {
+	if err := validateGroup(workloads); err != nil {
+		return nil, err
+	}
+
+	s.placing.Lock()
+	defer s.placing.Unlock()
+
+	search := &groupSearch{scheduler: s, members: workloads, placements: make([]Placement, 0, len(workloads))}
+	if !search.place(0) {
+		for _, placement := range search.placements {
+			s.inodes.ForgetWorkload(placement.Workload.Name)
+		}
+
+		if search.err == nil {
+			search.err = fmt.Errorf("no placement found within %d attempts", maxGroupAttempts)
+		}
+
+		return nil, &GroupError{Workload: workloads[search.failed].Name, Err: search.err}
+	}
+
+	for _, placement := range search.placements {
+		s.trackWorkload(placement.Workload)
+	}
+
+	return search.placements, nil
+}
+
+// Unexported
+
+// maxGroupAttempts bounds the placements tried by ScheduleGroup before giving up
+const maxGroupAttempts = 1000
+
+// groupSearch places the members of a group, backtracking over the ranked nodes of each member
+type groupSearch struct {
+	scheduler  *Scheduler
+	members    []*algorithms.Workload
+	placements []Placement
+	attempts   int
+
+	// failed is the index of the deepest member that could not be placed and err why
+	failed int
+	err    error
+}
+
+// place places the members from the given index on, keeping the ones before it where they are
+func (g *groupSearch) place(index int) bool {
+	if index == len(g.members) {
+		return true
+	}
+
+	member := g.members[index]
+	ranked, err := g.scheduler.rankAllNodes(member)
+	if err != nil {
+		g.fail(index, err)
+		return false
+	}
+
+	for _, candidate := range ranked {
+		if g.attempts == maxGroupAttempts {
+			return false
+		}
+
+		g.attempts++
+		if err := g.scheduler.inodes.AssumeWorkload(member.Name, candidate.Node.Name, workloadResources(member)); err != nil {
+			g.fail(index, err)
+			continue
+		}
+
+		g.placements = append(g.placements, Placement{Workload: member, Node: candidate.Node})
+		if g.place(index + 1) {
+			return true
+		}
+
+		g.placements = g.placements[:len(g.placements)-1]
+		g.scheduler.inodes.ForgetWorkload(member.Name)
+	}
+
+	return false
+}
+
+// fail records why a member could not be placed, unless a later member already failed
+func (g *groupSearch) fail(index int, err error) {
+	if g.err == nil || index >= g.failed {
+		g.failed = index
+		g.err = err
+	}
+}
+
+// validateGroup checks the group has members with unique names
+func validateGroup(workloads []*algorithms.Workload) error {
+	if len(workloads) == 0 {
+		return errors.New("workload group must have at least one member")
+	}
+
+	names := make(map[string]bool, len(workloads))
+	for i, workload := range workloads {
+		if workload == nil {
+			return fmt.Errorf("workload group member %d is nil", i)
+		}
+
+		// members are charged while the group is placed so they must be named
+		if workload.Name == "" {
+			return fmt.Errorf("workload group member %d has no name", i)
+		}
+
+		if names[workload.Name] {
+			return fmt.Errorf("workload group has several members named %q", workload.Name)
+		}
+
+		names[workload.Name] = true
+	}
+
+	return nil
+}
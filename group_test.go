@@ -0,0 +1,105 @@
+package scheduler
+
+import (
+	"errors"
+	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/labels"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newTestGroupScheduler(t *testing.T) IScheduler {
+	s, err := NewScheduler("location")
+	assert.NoError(t, err)
+
+	s.AddNode(newTestLocatedNode("Braga0", "Braga", "Portugal"))
+	s.AddNode(newTestLocatedNode("Madrid0", "Madrid", "Spain"))
+
+	return s
+}
+
+func newTestGroupMember(name string, country string) *algorithms.Workload {
+	return &algorithms.Workload{
+		Name:   name,
+		Labels: map[string]string{labels.WorkloadRequiredLocation: "-" + country + "-"},
+		CPU:    600,
+	}
+}
+
+func TestScheduleGroup(t *testing.T) {
+	s := newTestGroupScheduler(t)
+
+	placements, err := s.ScheduleGroup([]*algorithms.Workload{
+		newTestGroupMember("ingest", "PT"),
+		newTestGroupMember("train", "ES"),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(placements))
+	assert.Equal(t, "ingest", placements[0].Workload.Name)
+	assert.Equal(t, "Braga0", placements[0].Node.Name)
+	assert.Equal(t, "Madrid0", placements[1].Node.Name)
+
+	assert.Equal(t, []string{"ingest"}, workloadNames(s.GetNodeWorkloads("Braga0")))
+	assert.Equal(t, []string{"train"}, workloadNames(s.GetNodeWorkloads("Madrid0")))
+}
+
+func TestScheduleGroupRollback(t *testing.T) {
+	s := newTestGroupScheduler(t)
+
+	_, err := s.ScheduleGroup([]*algorithms.Workload{
+		newTestGroupMember("ingest", "PT"),
+		newTestGroupMember("train", "ES"),
+		newTestGroupMember("serve", "PT"),
+	})
+
+	var groupErr *GroupError
+	assert.True(t, errors.As(err, &groupErr))
+	assert.Equal(t, "serve", groupErr.Workload)
+	assert.Contains(t, err.Error(), "serve")
+	assert.True(t, errors.Is(err, algorithms.ErrRequiredLocationUnsatisfied))
+	assert.True(t, errors.Is(err, algorithms.ErrInsufficientResources))
+
+	// no member stays placed
+	assert.Empty(t, s.GetNodeWorkloads("Braga0"))
+	assert.Empty(t, s.GetNodeWorkloads("Madrid0"))
+
+	_, err = s.ScheduleWorkload(newTestGroupMember("other", "PT"))
+	assert.NoError(t, err)
+}
+
+func TestScheduleGroupBacktracks(t *testing.T) {
+	s := newTestGroupScheduler(t)
+
+	// placed first, the client would take Braga0 which the server requires
+	client := &algorithms.Workload{
+		Name:   "client",
+		Labels: map[string]string{labels.WorkloadPreferredLocation: "-PT-"},
+		CPU:    600,
+	}
+
+	placements, err := s.ScheduleGroup([]*algorithms.Workload{client, newTestGroupMember("server", "PT")})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(placements))
+	assert.Equal(t, "Madrid0", placements[0].Node.Name)
+	assert.Equal(t, "Braga0", placements[1].Node.Name)
+
+	assert.Equal(t, []string{"server"}, workloadNames(s.GetNodeWorkloads("Braga0")))
+	assert.Equal(t, []string{"client"}, workloadNames(s.GetNodeWorkloads("Madrid0")))
+}
+
+func TestScheduleGroupErrors(t *testing.T) {
+	s := newTestGroupScheduler(t)
+
+	_, err := s.ScheduleGroup(nil)
+	assert.Error(t, err)
+
+	_, err = s.ScheduleGroup([]*algorithms.Workload{newTestGroupMember("ingest", "PT"), nil})
+	assert.Error(t, err)
+
+	_, err = s.ScheduleGroup([]*algorithms.Workload{newTestGroupMember("ingest", "PT"), newTestGroupMember("", "ES")})
+	assert.Error(t, err)
+
+	_, err = s.ScheduleGroup([]*algorithms.Workload{newTestGroupMember("ingest", "PT"), newTestGroupMember("ingest", "ES")})
+	assert.Error(t, err)
+	assert.Empty(t, s.GetNodeWorkloads("Braga0"))
+}
@@ -12,8 +12,32 @@ const NodeCountry = "node.geolocate.io/country"
 // NodeContinent indicates Node continent location
 const NodeContinent = "node.geolocate.io/continent"
 
+// NodeLatitude indicates Node latitude in decimal degrees, used with NodeLongitude
+const NodeLatitude = "node.geolocate.io/latitude"
+
+// NodeLongitude indicates Node longitude in decimal degrees, used with NodeLatitude
+const NodeLongitude = "node.geolocate.io/longitude"
+
 // WorkloadRequiredLocation indicates Workloads required Node location to be scheduled there
 const WorkloadRequiredLocation = "workload.geolocate.io/requiredLocation"
 
 // WorkloadPreferredLocation indicates Workloads preferred Node location to be prioritized in scheduling
 const WorkloadPreferredLocation = "workload.geolocate.io/preferredLocation"
+
+// WorkloadForbiddenLocation indicates Node locations Workloads must never be scheduled to
+const WorkloadForbiddenLocation = "workload.geolocate.io/forbiddenLocation"
+
+// WorkloadWeightedPreferredLocation indicates Workloads ranked preferred Node locations with optional weights
+// such as "Porto:100, Lisbon:60, Spain:20", it takes precedence over WorkloadPreferredLocation
+const WorkloadWeightedPreferredLocation = "workload.geolocate.io/weightedPreferredLocation"
+
+// WorkloadOrigin indicates the point Workloads should be scheduled closest to,
+// as "<latitude>,<longitude>" coordinates or as a city or country name
+const WorkloadOrigin = "workload.geolocate.io/origin"
+
+// WorkloadClientRegion indicates the region Workload clients connect from, used to look up latencies
+const WorkloadClientRegion = "workload.geolocate.io/clientRegion"
+
+// WorkloadMaxLatency indicates the maximum expected round-trip time in milliseconds
+// between the Workload client region and its Node
+const WorkloadMaxLatency = "workload.geolocate.io/maxLatencyMs"
@@ -0,0 +1,53 @@
+package locations
+
+import (
+	"github.com/geolocate-orchestration/gountries"
+	"sort"
+)
+
+// BorderingCountries returns the alpha2 codes of the countries up to hops land borders away from the given ones,
+// grouped by the number of borders crossed to reach them, the nearest first
+// The given countries and countries already in a nearer group are not repeated, unknown codes are ignored
+func BorderingCountries(codes []string, hops int) [][]string {
+	query := gountries.New()
+	visited := make(map[string]bool, len(codes))
+	current := make([]gountries.Country, 0, len(codes))
+
+	for _, code := range codes {
+		if country, err := query.FindCountryByAlpha(code); err == nil && !visited[country.Alpha2] {
+			visited[country.Alpha2] = true
+			current = append(current, country)
+		}
+	}
+
+	rings := make([][]string, 0, hops)
+
+	for hop := 0; hop < hops && len(current) > 0; hop++ {
+		next := make([]gountries.Country, 0)
+		ring := make([]string, 0)
+
+		for _, country := range current {
+			// borders are listed by alpha3 code
+			for _, border := range country.Borders {
+				neighbour, err := query.FindCountryByAlpha(border)
+				if err != nil || visited[neighbour.Alpha2] {
+					continue
+				}
+
+				visited[neighbour.Alpha2] = true
+				next = append(next, neighbour)
+				ring = append(ring, neighbour.Alpha2)
+			}
+		}
+
+		if len(ring) == 0 {
+			break
+		}
+
+		sort.Strings(ring)
+		rings = append(rings, ring)
+		current = next
+	}
+
+	return rings
+}
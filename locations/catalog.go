@@ -0,0 +1,272 @@
+package locations
+
+import (
+	"fmt"
+	"github.com/geolocate-orchestration/gountries"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultCatalog is the catalog nodes caches resolve sites against unless given their own
+var DefaultCatalog = NewCatalog()
+
+// Site is an operator defined location gountries doesn't know, such as an edge site, a ship or an oil rig
+// Sites in a country are located at the city level, sites only in a continent at the country level
+type Site struct {
+	// Name identifies the site in node and workload location labels
+	Name string `json:"name" yaml:"name"`
+
+	// Country is the name or alpha2 code of the country containing the site
+	Country string `json:"country,omitempty" yaml:"country,omitempty"`
+
+	// Continent is the name or code of the continent containing the site, optional when Country is set
+	Continent string `json:"continent,omitempty" yaml:"continent,omitempty"`
+
+	// Latitude and Longitude are the optional site coordinates in decimal degrees
+	Latitude  *float64 `json:"latitude,omitempty" yaml:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty" yaml:"longitude,omitempty"`
+}
+
+// Catalog resolves operator defined sites by name, it is safe for concurrent use
+type Catalog struct {
+	mutex      sync.RWMutex
+	query      *gountries.Query
+	continents gountries.Continents
+	sites      map[string]Site // by lowercase name
+	codes      map[string]Site // by index code
+}
+
+// NewCatalog creates an empty catalog
+func NewCatalog() *Catalog {
+	return &Catalog{
+		query:      gountries.New(),
+		continents: gountries.NewContinents(),
+		sites:      make(map[string]Site),
+		codes:      make(map[string]Site),
+	}
+}
+
+// LoadCatalog creates a catalog with the sites listed in a YAML or JSON file
+func LoadCatalog(path string) (*Catalog, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	catalog := NewCatalog()
+	if err := catalog.Load(file); err != nil {
+		return nil, err
+	}
+
+	return catalog, nil
+}
+
+// Load registers the sites of a YAML or JSON list, such as:
+// [{"name": "edge-site-42", "country": "PT", "latitude": 41.15, "longitude": -8.61}]
+// No site is registered if any of them is invalid
+func (c *Catalog) Load(reader io.Reader) error {
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	sites := make([]Site, 0)
+	if err := yaml.UnmarshalStrict(data, &sites); err != nil {
+		return fmt.Errorf("invalid sites catalog: %s", err)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	validated := make([]Site, 0, len(sites))
+	names := make(map[string]bool, len(sites))
+
+	for _, site := range sites {
+		normalized, err := c.validate(site)
+		if err != nil {
+			return err
+		}
+
+		if names[strings.ToLower(normalized.Name)] {
+			return fmt.Errorf("site %q is duplicated", site.Name)
+		}
+
+		names[strings.ToLower(normalized.Name)] = true
+		validated = append(validated, normalized)
+	}
+
+	for _, site := range validated {
+		c.add(site)
+	}
+
+	return nil
+}
+
+// Register adds a site to the catalog
+// It fails if the site is invalid, already registered or named after a gountries location
+func (c *Catalog) Register(site Site) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	normalized, err := c.validate(site)
+	if err != nil {
+		return err
+	}
+
+	c.add(normalized)
+	return nil
+}
+
+// MustRegister works as Register but panics if the site can't be registered
+func (c *Catalog) MustRegister(site Site) {
+	if err := c.Register(site); err != nil {
+		panic(err)
+	}
+}
+
+// Find returns the site with the given name, names are case insensitive
+func (c *Catalog) Find(name string) (Site, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	site, ok := c.sites[strings.ToLower(strings.TrimSpace(name))]
+	return site, ok
+}
+
+// FindByCode returns the site indexed with the given code
+func (c *Catalog) FindByCode(code string) (Site, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	site, ok := c.codes[code]
+	return site, ok
+}
+
+// Sites lists the registered sites sorted by name
+func (c *Catalog) Sites() []Site {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	sites := make([]Site, 0, len(c.sites))
+	for _, site := range c.sites {
+		sites = append(sites, site)
+	}
+
+	sort.Slice(sites, func(i, j int) bool {
+		return sites[i].Name < sites[j].Name
+	})
+
+	return sites
+}
+
+// Level returns the level the site is located at
+func (s Site) Level() Level {
+	if s.Country != "" {
+		return LevelCity
+	}
+
+	return LevelCountry
+}
+
+// Code returns the code the site is indexed with, sites in a country are prefixed by its alpha2 code as cities are
+func (s Site) Code() string {
+	if s.Country != "" {
+		return fmt.Sprintf("%s-%s", s.Country, strings.ToLower(s.Name))
+	}
+
+	return strings.ToLower(s.Name)
+}
+
+// Coordinates returns the site coordinates when they are set
+func (s Site) Coordinates() (Coordinates, bool) {
+	if s.Latitude == nil || s.Longitude == nil {
+		return Coordinates{}, false
+	}
+
+	return Coordinates{Latitude: *s.Latitude, Longitude: *s.Longitude}, true
+}
+
+// validate checks the site and returns it with its country alpha2 code and continent code
+func (c *Catalog) validate(site Site) (Site, error) {
+	site.Name = strings.TrimSpace(site.Name)
+
+	switch {
+	case site.Name == "" || strings.ContainsAny(site.Name, " \t_=!(),\""):
+		return Site{}, fmt.Errorf("site name %q must be a non empty word", site.Name)
+	case c.sites[strings.ToLower(site.Name)].Name != "":
+		return Site{}, fmt.Errorf("site %q is already registered", site.Name)
+	case c.isGeographic(site.Name):
+		return Site{}, fmt.Errorf("site %q is already a known location", site.Name)
+	case site.Country == "" && site.Continent == "":
+		return Site{}, fmt.Errorf("site %q must have a country or a continent", site.Name)
+	case (site.Latitude == nil) != (site.Longitude == nil):
+		return Site{}, fmt.Errorf("site %q must have both latitude and longitude or none", site.Name)
+	}
+
+	if coordinates, ok := site.Coordinates(); ok {
+		if _, err := NewCoordinates(coordinates.Latitude, coordinates.Longitude); err != nil {
+			return Site{}, fmt.Errorf("site %q coordinates: %w", site.Name, err)
+		}
+	}
+
+	if site.Continent != "" {
+		continent, err := c.continents.FindContinent(site.Continent)
+		if err != nil {
+			return Site{}, fmt.Errorf("site %q continent %q is unknown", site.Name, site.Continent)
+		}
+
+		site.Continent = continent.Code
+	}
+
+	if site.Country != "" {
+		country, err := c.findCountry(site.Country)
+		if err != nil {
+			return Site{}, fmt.Errorf("site %q country %q is unknown", site.Name, site.Country)
+		}
+
+		continent, err := c.continents.FindContinent(country.Continent)
+		if err == nil && site.Continent != "" && site.Continent != continent.Code {
+			return Site{}, fmt.Errorf("site %q country %q is not in continent %q", site.Name, site.Country, site.Continent)
+		}
+
+		site.Country = country.Alpha2
+		if err == nil {
+			site.Continent = continent.Code
+		}
+	}
+
+	return site, nil
+}
+
+func (c *Catalog) add(site Site) {
+	c.sites[strings.ToLower(site.Name)] = site
+	c.codes[site.Code()] = site
+}
+
+// isGeographic reports whether gountries already knows a location with the given name
+func (c *Catalog) isGeographic(name string) bool {
+	if _, err := c.findCountry(name); err == nil {
+		return true
+	}
+
+	if _, err := c.continents.FindContinent(name); err == nil {
+		return true
+	}
+
+	_, err := c.query.FindSubdivisionByName(name)
+	return err == nil
+}
+
+func (c *Catalog) findCountry(countryID string) (gountries.Country, error) {
+	if country, err := c.query.FindCountryByName(countryID); err == nil {
+		return country, nil
+	}
+
+	return c.query.FindCountryByAlpha(countryID)
+}
@@ -0,0 +1,99 @@
+package locations
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level identifies the location level a Match condition applies to
+type Level string
+
+const (
+	// LevelCity matches node cities
+	LevelCity Level = "city"
+
+	// LevelCountry matches node countries
+	LevelCountry Level = "country"
+
+	// LevelContinent matches node continents
+	LevelContinent Level = "continent"
+)
+
+// Condition is a boolean location expression node
+// Implementations are *And, *Or, *Not and *Match
+type Condition interface {
+	fmt.Stringer
+	condition()
+}
+
+// And matches nodes satisfying both conditions
+type And struct {
+	Left  Condition
+	Right Condition
+}
+
+// Or matches nodes satisfying any of the conditions
+type Or struct {
+	Left  Condition
+	Right Condition
+}
+
+// Not matches nodes not satisfying the condition
+type Not struct {
+	Condition Condition
+}
+
+// Match matches nodes located in any of the values at the given level
+type Match struct {
+	Level  Level
+	Values []Name
+
+	// Pos is the byte offset of the level keyword in the parsed label
+	Pos int
+}
+
+func (*And) condition()   {}
+func (*Or) condition()    {}
+func (*Not) condition()   {}
+func (*Match) condition() {}
+
+func (c *And) String() string {
+	return fmt.Sprintf("(%s and %s)", c.Left, c.Right)
+}
+
+func (c *Or) String() string {
+	return fmt.Sprintf("(%s or %s)", c.Left, c.Right)
+}
+
+func (c *Not) String() string {
+	return fmt.Sprintf("not %s", c.Condition)
+}
+
+func (c *Match) String() string {
+	quoted := make([]string, 0, len(c.Values))
+
+	for _, value := range c.Values {
+		quoted = append(quoted, fmt.Sprintf("%q", value.Value))
+	}
+
+	return fmt.Sprintf("%s in (%s)", c.Level, strings.Join(quoted, ", "))
+}
+
+// MatchValues lists the values of the match
+func (c *Match) MatchValues() []string {
+	return values(c.Values)
+}
+
+// PositiveMatches lists the Match conditions not negated by a Not condition, in expression order
+func PositiveMatches(condition Condition) []*Match {
+	switch c := condition.(type) {
+	case *And:
+		return append(PositiveMatches(c.Left), PositiveMatches(c.Right)...)
+	case *Or:
+		return append(PositiveMatches(c.Left), PositiveMatches(c.Right)...)
+	case *Match:
+		return []*Match{c}
+	}
+
+	return nil
+}
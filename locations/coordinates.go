@@ -0,0 +1,100 @@
+package locations
+
+import (
+	"fmt"
+	"github.com/geolocate-orchestration/gountries"
+	"math"
+	"strconv"
+	"strings"
+)
+
+const coordinatesSeparator = ","
+
+// Coordinates is a point on Earth in decimal degrees
+type Coordinates struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// NewCoordinates validates the latitude and longitude ranges and returns their coordinates
+func NewCoordinates(latitude float64, longitude float64) (Coordinates, error) {
+	if message := checkRange(latitude, longitude); message != "" {
+		return Coordinates{}, fmt.Errorf("%w: %s", ErrInvalidLocation, message)
+	}
+
+	return Coordinates{Latitude: latitude, Longitude: longitude}, nil
+}
+
+// ParseLatLong parses latitude and longitude values given separately, such as node labels
+func ParseLatLong(latitude string, longitude string) (Coordinates, error) {
+	lat, err := strconv.ParseFloat(strings.TrimSpace(latitude), 64)
+	if err != nil {
+		return Coordinates{}, fmt.Errorf("%w: latitude %q is not a number", ErrInvalidLocation, latitude)
+	}
+
+	long, err := strconv.ParseFloat(strings.TrimSpace(longitude), 64)
+	if err != nil {
+		return Coordinates{}, fmt.Errorf("%w: longitude %q is not a number", ErrInvalidLocation, longitude)
+	}
+
+	return NewCoordinates(lat, long)
+}
+
+// IsCoordinates reports whether a label value is written as coordinates instead of a location name
+func IsCoordinates(input string) bool {
+	return strings.Contains(input, coordinatesSeparator)
+}
+
+// ParseCoordinates parses coordinates written as "<latitude>,<longitude>", such as "41.15,-8.61"
+// It returns a *ParseError pointing to the offending position if the value is malformed
+func ParseCoordinates(input string) (Coordinates, error) {
+	parts := split(input, 0, coordinatesSeparator)
+
+	if len(parts) != 2 {
+		return Coordinates{}, &ParseError{Input: input, Pos: len(input), Message: "expected coordinates as <latitude>,<longitude>"}
+	}
+
+	values := make([]float64, 0, len(parts))
+
+	for _, part := range parts {
+		trimmed, err := trimName(input, part)
+		if err != nil {
+			return Coordinates{}, &ParseError{Input: input, Pos: part.Pos, Message: "expected a number"}
+		}
+
+		value, err := strconv.ParseFloat(trimmed.Value, 64)
+		if err != nil {
+			return Coordinates{}, &ParseError{Input: input, Pos: trimmed.Pos, Message: "expected a number"}
+		}
+
+		values = append(values, value)
+	}
+
+	if message := checkRange(values[0], values[1]); message != "" {
+		return Coordinates{}, &ParseError{Input: input, Pos: 0, Message: message}
+	}
+
+	return Coordinates{Latitude: values[0], Longitude: values[1]}, nil
+}
+
+// DistanceTo returns the great-circle distance to the given coordinates in kilometres
+func (c Coordinates) DistanceTo(other Coordinates) float64 {
+	return gountries.CalculateHaversine(c.Latitude, c.Longitude, other.Latitude, other.Longitude)
+}
+
+func (c Coordinates) String() string {
+	return fmt.Sprintf("%g,%g", c.Latitude, c.Longitude)
+}
+
+// checkRange describes why coordinates are out of range, it returns an empty string for valid coordinates
+func checkRange(latitude float64, longitude float64) string {
+	if math.IsNaN(latitude) || latitude < -90 || latitude > 90 {
+		return fmt.Sprintf("latitude %g out of range [-90, 90]", latitude)
+	}
+
+	if math.IsNaN(longitude) || longitude < -180 || longitude > 180 {
+		return fmt.Sprintf("longitude %g out of range [-180, 180]", longitude)
+	}
+
+	return ""
+}
@@ -0,0 +1,377 @@
+package locations
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Boolean expressions grammar:
+//
+//	expression := and ("or" and)*
+//	and        := unary ("and" unary)*
+//	unary      := "not" unary | "(" expression ")" | match
+//	match      := level ("=" | "!=") value | level ["not"] "in" "(" value ("," value)* ")"
+//	level      := "city" | "country" | "continent" | any other level given to ParseWithLevels
+//	value      := word | "quoted string"
+//
+// Keywords are case insensitive.
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+	tokenEqual
+	tokenNotEqual
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+	pos   int
+}
+
+// isConditionExpression reports whether the label uses the boolean expressions syntax,
+// either containing operators or starting with a keyword followed by more words
+func isConditionExpression(input string, levels []Level) bool {
+	if strings.ContainsAny(input, "=(!") {
+		return true
+	}
+
+	words := strings.Fields(input)
+	if len(words) < 2 {
+		return false
+	}
+
+	first := Level(strings.ToLower(words[0]))
+	return first == "not" || hasLevel(levels, first)
+}
+
+type expressionParser struct {
+	input  string
+	levels []Level
+	tokens []token
+	next   int
+}
+
+func parseCondition(input string, levels []Level) (Condition, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &expressionParser{input: input, levels: levels, tokens: tokens}
+
+	condition, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorAt(t, "unexpected %s", describe(t))
+	}
+
+	return condition, nil
+}
+
+func (p *expressionParser) parseOr() (Condition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptKeyword("or") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &Or{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *expressionParser) parseAnd() (Condition, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.acceptKeyword("and") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		left = &And{Left: left, Right: right}
+	}
+
+	return left, nil
+}
+
+func (p *expressionParser) parseUnary() (Condition, error) {
+	if p.acceptKeyword("not") {
+		condition, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+
+		return &Not{Condition: condition}, nil
+	}
+
+	if p.peek().kind == tokenLeftParen {
+		p.advance()
+
+		condition, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := p.expect(tokenRightParen, "')'"); err != nil {
+			return nil, err
+		}
+
+		return condition, nil
+	}
+
+	return p.parseMatch()
+}
+
+func (p *expressionParser) parseMatch() (Condition, error) {
+	t := p.advance()
+	level := Level(strings.ToLower(t.value))
+
+	if t.kind != tokenWord || !hasLevel(p.levels, level) {
+		return nil, p.errorAt(t, "expected %s, found %s", describeLevels(p.levels), describe(t))
+	}
+
+	match := &Match{Level: level, Pos: t.pos}
+
+	switch operator := p.advance(); {
+	case operator.kind == tokenEqual || operator.kind == tokenNotEqual:
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+
+		match.Values = []Name{value}
+		if operator.kind == tokenNotEqual {
+			return &Not{Condition: match}, nil
+		}
+
+		return match, nil
+	case isKeyword(operator, "in"):
+		return p.parseList(match)
+	case isKeyword(operator, "not"):
+		if t := p.advance(); !isKeyword(t, "in") {
+			return nil, p.errorAt(t, "expected 'in', found %s", describe(t))
+		}
+
+		list, err := p.parseList(match)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Not{Condition: list}, nil
+	default:
+		return nil, p.errorAt(operator, "expected '=', '!=', 'in' or 'not in', found %s", describe(operator))
+	}
+}
+
+func (p *expressionParser) parseList(match *Match) (Condition, error) {
+	if _, err := p.expect(tokenLeftParen, "'('"); err != nil {
+		return nil, err
+	}
+
+	for {
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+
+		match.Values = append(match.Values, value)
+
+		if p.peek().kind != tokenComma {
+			break
+		}
+
+		p.advance()
+	}
+
+	if _, err := p.expect(tokenRightParen, "',' or ')'"); err != nil {
+		return nil, err
+	}
+
+	return match, nil
+}
+
+func (p *expressionParser) parseValue() (Name, error) {
+	t := p.advance()
+
+	if t.kind == tokenString || (t.kind == tokenWord && !isReserved(t.value)) {
+		if strings.TrimSpace(t.value) == "" {
+			return Name{}, p.errorAt(t, "empty location name")
+		}
+
+		return Name{Value: t.value, Pos: t.pos}, nil
+	}
+
+	return Name{}, p.errorAt(t, "expected location name, found %s", describe(t))
+}
+
+func (p *expressionParser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *expressionParser) advance() token {
+	t := p.tokens[p.next]
+
+	if t.kind != tokenEOF {
+		p.next++
+	}
+
+	return t
+}
+
+func (p *expressionParser) expect(kind tokenKind, expected string) (token, error) {
+	t := p.advance()
+
+	if t.kind != kind {
+		return t, p.errorAt(t, "expected %s, found %s", expected, describe(t))
+	}
+
+	return t, nil
+}
+
+func (p *expressionParser) acceptKeyword(keyword string) bool {
+	if isKeyword(p.peek(), keyword) {
+		p.advance()
+		return true
+	}
+
+	return false
+}
+
+func (p *expressionParser) errorAt(t token, format string, args ...interface{}) error {
+	return &ParseError{Input: p.input, Pos: t.pos, Message: fmt.Sprintf(format, args...)}
+}
+
+func tokenize(input string) ([]token, error) {
+	tokens := make([]token, 0)
+	runes := []rune(input)
+	offsets := runeOffsets(input)
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := offsets[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLeftParen, value: "(", pos: pos})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRightParen, value: ")", pos: pos})
+			i++
+		case r == ',':
+			tokens = append(tokens, token{kind: tokenComma, value: ",", pos: pos})
+			i++
+		case r == '=':
+			tokens = append(tokens, token{kind: tokenEqual, value: "=", pos: pos})
+			i++
+		case r == '!':
+			if i+1 >= len(runes) || runes[i+1] != '=' {
+				return nil, &ParseError{Input: input, Pos: pos, Message: "expected '!='"}
+			}
+
+			tokens = append(tokens, token{kind: tokenNotEqual, value: "!=", pos: pos})
+			i += 2
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+
+			if end >= len(runes) {
+				return nil, &ParseError{Input: input, Pos: pos, Message: "unterminated quoted location name"}
+			}
+
+			tokens = append(tokens, token{kind: tokenString, value: string(runes[i+1 : end]), pos: pos})
+			i = end + 1
+		default:
+			end := i
+			for end < len(runes) && !unicode.IsSpace(runes[end]) && !strings.ContainsRune("()=!,\"", runes[end]) {
+				end++
+			}
+
+			tokens = append(tokens, token{kind: tokenWord, value: string(runes[i:end]), pos: pos})
+			i = end
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(input)}), nil
+}
+
+// runeOffsets maps every rune index of the input to its byte offset
+func runeOffsets(input string) []int {
+	offsets := make([]int, 0, len(input))
+
+	for offset := range input {
+		offsets = append(offsets, offset)
+	}
+
+	return offsets
+}
+
+func isKeyword(t token, keyword string) bool {
+	return t.kind == tokenWord && strings.EqualFold(t.value, keyword)
+}
+
+func hasLevel(levels []Level, level Level) bool {
+	for _, l := range levels {
+		if l == level {
+			return true
+		}
+	}
+
+	return false
+}
+
+// describeLevels lists the levels as 'city', 'country' or 'continent'
+func describeLevels(levels []Level) string {
+	quoted := make([]string, 0, len(levels))
+
+	for _, level := range levels {
+		quoted = append(quoted, fmt.Sprintf("'%s'", level))
+	}
+
+	if len(quoted) < 2 {
+		return strings.Join(quoted, "")
+	}
+
+	return strings.Join(quoted[:len(quoted)-1], ", ") + " or " + quoted[len(quoted)-1]
+}
+
+func isReserved(word string) bool {
+	switch strings.ToLower(word) {
+	case "and", "or", "not", "in":
+		return true
+	}
+
+	return false
+}
+
+func describe(t token) string {
+	if t.kind == tokenEOF {
+		return "end of input"
+	}
+
+	return fmt.Sprintf("%q", t.value)
+}
@@ -0,0 +1,359 @@
+package locations
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	expression, err := Parse("Braga_Porto-PT_Spain-Europe")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []Name{{Value: "Braga", Pos: 0}, {Value: "Porto", Pos: 6}}, expression.Cities)
+	assert.Equal(t, []Name{{Value: "PT", Pos: 12}, {Value: "Spain", Pos: 15}}, expression.Countries)
+	assert.Equal(t, []Name{{Value: "Europe", Pos: 21}}, expression.Continents)
+	assert.Equal(t, []string{"Braga", "Porto"}, expression.CityNames())
+	assert.Equal(t, []string{"PT", "Spain"}, expression.CountryNames())
+	assert.Equal(t, []string{"Europe"}, expression.ContinentNames())
+	assert.False(t, expression.IsEmpty())
+}
+
+func TestParseEmptySections(t *testing.T) {
+	expression, err := Parse("Braga_Porto--Europe")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(expression.Cities))
+	assert.Equal(t, 0, len(expression.Countries))
+	assert.Equal(t, 1, len(expression.Continents))
+
+	expression, err = Parse("--")
+	assert.NoError(t, err)
+	assert.True(t, expression.IsEmpty())
+}
+
+func TestParseTrimsSpaces(t *testing.T) {
+	expression, err := Parse(" Braga _Porto-PT-")
+	assert.NoError(t, err)
+	assert.Equal(t, Name{Value: "Braga", Pos: 1}, expression.Cities[0])
+}
+
+func TestParseString(t *testing.T) {
+	for _, label := range []string{"Braga_Porto-PT_Spain-Europe", "--", "-PT-"} {
+		expression, err := Parse(label)
+		assert.NoError(t, err)
+		assert.Equal(t, label, expression.String())
+	}
+}
+
+func assertParseError(t *testing.T, input string, pos int) {
+	_, err := Parse(input)
+
+	var parseErr *ParseError
+	assert.True(t, errors.Is(err, ErrInvalidLocation))
+	assert.True(t, errors.As(err, &parseErr))
+	assert.Equal(t, input, parseErr.Input)
+	assert.Equal(t, pos, parseErr.Pos, input)
+}
+
+func TestParseMissingSections(t *testing.T) {
+	assertParseError(t, "", 0)
+	assertParseError(t, "Braga", 5)
+	assertParseError(t, "Braga-Portugal", 14)
+}
+
+func TestParseTooManySections(t *testing.T) {
+	assertParseError(t, "Braga-Portugal-Europe-Earth", 21)
+	assertParseError(t, "---", 2)
+}
+
+func TestParseEmptyNames(t *testing.T) {
+	assertParseError(t, "Braga__Porto--", 6)
+	assertParseError(t, "_Braga--", 0)
+	assertParseError(t, "-PT_-", 4)
+	assertParseError(t, "--Europe_ ", 9)
+}
+
+func TestParseErrorMessage(t *testing.T) {
+	_, err := Parse("Braga")
+	assert.Equal(t, `invalid location "Braga" at position 5: expected '-' separator, format is <cities>-<countries>-<continents>`, err.Error())
+}
+
+func mustParseCondition(t *testing.T, input string) Condition {
+	expression, err := Parse(input)
+	assert.NoError(t, err, input)
+	assert.False(t, expression.IsEmpty())
+	return expression.Condition
+}
+
+func TestParseConditionMatch(t *testing.T) {
+	condition := mustParseCondition(t, "country in (PT, ES)")
+
+	assert.Equal(t, &Match{
+		Level:  LevelCountry,
+		Values: []Name{{Value: "PT", Pos: 12}, {Value: "ES", Pos: 16}},
+		Pos:    0,
+	}, condition)
+
+	assert.Equal(t, &Match{Level: LevelContinent, Values: []Name{{Value: "Europe", Pos: 10}}}, mustParseCondition(t, "continent=Europe"))
+}
+
+func TestParseConditionPrecedence(t *testing.T) {
+	assert.Equal(t,
+		`(country in ("PT", "ES") and not city in ("Madrid"))`,
+		mustParseCondition(t, "country in (PT, ES) and not city in (Madrid)").String())
+
+	assert.Equal(t,
+		`(continent in ("Europe") or (country in ("US") and city in ("Boston")))`,
+		mustParseCondition(t, "continent=Europe or country=US and city=Boston").String())
+
+	assert.Equal(t,
+		`((continent in ("Europe") or country in ("US")) and city in ("Boston"))`,
+		mustParseCondition(t, "(continent=Europe or country=US) and city=Boston").String())
+}
+
+func TestParseConditionNegations(t *testing.T) {
+	assert.Equal(t, `not city in ("Madrid")`, mustParseCondition(t, "city != Madrid").String())
+	assert.Equal(t, `not country in ("PT", "ES")`, mustParseCondition(t, "country not in (PT, ES)").String())
+	assert.Equal(t, `not not city in ("Madrid")`, mustParseCondition(t, "NOT not City = Madrid").String())
+}
+
+func TestParseConditionQuotedValues(t *testing.T) {
+	condition := mustParseCondition(t, `country = "United States"`)
+	assert.Equal(t, []string{"United States"}, condition.(*Match).MatchValues())
+
+	condition = mustParseCondition(t, `country in ("in", Portugal)`)
+	assert.Equal(t, []string{"in", "Portugal"}, condition.(*Match).MatchValues())
+}
+
+func TestParseConditionErrors(t *testing.T) {
+	assertParseError(t, "country in (PT, ES", 18)
+	assertParseError(t, "country in PT", 11)
+	assertParseError(t, "country in ()", 12)
+	assertParseError(t, "region = Iberia", 0)
+	assertParseError(t, "country = PT and", 16)
+	assertParseError(t, "country = PT city = Braga", 13)
+	assertParseError(t, "(country = PT", 13)
+	assertParseError(t, "country ! PT", 8)
+	assertParseError(t, `country = "PT`, 10)
+	assertParseError(t, `country = ""`, 10)
+	assertParseError(t, "country = and", 10)
+	assertParseError(t, "country not PT", 12)
+	assertParseError(t, "country PT", 8)
+	assertParseError(t, "cidade = Braga", 0)
+}
+
+func TestParseConditionUnicodePositions(t *testing.T) {
+	condition := mustParseCondition(t, "city in (Évora, Braga)")
+	assert.Equal(t, 17, condition.(*Match).Values[1].Pos)
+}
+
+func TestAnyOf(t *testing.T) {
+	expression, _ := Parse("Braga-PT_ES-Europe")
+	assert.Equal(t, `((city in ("Braga") or country in ("PT", "ES")) or continent in ("Europe"))`, expression.AnyOf().String())
+
+	expression, _ = Parse("-PT-")
+	assert.Equal(t, `country in ("PT")`, expression.AnyOf().String())
+
+	expression, _ = Parse("--")
+	assert.Nil(t, expression.AnyOf())
+
+	expression, _ = Parse("country != PT")
+	assert.Equal(t, expression.Condition, expression.AnyOf())
+}
+
+func TestParsePreferences(t *testing.T) {
+	preferences, err := ParsePreferences("Porto:100, Lisbon:60, Spain : 20")
+	assert.NoError(t, err)
+	assert.Equal(t, []Preference{
+		{Name: Name{Value: "Porto", Pos: 0}, Weight: 100},
+		{Name: Name{Value: "Lisbon", Pos: 11}, Weight: 60},
+		{Name: Name{Value: "Spain", Pos: 22}, Weight: 20},
+	}, preferences)
+}
+
+func TestParsePreferencesOrdered(t *testing.T) {
+	preferences, err := ParsePreferences("Porto,Portugal, Europe")
+	assert.NoError(t, err)
+	assert.Equal(t, []Preference{
+		{Name: Name{Value: "Porto", Pos: 0}, Weight: 3},
+		{Name: Name{Value: "Portugal", Pos: 6}, Weight: 2},
+		{Name: Name{Value: "Europe", Pos: 16}, Weight: 1},
+	}, preferences)
+}
+
+func assertParsePreferencesError(t *testing.T, input string, pos int) {
+	_, err := ParsePreferences(input)
+
+	var parseErr *ParseError
+	assert.True(t, errors.Is(err, ErrInvalidLocation), input)
+	assert.True(t, errors.As(err, &parseErr), input)
+	assert.Equal(t, pos, parseErr.Pos, input)
+}
+
+func TestParsePreferencesErrors(t *testing.T) {
+	assertParsePreferencesError(t, "", 0)
+	assertParsePreferencesError(t, "Porto,,Spain", 6)
+	assertParsePreferencesError(t, ":10", 0)
+	assertParsePreferencesError(t, "Porto:", 6)
+	assertParsePreferencesError(t, "Porto: x", 7)
+	assertParsePreferencesError(t, "Porto:0", 6)
+	assertParsePreferencesError(t, "Porto:-5", 6)
+	assertParsePreferencesError(t, "Porto:10, Spain", 9)
+	assertParsePreferencesError(t, "Porto, Spain:10", 6)
+}
+
+func TestParseCoordinates(t *testing.T) {
+	coordinates, err := ParseCoordinates(" 41.15, -8.61")
+	assert.NoError(t, err)
+	assert.Equal(t, Coordinates{Latitude: 41.15, Longitude: -8.61}, coordinates)
+	assert.Equal(t, "41.15,-8.61", coordinates.String())
+
+	for input, pos := range map[string]int{"41.15": 5, "41.15,": 6, "41.15,x": 6, "1,2,3": 5, "91,0": 0, "0,-181": 0, "NaN,0": 0} {
+		_, err := ParseCoordinates(input)
+
+		var parseErr *ParseError
+		assert.True(t, errors.As(err, &parseErr), input)
+		assert.Equal(t, pos, parseErr.Pos, input)
+	}
+}
+
+func TestParseLatLong(t *testing.T) {
+	coordinates, err := ParseLatLong("41.15", "-8.61")
+	assert.NoError(t, err)
+	assert.Equal(t, Coordinates{Latitude: 41.15, Longitude: -8.61}, coordinates)
+
+	for _, latLong := range [][]string{{"", "0"}, {"0", "x"}, {"-90.5", "0"}, {"0", "180.5"}} {
+		_, err := ParseLatLong(latLong[0], latLong[1])
+		assert.True(t, errors.Is(err, ErrInvalidLocation), latLong)
+	}
+}
+
+func TestDistanceTo(t *testing.T) {
+	porto := Coordinates{Latitude: 41.1579, Longitude: -8.6291}
+	lisbon := Coordinates{Latitude: 38.7223, Longitude: -9.1393}
+
+	assert.InDelta(t, 274, porto.DistanceTo(lisbon), 1)
+	assert.InDelta(t, porto.DistanceTo(lisbon), lisbon.DistanceTo(porto), 1e-9)
+	assert.Equal(t, 0.0, porto.DistanceTo(porto))
+}
+
+func TestParseWithLevels(t *testing.T) {
+	levels := []Level{"rack", "zone", LevelCountry}
+
+	expression, err := ParseWithLevels("zone in (z1, z2) and country != ES", levels)
+	assert.NoError(t, err)
+	assert.Equal(t, `(zone in ("z1", "z2") and not country in ("ES"))`, expression.Condition.String())
+
+	_, err = ParseWithLevels("city = Braga", levels)
+	assert.True(t, errors.Is(err, ErrInvalidLocation))
+
+	// lists keep the city, country and continent sections
+	expression, err = ParseWithLevels("Braga-PT-", levels)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Braga"}, expression.CityNames())
+}
+
+func TestPositiveMatches(t *testing.T) {
+	condition := mustParseCondition(t, "(country = PT or city = Madrid) and not continent = Asia")
+
+	matches := PositiveMatches(condition)
+	assert.Equal(t, 2, len(matches))
+	assert.Equal(t, LevelCountry, matches[0].Level)
+	assert.Equal(t, []string{"Madrid"}, matches[1].MatchValues())
+}
+
+func TestCatalogRegister(t *testing.T) {
+	catalog := NewCatalog()
+	latitude, longitude := 41.15, -8.61
+
+	assert.NoError(t, catalog.Register(Site{Name: "edge-site-42", Country: "Portugal", Latitude: &latitude, Longitude: &longitude}))
+	assert.NoError(t, catalog.Register(Site{Name: "oil-rig-7", Continent: "Europe"}))
+
+	site, ok := catalog.Find("Edge-Site-42")
+	assert.True(t, ok)
+	assert.Equal(t, "PT", site.Country)
+	assert.Equal(t, "EU", site.Continent)
+	assert.Equal(t, LevelCity, site.Level())
+	assert.Equal(t, "PT-edge-site-42", site.Code())
+
+	coordinates, ok := site.Coordinates()
+	assert.True(t, ok)
+	assert.Equal(t, Coordinates{Latitude: 41.15, Longitude: -8.61}, coordinates)
+
+	site, ok = catalog.FindByCode("oil-rig-7")
+	assert.True(t, ok)
+	assert.Equal(t, LevelCountry, site.Level())
+	_, ok = site.Coordinates()
+	assert.False(t, ok)
+
+	assert.Equal(t, 2, len(catalog.Sites()))
+}
+
+func TestCatalogRegisterInvalid(t *testing.T) {
+	catalog := NewCatalog()
+	catalog.MustRegister(Site{Name: "edge-site-42", Country: "PT"})
+	latitude := 91.0
+
+	invalid := []Site{
+		{Name: "", Country: "PT"},
+		{Name: "edge site", Country: "PT"},
+		{Name: "EDGE-SITE-42", Country: "PT"},
+		{Name: "Porto", Country: "PT"},
+		{Name: "Spain", Continent: "Europe"},
+		{Name: "ship-1"},
+		{Name: "ship-1", Country: "Atlantis"},
+		{Name: "ship-1", Continent: "Atlantis"},
+		{Name: "ship-1", Country: "PT", Continent: "Asia"},
+		{Name: "ship-1", Country: "PT", Latitude: &latitude},
+		{Name: "ship-1", Country: "PT", Latitude: &latitude, Longitude: &latitude},
+	}
+
+	for _, site := range invalid {
+		assert.Error(t, catalog.Register(site), site.Name)
+	}
+
+	assert.Panics(t, func() { catalog.MustRegister(Site{Name: "ship-1"}) })
+}
+
+func TestCatalogLoad(t *testing.T) {
+	catalog := NewCatalog()
+
+	assert.NoError(t, catalog.Load(strings.NewReader(`[{"name": "edge-site-42", "country": "PT", "latitude": 41.15, "longitude": -8.61}]`)))
+	assert.NoError(t, catalog.Load(strings.NewReader(`
+- name: oil-rig-7
+  continent: Europe
+- name: ship-1
+  country: Spain
+`)))
+	assert.Equal(t, 3, len(catalog.Sites()))
+
+	// invalid catalogs register no site
+	assert.Error(t, catalog.Load(strings.NewReader(`[{"name": "ship-2", "country": "PT"}, {"name": "ship-2", "country": "ES"}]`)))
+	assert.Error(t, catalog.Load(strings.NewReader(`[{"name": "ship-3", "country": "PT"}, {"name": "ship-4"}]`)))
+	assert.Error(t, catalog.Load(strings.NewReader(`[{"name": "ship-5", "region": "PT"}]`)))
+	assert.Equal(t, 3, len(catalog.Sites()))
+}
+
+func TestLoadCatalog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sites.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("- name: edge-site-42\n  country: PT\n"), 0600))
+
+	catalog, err := LoadCatalog(path)
+	assert.NoError(t, err)
+
+	_, ok := catalog.Find("edge-site-42")
+	assert.True(t, ok)
+
+	_, err = LoadCatalog(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestBorderingCountries(t *testing.T) {
+	assert.Equal(t, [][]string{{"ES"}, {"AD", "FR", "GI", "MA"}}, BorderingCountries([]string{"PT"}, 2))
+	assert.Equal(t, [][]string{{"AD", "BE", "CH", "DE", "GI", "IT", "LU", "MA", "MC", "PT"}}, BorderingCountries([]string{"ES", "FR"}, 1))
+	assert.Equal(t, 0, len(BorderingCountries([]string{"IS", "XX"}, 3)))
+	assert.Equal(t, 0, len(BorderingCountries([]string{"PT"}, 0)))
+}
@@ -0,0 +1,122 @@
+package locations
+
+import (
+	"strings"
+	"unicode"
+)
+
+const levelSeparator = "-"
+const listSeparator = "_"
+
+// levels is the number of location levels in a label: cities, countries and continents
+const levels = 3
+
+// DefaultLevels are the levels boolean expressions match by default
+var DefaultLevels = []Level{LevelCity, LevelCountry, LevelContinent}
+
+// Parse validates a workload location label and returns its parsed expression
+// Labels containing operators or starting with a level keyword are parsed as boolean expressions,
+// others use the list format
+// It returns a *ParseError pointing to the offending position if the label is malformed
+func Parse(input string) (*Expression, error) {
+	return ParseWithLevels(input, DefaultLevels)
+}
+
+// ParseWithLevels works as Parse but boolean expressions match the given levels, such as a custom topology ones
+// The list format always lists cities, countries and continents
+func ParseWithLevels(input string, expressionLevels []Level) (*Expression, error) {
+	if isConditionExpression(input, expressionLevels) {
+		condition, err := parseCondition(input, expressionLevels)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Expression{
+			Cities:     make([]Name, 0),
+			Countries:  make([]Name, 0),
+			Continents: make([]Name, 0),
+			Condition:  condition,
+		}, nil
+	}
+
+	sections := split(input, 0, levelSeparator)
+
+	if len(sections) < levels {
+		return nil, &ParseError{
+			Input:   input,
+			Pos:     len(input),
+			Message: "expected '-' separator, format is <cities>-<countries>-<continents>",
+		}
+	}
+
+	if len(sections) > levels {
+		return nil, &ParseError{
+			Input:   input,
+			Pos:     sections[levels].Pos - len(levelSeparator),
+			Message: "unexpected '-' separator, format is <cities>-<countries>-<continents>",
+		}
+	}
+
+	lists := make([][]Name, 0, levels)
+
+	for _, section := range sections {
+		list, err := parseList(input, section)
+		if err != nil {
+			return nil, err
+		}
+
+		lists = append(lists, list)
+	}
+
+	return &Expression{
+		Cities:     lists[0],
+		Countries:  lists[1],
+		Continents: lists[2],
+	}, nil
+}
+
+func parseList(input string, section Name) ([]Name, error) {
+	list := make([]Name, 0)
+
+	if section.Value == "" {
+		return list, nil
+	}
+
+	for _, name := range split(section.Value, section.Pos, listSeparator) {
+		name, err := trimName(input, name)
+		if err != nil {
+			return nil, err
+		}
+
+		list = append(list, name)
+	}
+
+	return list, nil
+}
+
+// trimName trims the spaces around a location name, keeping its position, and rejects empty names
+func trimName(input string, name Name) (Name, error) {
+	trimmed := strings.TrimLeftFunc(name.Value, unicode.IsSpace)
+
+	if trimmed == "" {
+		return Name{}, &ParseError{Input: input, Pos: name.Pos, Message: "empty location name"}
+	}
+
+	name.Pos += len(name.Value) - len(trimmed)
+	name.Value = strings.TrimRightFunc(trimmed, unicode.IsSpace)
+
+	return name, nil
+}
+
+// split works like strings.Split but keeps the offset of each part, starting at the given offset
+func split(value string, offset int, separator string) []Name {
+	parts := strings.Split(value, separator)
+	names := make([]Name, 0, len(parts))
+
+	for _, part := range parts {
+		names = append(names, Name{Value: part, Pos: offset})
+		offset += len(part) + len(separator)
+	}
+
+	return names
+}
@@ -0,0 +1,76 @@
+package locations
+
+import (
+	"strconv"
+	"strings"
+)
+
+const preferenceSeparator = ","
+const weightSeparator = ":"
+
+// Preference is a location of a weighted preferred locations label and its weight
+type Preference struct {
+	Name
+
+	// Weight ranks the preference, higher weights are preferred
+	Weight int
+}
+
+// ParsePreferences validates a weighted preferred locations label and returns its preferences in order
+// Labels list city, country or continent names with optional positive weights: Porto:100, Lisbon:60, Spain:20
+// Weights must be set on every name or on none, names without weights are weighted by their order
+// It returns a *ParseError pointing to the offending position if the label is malformed
+func ParsePreferences(input string) ([]Preference, error) {
+	parts := split(input, 0, preferenceSeparator)
+	preferences := make([]Preference, 0, len(parts))
+	weighted := 0
+
+	for _, part := range parts {
+		name := part
+		weight := -1
+
+		if i := strings.LastIndex(part.Value, weightSeparator); i >= 0 {
+			var err error
+			name = Name{Value: part.Value[:i], Pos: part.Pos}
+
+			if weight, err = parseWeight(input, Name{Value: part.Value[i+1:], Pos: part.Pos + i + 1}); err != nil {
+				return nil, err
+			}
+
+			weighted++
+		}
+
+		if weighted != 0 && weighted != len(preferences)+1 {
+			return nil, &ParseError{Input: input, Pos: part.Pos, Message: "weights must be set on every location or on none"}
+		}
+
+		name, err := trimName(input, name)
+		if err != nil {
+			return nil, err
+		}
+
+		preferences = append(preferences, Preference{Name: name, Weight: weight})
+	}
+
+	if weighted == 0 {
+		for i := range preferences {
+			preferences[i].Weight = len(preferences) - i
+		}
+	}
+
+	return preferences, nil
+}
+
+func parseWeight(input string, weight Name) (int, error) {
+	trimmed, err := trimName(input, weight)
+	if err != nil {
+		return 0, &ParseError{Input: input, Pos: weight.Pos, Message: "expected weight after ':'"}
+	}
+
+	value, err := strconv.Atoi(trimmed.Value)
+	if err != nil || value <= 0 {
+		return 0, &ParseError{Input: input, Pos: trimmed.Pos, Message: "weight must be a positive integer"}
+	}
+
+	return value, nil
+}
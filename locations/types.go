@@ -0,0 +1,146 @@
+package locations
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidLocation is returned when a workload location label is malformed
+var ErrInvalidLocation = errors.New("invalid location")
+
+// ErrUnknownLocation is returned when location names can't be resolved
+var ErrUnknownLocation = errors.New("unknown location")
+
+// Expression represents a parsed workload location label
+// Labels either use the list format, filling Cities, Countries and Continents:
+// (<CITY>?(_<CITY>)*)-(<COUNTRY>?(_<COUNTRY>)*)-(<CONTINENT>?(_<CONTINENT>)*)
+// or the boolean expressions format, filling Condition:
+// country in (PT, ES) and not city = Madrid
+type Expression struct {
+	Cities     []Name
+	Countries  []Name
+	Continents []Name
+
+	// Condition is set when the label uses the boolean expressions format
+	Condition Condition
+}
+
+// Name is a location name and its position in the parsed label
+type Name struct {
+	// Value is the location name as written in the label
+	Value string
+
+	// Pos is the byte offset of the name in the label
+	Pos int
+}
+
+// ParseError describes why and where a location label is malformed
+// It matches ErrInvalidLocation with errors.Is
+type ParseError struct {
+	// Input is the label being parsed
+	Input string
+
+	// Pos is the byte offset where the error was found
+	Pos int
+
+	// Message describes the error
+	Message string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s %q at position %d: %s", ErrInvalidLocation, e.Input, e.Pos, e.Message)
+}
+
+// Is reports whether target is ErrInvalidLocation
+func (e *ParseError) Is(target error) bool {
+	return target == ErrInvalidLocation
+}
+
+// UnknownLocationError lists the location names that could not be resolved
+// It matches ErrUnknownLocation with errors.Is
+type UnknownLocationError struct {
+	// Names lists the unresolved location names
+	Names []string
+}
+
+func (e *UnknownLocationError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrUnknownLocation, strings.Join(e.Names, ", "))
+}
+
+// Is reports whether target is ErrUnknownLocation
+func (e *UnknownLocationError) Is(target error) bool {
+	return target == ErrUnknownLocation
+}
+
+// CityNames lists the city names values
+func (e *Expression) CityNames() []string {
+	return values(e.Cities)
+}
+
+// CountryNames lists the country names values
+func (e *Expression) CountryNames() []string {
+	return values(e.Countries)
+}
+
+// ContinentNames lists the continent names values
+func (e *Expression) ContinentNames() []string {
+	return values(e.Continents)
+}
+
+// AnyOf returns a condition matching nodes in any of the expression locations
+// Boolean expressions return their own condition, empty expressions return nil
+func (e *Expression) AnyOf() Condition {
+	if e.Condition != nil {
+		return e.Condition
+	}
+
+	var condition Condition
+	lists := []struct {
+		level Level
+		names []Name
+	}{{LevelCity, e.Cities}, {LevelCountry, e.Countries}, {LevelContinent, e.Continents}}
+
+	for _, list := range lists {
+		if len(list.names) == 0 {
+			continue
+		}
+
+		match := &Match{Level: list.level, Values: list.names, Pos: list.names[0].Pos}
+		if condition == nil {
+			condition = match
+		} else {
+			condition = &Or{Left: condition, Right: match}
+		}
+	}
+
+	return condition
+}
+
+// IsEmpty reports whether the expression doesn't reference any location
+func (e *Expression) IsEmpty() bool {
+	return e.Condition == nil && len(e.Cities) == 0 && len(e.Countries) == 0 && len(e.Continents) == 0
+}
+
+// String formats the expression back into the label format
+func (e *Expression) String() string {
+	if e.Condition != nil {
+		return e.Condition.String()
+	}
+
+	return strings.Join([]string{
+		strings.Join(e.CityNames(), listSeparator),
+		strings.Join(e.CountryNames(), listSeparator),
+		strings.Join(e.ContinentNames(), listSeparator),
+	}, levelSeparator)
+}
+
+func values(names []Name) []string {
+	list := make([]string, 0, len(names))
+
+	for _, name := range names {
+		list = append(list, name.Value)
+	}
+
+	return list
+}
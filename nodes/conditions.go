@@ -0,0 +1,119 @@
+package nodes
+
+import (
+	"fmt"
+	"github.com/geolocate-orchestration/scheduler/locations"
+)
+
+// GetNodesMatching lists the nodes whose location satisfies the boolean location condition
+// It returns a *locations.UnknownLocationError if any of the condition location names can't be resolved
+func (n *Nodes) GetNodesMatching(condition locations.Condition) ([]*Node, error) {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	if err := n.checkLevels(condition); err != nil {
+		return nil, err
+	}
+
+	unknown := make([]string, 0)
+	matching := n.evaluate(condition, &unknown)
+
+	if len(unknown) > 0 {
+		return nil, &locations.UnknownLocationError{Names: unknown}
+	}
+
+	filtered := make([]*Node, 0, len(matching))
+	for _, node := range n.Nodes {
+		if matching[node.Name] {
+			filtered = append(filtered, node)
+		}
+	}
+
+	return filtered, nil
+}
+
+// evaluate returns the set of node names satisfying the condition
+func (n *Nodes) evaluate(condition locations.Condition, unknown *[]string) map[string]bool {
+	switch c := condition.(type) {
+	case *locations.And:
+		left := n.evaluate(c.Left, unknown)
+		right := n.evaluate(c.Right, unknown)
+
+		for name := range left {
+			if !right[name] {
+				delete(left, name)
+			}
+		}
+
+		return left
+	case *locations.Or:
+		left := n.evaluate(c.Left, unknown)
+
+		for name := range n.evaluate(c.Right, unknown) {
+			left[name] = true
+		}
+
+		return left
+	case *locations.Not:
+		excluded := n.evaluate(c.Condition, unknown)
+		matching := make(map[string]bool)
+
+		for _, node := range n.Nodes {
+			if !excluded[node.Name] {
+				matching[node.Name] = true
+			}
+		}
+
+		return matching
+	case *locations.Match:
+		return n.evaluateMatch(c, unknown)
+	}
+
+	return make(map[string]bool)
+}
+
+func (n *Nodes) evaluateMatch(match *locations.Match, unknown *[]string) map[string]bool {
+	level := string(match.Level)
+	index := n.index(level, false)
+	matching := make(map[string]bool)
+
+	for _, value := range match.MatchValues() {
+		code, err := n.resolve(level, value)
+		if err != nil {
+			*unknown = append(*unknown, value)
+			continue
+		}
+
+		for _, node := range index[code] {
+			matching[node.Name] = true
+		}
+	}
+
+	return matching
+}
+
+// checkLevels returns an error if the condition matches levels outside the topology
+func (n *Nodes) checkLevels(condition locations.Condition) error {
+	switch c := condition.(type) {
+	case *locations.And:
+		if err := n.checkLevels(c.Left); err != nil {
+			return err
+		}
+
+		return n.checkLevels(c.Right)
+	case *locations.Or:
+		if err := n.checkLevels(c.Left); err != nil {
+			return err
+		}
+
+		return n.checkLevels(c.Right)
+	case *locations.Not:
+		return n.checkLevels(c.Condition)
+	case *locations.Match:
+		if _, ok := n.topology().Level(string(c.Level)); !ok {
+			return fmt.Errorf("%w: unknown topology level %q", locations.ErrInvalidLocation, c.Level)
+		}
+	}
+
+	return nil
+}
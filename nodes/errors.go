@@ -0,0 +1,11 @@
+package nodes
+
+import (
+	"errors"
+)
+
+// ErrNoNodes is returned when there are no nodes to choose from
+var ErrNoNodes = errors.New("no nodes are available")
+
+// ErrInsufficientResources is returned when a node doesn't have enough resources for a workload
+var ErrInsufficientResources = errors.New("not enough resources")
@@ -5,7 +5,7 @@ func (n *Nodes) filterNodes(filter *NodeFilter) []*Node {
 
 	var nodeList []*Node
 
-	if filter.Locations.Cities == nil && filter.Locations.Countries == nil && filter.Locations.Continents == nil {
+	if filter.Locations.isEmpty() {
 		nodeList = n.Nodes
 	} else {
 		nodeList = n.buildFromLocations(filter.Locations)
@@ -25,6 +25,14 @@ func (n *Nodes) buildFromLocations(locations Locations) []*Node {
 	n.getCountries(locations.Countries, &nodesMap)
 	n.getContinents(locations.Continents, &nodesMap)
 
+	for level, codes := range locations.Levels {
+		for _, code := range codes {
+			for _, node := range n.index(level, false)[code] {
+				nodesMap[node.Name] = node
+			}
+		}
+	}
+
 	nodes := make([]*Node, 0, len(nodesMap))
 	for _, value := range nodesMap {
 		nodes = append(nodes, value)
@@ -56,6 +64,10 @@ func (n *Nodes) getContinents(locations []string, nodesMap *map[string]*Node) {
 	}
 }
 
+func (l Locations) isEmpty() bool {
+	return l.Cities == nil && l.Countries == nil && l.Continents == nil && l.Levels == nil
+}
+
 func nodeMatchesFilters(node *Node, filter *NodeFilter) bool {
 	if filter == nil {
 		return true
@@ -94,10 +106,25 @@ func matchesResources(node *Node, resources Resources) bool {
 	return true
 }
 
+// InsufficientResources lists the resources ("cpu", "memory") the node lacks to fit the requested ones
+func InsufficientResources(node *Node, resources Resources) []string {
+	missing := make([]string, 0)
+
+	if resources.CPU != 0 && !nodeHasAllocatableCPU(node, resources.CPU) {
+		missing = append(missing, "cpu")
+	}
+
+	if resources.Memory != 0 && !nodeHasAllocatableMemory(node, resources.Memory) {
+		missing = append(missing, "memory")
+	}
+
+	return missing
+}
+
 func nodeHasAllocatableCPU(node *Node, cpu int64) bool {
-	return node.CPU >= cpu
+	return node.CPU-node.Requested.CPU >= cpu
 }
 
 func nodeHasAllocatableMemory(node *Node, memory int64) bool {
-	return node.Memory >= memory
+	return node.Memory-node.Requested.Memory >= memory
 }
@@ -4,54 +4,91 @@ import (
 	"errors"
 	"fmt"
 	"github.com/geolocate-orchestration/gountries"
-	"github.com/geolocate-orchestration/scheduler/labels"
+	"github.com/geolocate-orchestration/scheduler/locations"
 	"k8s.io/klog/v2"
+	"strings"
 )
 
-func (n *Nodes) addToCities(node *Node) {
-	cityValue := node.Labels[labels.NodeCity]
-
-	if cityValue != "" {
-		if city, err := n.Query.FindSubdivisionByName(cityValue); err == nil {
-			cityCode := fmt.Sprintf("%s-%s", city.CountryAlpha2, city.Code)
-			n.Cities[cityCode] = append(n.Cities[cityCode], node)
-		} else {
-			klog.Errorln(err)
-		}
+func (n *Nodes) addNode(node *Node) {
+	if !n.nodeHasAnyLabel(node) {
+		// Don't add new node if it doesn't have the node.geolocate.io role
+		return
 	}
-}
 
-func (n *Nodes) addToCountries(node *Node) {
-	countryValue := node.Labels[labels.NodeCountry]
+	// Keep a private copy so later changes to the given node don't leak into the cache
+	node = cloneNode(node)
+	node.Requested = n.requestedOn(node.Name)
 
-	if countryValue != "" {
-		if country, err := n.findCountry(countryValue); err == nil {
-			n.Countries[country.Alpha2] = append(n.Countries[country.Alpha2], node)
-		} else {
-			klog.Errorln(err)
-		}
-	}
+	n.Nodes = append(n.Nodes, node)
+	n.addToLevels(node)
+	n.addToCells(node)
+	klog.Infof("node added to cache: %s\n", node.Name)
+}
+
+func (n *Nodes) deleteNode(node *Node) {
+	n.removeNodeFromNodes(node)
+	n.removeNodeFromLevels(node)
+	n.removeNodeFromCells(node)
+	klog.Infof("node deleted from cache: %s\n", node.Name)
 }
 
-func (n *Nodes) addToContinents(node *Node) {
-	continentValue := node.Labels[labels.NodeContinent]
+func (n *Nodes) addToLevels(node *Node) {
+	for _, level := range n.topology().Levels {
+		value := node.Labels[level.Label]
 
-	if continentValue != "" {
-		if continent, err := n.ContinentsList.FindContinent(continentValue); err == nil {
-			n.Continents[continent.Code] = append(n.Continents[continent.Code], node)
-		} else {
-			klog.Errorln(err)
+		if value != "" {
+			if code, err := n.resolve(level.Name, value); err == nil {
+				index := n.index(level.Name, true)
+				index[code] = append(index[code], node)
+			} else {
+				klog.Errorln(err)
+			}
 		}
 	}
 }
 
 func (n *Nodes) updateNodeData(savedNode *Node, newNode *Node) {
-	if nodeHasSignificantChanges(savedNode, newNode) {
-		n.DeleteNode(savedNode)
-		n.AddNode(newNode)
+	if n.nodeHasSignificantChanges(savedNode, newNode) {
+		n.deleteNode(savedNode)
+		n.addNode(newNode)
 		klog.Infof("node replaced in cache: %s\n", savedNode.Name)
 	} else {
-		n.updateNodeFields(savedNode, newNode)
+		// Cached nodes may be shared with snapshots so they are replaced instead of modified
+		updatedNode := cloneNode(savedNode)
+		n.updateNodeFields(updatedNode, newNode)
+		n.replaceNode(updatedNode)
+	}
+}
+
+func (n *Nodes) replaceNode(node *Node) {
+	replaceInList(n.Nodes, node)
+
+	for _, index := range []map[string][]*Node{n.Cities, n.Countries, n.Continents, n.Cells} {
+		for _, list := range index {
+			replaceInList(list, node)
+		}
+	}
+
+	for _, index := range n.Levels {
+		for _, list := range index {
+			replaceInList(list, node)
+		}
+	}
+}
+
+func (n *Nodes) copyNodes() *Nodes {
+	return &Nodes{
+		Query:          n.Query,
+		ContinentsList: n.ContinentsList,
+		Topology:       n.Topology,
+		Catalog:        n.Catalog,
+
+		Nodes:      copyNodeList(n.Nodes),
+		Cities:     copyNodeIndex(n.Cities),
+		Countries:  copyNodeIndex(n.Countries),
+		Continents: copyNodeIndex(n.Continents),
+		Cells:      copyNodeIndex(n.Cells),
+		Levels:     copyLevelIndexes(n.Levels),
 	}
 }
 
@@ -102,58 +139,133 @@ func (n *Nodes) removeNodeFromNodes(node *Node) {
 	for i, v := range n.Nodes {
 		if v.Name == node.Name {
 			n.Nodes = append(n.Nodes[:i], n.Nodes[i+1:]...)
+			return
 		}
 	}
 }
 
-func (n *Nodes) removeNodeFromCities(node *Node) {
-	cityValue := node.Labels[labels.NodeCity]
+func (n *Nodes) removeNodeFromLevels(node *Node) {
+	for _, level := range n.topology().Levels {
+		value := node.Labels[level.Label]
+
+		if value != "" {
+			if code, err := n.resolve(level.Name, value); err == nil {
+				index := n.index(level.Name, false)
 
-	if cityValue != "" {
-		if city, err := n.Query.FindSubdivisionByName(cityValue); err == nil {
-			cityCode := fmt.Sprintf("%s-%s", city.CountryAlpha2, city.Code)
-			for i, v := range n.Cities[cityCode] {
-				if v.Name == node.Name {
-					n.Cities[cityCode] = append(n.Cities[cityCode][:i], n.Cities[cityCode][i+1:]...)
+				for i, v := range index[code] {
+					if v.Name == node.Name {
+						index[code] = append(index[code][:i], index[code][i+1:]...)
+						break
+					}
 				}
+			} else {
+				klog.Errorln(err)
 			}
-		} else {
-			klog.Errorln(err)
 		}
 	}
 }
 
-func (n *Nodes) removeNodeFromCountries(node *Node) {
-	countryValue := node.Labels[labels.NodeCountry]
-	if countryValue != "" {
-		if country, err := n.findCountry(countryValue); err == nil {
-			for i, v := range n.Countries[country.Alpha2] {
-				if v.Name == node.Name {
-					n.Countries[country.Alpha2] =
-						append(n.Countries[country.Alpha2][:i], n.Countries[country.Alpha2][i+1:]...)
-				}
-			}
-		} else {
-			klog.Errorln(err)
-		}
+// topology returns the cache topology, caches built without one use the default topology
+func (n *Nodes) topology() *Topology {
+	if n.Topology == nil {
+		return defaultTopology
 	}
+
+	return n.Topology
 }
 
-func (n *Nodes) removeNodeFromContinents(node *Node) {
-	continentValue := node.Labels[labels.NodeContinent]
+func (n *Nodes) catalog() *locations.Catalog {
+	if n.Catalog == nil {
+		return locations.DefaultCatalog
+	}
 
-	if continentValue != "" {
-		if continent, err := n.ContinentsList.FindContinent(continentValue); err == nil {
-			for i, v := range n.Continents[continent.Code] {
-				if v.Name == node.Name {
-					n.Continents[continent.Code] =
-						append(n.Continents[continent.Code][:i], n.Continents[continent.Code][i+1:]...)
-				}
-			}
-		} else {
-			klog.Errorln(err)
+	return n.Catalog
+}
+
+// index returns the index of a topology level, creating it when create is set
+func (n *Nodes) index(level string, create bool) map[string][]*Node {
+	var index *map[string][]*Node
+
+	switch level {
+	case LevelCity:
+		index = &n.Cities
+	case LevelCountry:
+		index = &n.Countries
+	case LevelContinent:
+		index = &n.Continents
+	default:
+		if n.Levels == nil && create {
+			n.Levels = make(map[string]map[string][]*Node)
+		}
+
+		if n.Levels[level] == nil && create {
+			n.Levels[level] = make(map[string][]*Node)
 		}
+
+		return n.Levels[level]
 	}
+
+	if *index == nil && create {
+		*index = make(map[string][]*Node)
+	}
+
+	return *index
+}
+
+// resolve returns the index code of a location at a topology level
+// Built-in levels are resolved through gountries, other levels use the lower cased value
+func (n *Nodes) resolve(level string, value string) (string, error) {
+	switch level {
+	case LevelCity:
+		return n.cityCode(value)
+	case LevelCountry:
+		return n.countryCode(value)
+	case LevelContinent:
+		return n.continentCode(value)
+	}
+
+	code := strings.ToLower(strings.TrimSpace(value))
+	if code == "" {
+		return "", fmt.Errorf("empty %s location", level)
+	}
+
+	return code, nil
+}
+
+func (n *Nodes) cityCode(cityName string) (string, error) {
+	city, err := n.Query.FindSubdivisionByName(cityName)
+	if err != nil {
+		return n.siteCode(locations.LevelCity, cityName, err)
+	}
+
+	return fmt.Sprintf("%s-%s", city.CountryAlpha2, city.Code), nil
+}
+
+func (n *Nodes) countryCode(countryID string) (string, error) {
+	country, err := n.findCountry(countryID)
+	if err != nil {
+		return n.siteCode(locations.LevelCountry, countryID, err)
+	}
+
+	return country.Alpha2, nil
+}
+
+// siteCode resolves a location gountries doesn't know against the catalog sites of the given level
+func (n *Nodes) siteCode(level locations.Level, name string, notFound error) (string, error) {
+	if site, ok := n.catalog().Find(name); ok && site.Level() == level {
+		return site.Code(), nil
+	}
+
+	return "", notFound
+}
+
+func (n *Nodes) continentCode(continentID string) (string, error) {
+	continent, err := n.ContinentsList.FindContinent(continentID)
+	if err != nil {
+		return "", err
+	}
+
+	return continent.Code, nil
 }
 
 func (n *Nodes) findCountry(countryID string) (gountries.Country, error) {
@@ -1,10 +1,16 @@
 package nodes
 
 import (
+	"errors"
+	"fmt"
 	"github.com/geolocate-orchestration/gountries"
 	"github.com/geolocate-orchestration/scheduler/labels"
+	"github.com/geolocate-orchestration/scheduler/locations"
 	"github.com/stretchr/testify/assert"
+	"sort"
+	"sync"
 	"testing"
+	"time"
 )
 
 func newTestNode(name string, labeled bool, city string, country string, continent string) *Node {
@@ -37,6 +43,7 @@ func newTestNodes() *Nodes {
 		Cities:     make(map[string][]*Node),
 		Countries:  make(map[string][]*Node),
 		Continents: make(map[string][]*Node),
+		Cells:      make(map[string][]*Node),
 	}
 }
 
@@ -149,8 +156,8 @@ func TestNodeHasSignificantChanges(t *testing.T) {
 	oldNode := newTestNode("Node0", true, "Braga", "Portugal", "Europe")
 	newNode := newTestNode("Node0", true, "Porto", "Portugal", "Europe")
 
-	assert.Equal(t, false, nodeHasSignificantChanges(oldNode, oldNode))
-	assert.Equal(t, true, nodeHasSignificantChanges(oldNode, newNode))
+	assert.Equal(t, false, newTestNodes().nodeHasSignificantChanges(oldNode, oldNode))
+	assert.Equal(t, true, newTestNodes().nodeHasSignificantChanges(oldNode, newNode))
 }
 
 func TestFindNodeByName(t *testing.T) {
@@ -223,7 +230,7 @@ func TestGetRandomFromListHit(t *testing.T) {
 
 func TestGetRandomFromListEmptyError(t *testing.T) {
 	_, err := GetRandomFromList([]*Node{})
-	assert.Error(t, err)
+	assert.True(t, errors.Is(err, ErrNoNodes))
 }
 
 func TestGetRandomFromMapHit(t *testing.T) {
@@ -236,3 +243,639 @@ func TestGetRandomFromMapError(t *testing.T) {
 	_, err := GetRandomFromMap(map[string][]*Node{})
 	assert.Error(t, err)
 }
+
+func TestSnapshotIsImmutable(t *testing.T) {
+	nodes := newTestNodes()
+	node := newTestNode("Node0", true, "Braga", "Portugal", "Europe")
+	node.CPU = 1000
+	nodes.AddNode(node)
+
+	snapshot := nodes.Snapshot()
+
+	updatedNode := newTestNode("Node0", true, "Braga", "Portugal", "Europe")
+	updatedNode.CPU = 2000
+	nodes.UpdateNode(node, updatedNode)
+	nodes.AddNode(newTestNode("Node1", true, "Braga", "Portugal", "Europe"))
+
+	assert.Equal(t, 1, snapshot.CountNodes())
+	assert.Equal(t, int64(1000), snapshot.GetAllNodes()[0].CPU)
+	assert.Equal(t, 1, len(snapshot.GetNodes(&NodeFilter{Locations: Locations{Cities: []string{"PT-03"}}})))
+
+	assert.Equal(t, 2, nodes.CountNodes())
+	assert.Equal(t, int64(2000), nodes.GetAllNodes()[0].CPU)
+	assert.Equal(t, 2, len(nodes.GetNodes(&NodeFilter{Locations: Locations{Cities: []string{"PT-03"}}})))
+}
+
+func TestAddNodeKeepsPrivateCopy(t *testing.T) {
+	nodes := newTestNodes()
+	node := newTestNode("Node0", true, "Braga", "Portugal", "Europe")
+	nodes.AddNode(node)
+
+	node.CPU = 1000
+	node.Labels["test_label"] = "test"
+
+	assert.Equal(t, int64(0), nodes.GetAllNodes()[0].CPU)
+	assert.NotContains(t, nodes.GetAllNodes()[0].Labels, "test_label")
+}
+
+func TestUpdateNodeDoesNotModifyReturnedNodes(t *testing.T) {
+	nodes := newTestNodes()
+	oldNode := newTestNode("Node0", true, "Braga", "Portugal", "Europe")
+	nodes.AddNode(oldNode)
+
+	returned := nodes.GetNodes(&NodeFilter{Locations: Locations{Countries: []string{"PT"}}})[0]
+
+	newNode := newTestNode("Node0", true, "Braga", "Portugal", "Europe")
+	newNode.CPU = 1000
+	nodes.UpdateNode(oldNode, newNode)
+
+	assert.Equal(t, int64(0), returned.CPU)
+	assert.Equal(t, int64(1000), nodes.GetNodes(&NodeFilter{Locations: Locations{Countries: []string{"PT"}}})[0].CPU)
+}
+
+// TestConcurrentAccess is meant to be run with the race detector enabled
+func TestConcurrentAccess(t *testing.T) {
+	nodes := New()
+	cities := []string{"Braga", "Porto", "Madrid"}
+	countries := []string{"Portugal", "Portugal", "Spain"}
+	filter := &NodeFilter{
+		Resources: Resources{CPU: 1},
+		Locations: Locations{Countries: []string{"PT", "ES"}},
+	}
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			for i := 0; i < 200; i++ {
+				name := fmt.Sprintf("Node%d-%d", w, i%10)
+				node := newTestNode(name, true, cities[i%3], countries[i%3], "Europe")
+				node.CPU = int64(i)
+
+				switch i % 3 {
+				case 0:
+					nodes.AddNode(node)
+				case 1:
+					moved := newTestNode(name, true, cities[(i+1)%3], countries[(i+1)%3], "Europe")
+					moved.CPU = int64(i)
+					nodes.UpdateNode(node, moved)
+				case 2:
+					nodes.DeleteNode(node)
+				}
+			}
+		}(w)
+	}
+
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 200; i++ {
+				snapshot := nodes.Snapshot()
+				count := snapshot.CountNodes()
+
+				assert.Equal(t, count, len(snapshot.GetAllNodes()))
+				for _, node := range snapshot.GetNodes(filter) {
+					assert.NotEmpty(t, node.Labels[labels.NodeCountry])
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+func newTestNodesWithResources(cpu int64, memory int64) *Nodes {
+	nodes := newTestNodes()
+	node := newTestNode("Node0", true, "Braga", "Portugal", "Europe")
+	node.CPU = cpu
+	node.Memory = memory
+	nodes.AddNode(node)
+	return nodes
+}
+
+func TestAssumeWorkloadChargesNode(t *testing.T) {
+	nodes := newTestNodesWithResources(1000, 1000)
+	filter := &NodeFilter{Resources: Resources{CPU: 600}}
+
+	assert.NoError(t, nodes.AssumeWorkload("Workload0", "Node0", Resources{CPU: 600, Memory: 100}))
+	assert.Equal(t, Resources{CPU: 600, Memory: 100}, nodes.GetAllNodes()[0].Requested)
+	assert.Equal(t, 0, len(nodes.GetNodes(filter)))
+	assert.Equal(t, 0, len(nodes.Snapshot().GetNodes(filter)))
+
+	assert.True(t, errors.Is(nodes.AssumeWorkload("Workload1", "Node0", Resources{CPU: 600}), ErrInsufficientResources))
+	assert.NoError(t, nodes.AssumeWorkload("Workload1", "Node0", Resources{CPU: 400}))
+}
+
+func TestAssumeWorkloadErrors(t *testing.T) {
+	nodes := newTestNodesWithResources(1000, 1000)
+
+	assert.Error(t, nodes.AssumeWorkload("", "Node0", Resources{}))
+	assert.Error(t, nodes.AssumeWorkload("Workload0", "Node1", Resources{}))
+	assert.NoError(t, nodes.AssumeWorkload("Workload0", "Node0", Resources{}))
+	assert.Error(t, nodes.AssumeWorkload("Workload0", "Node0", Resources{}))
+}
+
+func TestForgetWorkloadReleasesNode(t *testing.T) {
+	nodes := newTestNodesWithResources(1000, 1000)
+
+	assert.NoError(t, nodes.AssumeWorkload("Workload0", "Node0", Resources{CPU: 600, Memory: 100}))
+	nodes.ForgetWorkload("Workload0")
+	nodes.ForgetWorkload("Workload1")
+
+	assert.Equal(t, Resources{}, nodes.GetAllNodes()[0].Requested)
+}
+
+func TestAssumedWorkloadExpires(t *testing.T) {
+	nodes := newTestNodesWithResources(1000, 1000)
+	now := time.Now()
+	nodes.now = func() time.Time { return now }
+	nodes.SetAssumeTTL(time.Minute)
+
+	assert.NoError(t, nodes.AssumeWorkload("Workload0", "Node0", Resources{CPU: 600}))
+	assert.NoError(t, nodes.AssumeWorkload("Workload1", "Node0", Resources{CPU: 100}))
+	assert.NoError(t, nodes.BindWorkload("Workload1", "Node0", Resources{CPU: 100}))
+
+	now = now.Add(2 * time.Minute)
+	snapshot := nodes.Snapshot()
+
+	assert.Equal(t, Resources{CPU: 100}, snapshot.GetAllNodes()[0].Requested)
+}
+
+func TestAssumeTTLDisabled(t *testing.T) {
+	nodes := newTestNodesWithResources(1000, 1000)
+	now := time.Now()
+	nodes.now = func() time.Time { return now }
+	nodes.SetAssumeTTL(0)
+
+	assert.NoError(t, nodes.AssumeWorkload("Workload0", "Node0", Resources{CPU: 600}))
+
+	now = now.Add(time.Hour)
+	assert.Equal(t, Resources{CPU: 600}, nodes.Snapshot().GetAllNodes()[0].Requested)
+}
+
+func TestBindWorkload(t *testing.T) {
+	nodes := newTestNodesWithResources(1000, 1000)
+	nodes.AddNode(newTestNode("Node1", true, "Porto", "Portugal", "Europe"))
+
+	// Bind without assume charges the node
+	assert.NoError(t, nodes.BindWorkload("Workload0", "Node0", Resources{CPU: 100}))
+	assert.Equal(t, Resources{CPU: 100}, nodes.GetAllNodes()[0].Requested)
+
+	// Bind to a different node moves the charge
+	assert.NoError(t, nodes.BindWorkload("Workload0", "Node1", Resources{CPU: 100}))
+	assert.Equal(t, Resources{}, nodes.GetAllNodes()[0].Requested)
+	assert.Equal(t, Resources{CPU: 100}, nodes.GetAllNodes()[1].Requested)
+
+	assert.Error(t, nodes.BindWorkload("Workload0", "Node2", Resources{CPU: 100}))
+	assert.Error(t, nodes.BindWorkload("", "Node0", Resources{CPU: 100}))
+}
+
+func TestWorkloadChargeSurvivesNodeUpdates(t *testing.T) {
+	nodes := newTestNodesWithResources(1000, 1000)
+	assert.NoError(t, nodes.BindWorkload("Workload0", "Node0", Resources{CPU: 100}))
+
+	// Resources update
+	updated := newTestNode("Node0", true, "Braga", "Portugal", "Europe")
+	updated.CPU = 2000
+	nodes.UpdateNode(updated, updated)
+	assert.Equal(t, Resources{CPU: 100}, nodes.GetAllNodes()[0].Requested)
+
+	// Location update replaces the node
+	moved := newTestNode("Node0", true, "Porto", "Portugal", "Europe")
+	nodes.UpdateNode(updated, moved)
+	assert.Equal(t, Resources{CPU: 100}, nodes.GetAllNodes()[0].Requested)
+	assert.Equal(t, Resources{CPU: 100}, nodes.Cities["PT-13"][0].Requested)
+}
+
+func TestDeleteNodeForgetsWorkloads(t *testing.T) {
+	nodes := newTestNodesWithResources(1000, 1000)
+	node := nodes.GetAllNodes()[0]
+	assert.NoError(t, nodes.BindWorkload("Workload0", "Node0", Resources{CPU: 100}))
+
+	nodes.DeleteNode(node)
+	nodes.AddNode(node)
+
+	assert.Equal(t, Resources{}, nodes.GetAllNodes()[0].Requested)
+}
+
+func TestUnlabelNodeForgetsWorkloads(t *testing.T) {
+	nodes := newTestNodesWithResources(1000, 1000)
+	node := nodes.GetAllNodes()[0]
+	assert.NoError(t, nodes.BindWorkload("Workload0", "Node0", Resources{CPU: 100}))
+
+	unlabeled := newTestNode("Node0", false, "", "", "")
+	nodes.UpdateNode(node, unlabeled)
+	nodes.UpdateNode(unlabeled, node)
+
+	assert.Equal(t, Resources{}, nodes.GetAllNodes()[0].Requested)
+	assert.Empty(t, nodes.GetNodeWorkloads("Node0"))
+}
+
+func TestGetNodeWorkloads(t *testing.T) {
+	nodes := newTestNodesWithResources(1000, 1000)
+	nodes.AddNode(newTestNode("Node1", true, "Porto", "Portugal", "Europe"))
+	assert.NoError(t, nodes.BindWorkload("Workload1", "Node0", Resources{CPU: 100}))
+	assert.NoError(t, nodes.AssumeWorkload("Workload0", "Node0", Resources{Memory: 200}))
+	assert.NoError(t, nodes.BindWorkload("Workload2", "Node1", Resources{CPU: 300}))
+
+	assert.Equal(t, []NodeWorkload{
+		{Name: "Workload0", Node: "Node0", Resources: Resources{Memory: 200}},
+		{Name: "Workload1", Node: "Node0", Resources: Resources{CPU: 100}, Bound: true},
+	}, nodes.GetNodeWorkloads("Node0"))
+
+	nodes.ForgetWorkload("Workload2")
+	assert.Empty(t, nodes.GetNodeWorkloads("Node1"))
+	assert.Empty(t, nodes.GetNodeWorkloads("Node2"))
+}
+
+func newTestConditionNodes() *Nodes {
+	nodes := newTestNodes()
+	nodes.AddNode(newTestNode("Node0", true, "Braga", "Portugal", "Europe"))
+	nodes.AddNode(newTestNode("Node1", true, "Madrid", "Spain", "Europe"))
+	nodes.AddNode(newTestNode("Node2", true, "Barcelona", "Spain", "Europe"))
+	nodes.AddNode(newTestNode("Node3", true, "", "United States", "North America"))
+	nodes.AddNode(newTestNode("Node4", true, "", "France", "Europe"))
+	return nodes
+}
+
+func assertNodesMatching(t *testing.T, nodes *Nodes, expression string, expected []string) {
+	parsed, err := locations.Parse(expression)
+	assert.NoError(t, err)
+
+	matching, err := nodes.GetNodesMatching(parsed.Condition)
+	assert.NoError(t, err)
+
+	names := make([]string, 0)
+	for _, node := range matching {
+		names = append(names, node.Name)
+	}
+
+	assert.Equal(t, expected, names, expression)
+}
+
+func TestGetNodesMatching(t *testing.T) {
+	nodes := newTestConditionNodes()
+
+	assertNodesMatching(t, nodes, "country in (PT, ES)", []string{"Node0", "Node1", "Node2"})
+	assertNodesMatching(t, nodes, "country in (PT, ES) and not city in (Madrid)", []string{"Node0", "Node2"})
+	assertNodesMatching(t, nodes, "continent=Europe or country=US", []string{"Node0", "Node1", "Node2", "Node3", "Node4"})
+	assertNodesMatching(t, nodes, "continent = Europe and country != Spain", []string{"Node0", "Node4"})
+	assertNodesMatching(t, nodes, "not continent = Europe", []string{"Node3"})
+	assertNodesMatching(t, nodes, "city = Porto", []string{})
+}
+
+func TestGetNodesMatchingUnknownLocation(t *testing.T) {
+	nodes := newTestConditionNodes()
+	parsed, _ := locations.Parse("country in (PT, Portugall) or city = Bragaa")
+
+	_, err := nodes.GetNodesMatching(parsed.Condition)
+
+	var unknownErr *locations.UnknownLocationError
+	assert.True(t, errors.As(err, &unknownErr))
+	assert.Equal(t, []string{"Portugall", "Bragaa"}, unknownErr.Names)
+}
+
+func TestSnapshotGetNodesMatching(t *testing.T) {
+	nodes := newTestConditionNodes()
+	snapshot := nodes.Snapshot()
+	parsed, _ := locations.Parse("country = Spain")
+
+	nodes.DeleteNode(newTestNode("Node1", true, "Madrid", "Spain", "Europe"))
+
+	matching, err := snapshot.GetNodesMatching(parsed.Condition)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(matching))
+}
+
+func newTestSpatialNode(name string, latitude string, longitude string) *Node {
+	node := newTestNode(name, true, "", "", "")
+	node.Labels[labels.NodeLatitude] = latitude
+	node.Labels[labels.NodeLongitude] = longitude
+	return node
+}
+
+func newTestSpatialNodes() *Nodes {
+	nodes := newTestNodes()
+	nodes.AddNode(newTestSpatialNode("Porto", "41.1579", "-8.6291"))
+	nodes.AddNode(newTestSpatialNode("Braga", "41.5454", "-8.4265"))
+	nodes.AddNode(newTestSpatialNode("Lisbon", "38.7223", "-9.1393"))
+	nodes.AddNode(newTestSpatialNode("Madrid", "40.4168", "-3.7038"))
+	nodes.AddNode(newTestSpatialNode("Fiji", "-17.7134", "178.0650"))
+	nodes.AddNode(newTestSpatialNode("Samoa", "-13.7590", "-172.1046"))
+	nodes.AddNode(newTestSpatialNode("Svalbard", "78.2232", "15.6267"))
+	nodes.AddNode(newTestNode("Unlocated", true, "Braga", "Portugal", "Europe"))
+	return nodes
+}
+
+func nodeNames(list []*Node) []string {
+	names := make([]string, 0, len(list))
+	for _, node := range list {
+		names = append(names, node.Name)
+	}
+	return names
+}
+
+func TestGetNodesWithinRadius(t *testing.T) {
+	nodes := newTestSpatialNodes()
+
+	tests := []struct {
+		latitude  float64
+		longitude float64
+		km        float64
+		expected  []string
+	}{
+		{41.15, -8.61, 10, []string{"Porto"}},
+		{41.15, -8.61, 100, []string{"Porto", "Braga"}},
+		{41.15, -8.61, 500, []string{"Porto", "Braga", "Lisbon", "Madrid"}},
+		{41.15, -8.61, 0, []string{}},
+		// across the antimeridian
+		{-16, 180, 500, []string{"Fiji"}},
+		{-16, -179.9, 1500, []string{"Fiji", "Samoa"}},
+		// around the north pole
+		{89, -170, 1500, []string{"Svalbard"}},
+	}
+
+	for _, test := range tests {
+		found, err := nodes.GetNodesWithinRadius(test.latitude, test.longitude, test.km, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, nodeNames(found), test)
+	}
+
+	all, err := nodes.GetNodesWithinRadius(0, 0, maxDistance, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, len(all))
+}
+
+func TestGetNodesWithinRadiusFilter(t *testing.T) {
+	nodes := newTestSpatialNodes()
+	madrid := newTestSpatialNode("Madrid", "40.4168", "-3.7038")
+	madrid.Labels[labels.NodeCountry] = "Spain"
+	nodes.UpdateNode(madrid, madrid)
+
+	found, err := nodes.GetNodesWithinRadius(41.15, -8.61, 500, &NodeFilter{Locations: Locations{Countries: []string{"ES"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Madrid"}, nodeNames(found))
+}
+
+func TestGetNearestNodes(t *testing.T) {
+	nodes := newTestSpatialNodes()
+
+	nearest, err := nodes.GetNearestNodes(41.15, -8.61, 3, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Porto", "Braga", "Lisbon"}, nodeNames(nearest))
+
+	nearest, err = nodes.GetNearestNodes(-33.9, 151.2, 2, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Fiji", "Samoa"}, nodeNames(nearest))
+
+	nearest, err = nodes.GetNearestNodes(0, 0, 100, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, len(nearest))
+
+	nearest, err = nodes.GetNearestNodes(0, 0, 0, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(nearest))
+
+	nearest, err = nodes.GetNearestNodes(41.15, -8.61, 1, &NodeFilter{Resources: Resources{CPU: 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(nearest))
+}
+
+func TestSpatialQueriesInvalidCoordinates(t *testing.T) {
+	nodes := newTestSpatialNodes()
+
+	_, err := nodes.GetNodesWithinRadius(91, 0, 10, nil)
+	assert.True(t, errors.Is(err, locations.ErrInvalidLocation))
+
+	_, err = nodes.GetNearestNodes(0, 181, 1, nil)
+	assert.True(t, errors.Is(err, locations.ErrInvalidLocation))
+}
+
+func TestSpatialIndexConsistency(t *testing.T) {
+	nodes := newTestSpatialNodes()
+	snapshot := nodes.Snapshot()
+
+	// moving Porto to Madrid
+	porto := newTestSpatialNode("Porto", "41.1579", "-8.6291")
+	moved := newTestSpatialNode("Porto", "40.4", "-3.7")
+	nodes.UpdateNode(porto, moved)
+
+	found, _ := nodes.GetNodesWithinRadius(41.15, -8.61, 10, nil)
+	assert.Equal(t, []string{}, nodeNames(found))
+
+	found, _ = nodes.GetNodesWithinRadius(40.4, -3.7, 10, nil)
+	assert.Equal(t, []string{"Porto", "Madrid"}, nodeNames(found))
+
+	// invalid coordinates remove the node from the index
+	invalid := newTestSpatialNode("Braga", "north", "-8.4265")
+	nodes.UpdateNode(invalid, invalid)
+
+	found, _ = nodes.GetNodesWithinRadius(41.5454, -8.4265, 10, nil)
+	assert.Equal(t, []string{}, nodeNames(found))
+	assert.Equal(t, 8, nodes.CountNodes())
+
+	nodes.DeleteNode(moved)
+	found, _ = nodes.GetNodesWithinRadius(40.4, -3.7, 10, nil)
+	assert.Equal(t, []string{"Madrid"}, nodeNames(found))
+
+	// resources updates keep the node indexed
+	madrid := newTestSpatialNode("Madrid", "40.4168", "-3.7038")
+	madrid.CPU = 1000
+	nodes.UpdateNode(madrid, madrid)
+
+	found, _ = nodes.GetNodesWithinRadius(40.4, -3.7, 10, &NodeFilter{Resources: Resources{CPU: 1000}})
+	assert.Equal(t, []string{"Madrid"}, nodeNames(found))
+
+	// snapshots are not affected
+	found, _ = snapshot.GetNodesWithinRadius(41.15, -8.61, 10, nil)
+	assert.Equal(t, []string{"Porto"}, nodeNames(found))
+
+	found, _ = snapshot.GetNodesWithinRadius(41.5454, -8.4265, 10, nil)
+	assert.Equal(t, []string{"Braga"}, nodeNames(found))
+}
+
+func newTestTopology(t *testing.T) *Topology {
+	topology, err := NewTopology(
+		TopologyLevel{Name: "rack", Label: "node.geolocate.io/rack", Parent: "zone"},
+		TopologyLevel{Name: "zone", Label: "node.geolocate.io/zone", Parent: "datacenter"},
+		TopologyLevel{Name: "datacenter", Label: "node.geolocate.io/datacenter", Parent: "country"},
+		TopologyLevel{Name: "country", Label: labels.NodeCountry, Parent: "continent"},
+		TopologyLevel{Name: "continent", Label: labels.NodeContinent},
+	)
+	assert.NoError(t, err)
+	return topology
+}
+
+func newTestTopologyNode(name string, rack string, zone string, datacenter string, country string) *Node {
+	node := newTestNode(name, true, "", country, "")
+	node.Labels["node.geolocate.io/rack"] = rack
+	node.Labels["node.geolocate.io/zone"] = zone
+	node.Labels["node.geolocate.io/datacenter"] = datacenter
+	return node
+}
+
+func newTestTopologyNodes(t *testing.T) INodes {
+	nodes := NewWithTopology(newTestTopology(t))
+	nodes.AddNode(newTestTopologyNode("Node0", "r1", "z1", "dc1", "Portugal"))
+	nodes.AddNode(newTestTopologyNode("Node1", "r2", "z1", "dc1", "Portugal"))
+	nodes.AddNode(newTestTopologyNode("Node2", "r3", "z2", "dc1", "Portugal"))
+	nodes.AddNode(newTestTopologyNode("Node3", "r4", "z3", "dc2", "Spain"))
+	return nodes
+}
+
+func TestNewTopology(t *testing.T) {
+	topology := newTestTopology(t)
+	assert.Equal(t, []string{"rack", "zone", "datacenter", "country", "continent"}, topology.LevelNames())
+
+	level, ok := topology.Level("Zone")
+	assert.True(t, ok)
+	assert.Equal(t, "datacenter", level.Parent)
+
+	_, ok = topology.Level("city")
+	assert.False(t, ok)
+
+	invalid := [][]TopologyLevel{
+		{},
+		{{Name: "", Label: "rack"}},
+		{{Name: "data center", Label: "dc"}},
+		{{Name: "rack", Label: "rack"}, {Name: "Rack", Label: "other"}},
+		{{Name: "rack", Label: "rack"}, {Name: "zone", Label: "rack"}},
+		{{Name: "rack"}},
+		{{Name: "zone", Label: "zone"}, {Name: "rack", Label: "rack", Parent: "zone"}},
+		{{Name: "rack", Label: "rack", Parent: "zone"}},
+		{{Name: "rack", Label: "rack", Parent: "Rack"}},
+	}
+
+	for _, levels := range invalid {
+		_, err := NewTopology(levels...)
+		assert.Error(t, err, fmt.Sprint(levels))
+	}
+}
+
+func TestTopologyIndex(t *testing.T) {
+	nodes := newTestTopologyNodes(t)
+
+	zone := &NodeFilter{Locations: Locations{Levels: map[string][]string{"zone": {"z1"}}}}
+	assert.Equal(t, []string{"Node0", "Node1"}, sortedNames(nodes.GetNodes(zone)))
+
+	country := &NodeFilter{Locations: Locations{Countries: []string{"ES"}}}
+	assert.Equal(t, []string{"Node3"}, sortedNames(nodes.GetNodes(country)))
+
+	nodes.UpdateNode(newTestTopologyNode("Node1", "r2", "z1", "dc1", "Portugal"),
+		newTestTopologyNode("Node1", "r5", "z2", "dc1", "Portugal"))
+	assert.Equal(t, []string{"Node0"}, sortedNames(nodes.GetNodes(zone)))
+
+	nodes.DeleteNode(newTestTopologyNode("Node0", "r1", "z1", "dc1", "Portugal"))
+	assert.Equal(t, 0, len(nodes.GetNodes(zone)))
+}
+
+func TestTopologyGetNodesMatching(t *testing.T) {
+	nodes := newTestTopologyNodes(t)
+	levels := nodes.GetTopology().ExpressionLevels()
+
+	parsed, err := locations.ParseWithLevels("datacenter = DC1 and zone != z2", levels)
+	assert.NoError(t, err)
+
+	matching, err := nodes.GetNodesMatching(parsed.Condition)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Node0", "Node1"}, nodeNames(matching))
+
+	parsed, _ = locations.Parse("city = Braga")
+	_, err = nodes.GetNodesMatching(parsed.Condition)
+	assert.True(t, errors.Is(err, locations.ErrInvalidLocation))
+}
+
+func TestTopologyGetParentLocations(t *testing.T) {
+	nodes := newTestTopologyNodes(t)
+
+	level, codes := nodes.GetParentLocations("rack", []string{"r1", "r3"})
+	assert.Equal(t, "zone", level)
+	assert.Equal(t, []string{"z1", "z2"}, codes)
+
+	level, codes = nodes.GetParentLocations("datacenter", []string{"dc2"})
+	assert.Equal(t, "country", level)
+	assert.Equal(t, []string{"ES"}, codes)
+
+	// built-in levels parents are known without nodes
+	level, codes = nodes.GetParentLocations("country", []string{"FR"})
+	assert.Equal(t, "continent", level)
+	assert.Equal(t, []string{"EU"}, codes)
+
+	level, _ = nodes.GetParentLocations("continent", []string{"EU"})
+	assert.Equal(t, "", level)
+
+	code, err := nodes.ResolveLocation("country", "Portugal")
+	assert.NoError(t, err)
+	assert.Equal(t, "PT", code)
+
+	_, err = nodes.ResolveLocation("city", "Braga")
+	assert.True(t, errors.Is(err, locations.ErrInvalidLocation))
+}
+
+func TestDefaultTopologyGetParentLocations(t *testing.T) {
+	nodes := newTestConditionNodes()
+
+	level, codes := nodes.GetParentLocations("city", []string{"PT-03", "ES-M"})
+	assert.Equal(t, "country", level)
+	assert.Equal(t, []string{"ES", "PT"}, codes)
+}
+
+func sortedNames(list []*Node) []string {
+	names := nodeNames(list)
+	sort.Strings(names)
+	return names
+}
+
+func newTestCatalogNodes() INodes {
+	catalog := locations.NewCatalog()
+	catalog.MustRegister(locations.Site{Name: "edge-site-42", Country: "Portugal"})
+	catalog.MustRegister(locations.Site{Name: "oil-rig-7", Continent: "Europe"})
+
+	nodes := NewWithCatalog(DefaultTopology(), catalog)
+	nodes.AddNode(newTestNode("Node0", true, "edge-site-42", "Portugal", "Europe"))
+	nodes.AddNode(newTestNode("Node1", true, "", "oil-rig-7", "Europe"))
+	nodes.AddNode(newTestNode("Node2", true, "Braga", "Portugal", "Europe"))
+	return nodes
+}
+
+func TestCatalogSitesIndex(t *testing.T) {
+	nodes := newTestCatalogNodes()
+
+	cities := &NodeFilter{Locations: Locations{Cities: []string{"PT-edge-site-42"}}}
+	assert.Equal(t, []string{"Node0"}, sortedNames(nodes.GetNodes(cities)))
+
+	countries := &NodeFilter{Locations: Locations{Countries: []string{"oil-rig-7"}}}
+	assert.Equal(t, []string{"Node1"}, sortedNames(nodes.GetNodes(countries)))
+
+	parsed, err := locations.Parse("city = Edge-Site-42 or country = oil-rig-7")
+	assert.NoError(t, err)
+
+	matching, err := nodes.GetNodesMatching(parsed.Condition)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Node0", "Node1"}, nodeNames(matching))
+
+	// sites are only known at their own level
+	parsed, _ = locations.Parse("country = edge-site-42")
+	_, err = nodes.GetNodesMatching(parsed.Condition)
+	assert.True(t, errors.Is(err, locations.ErrUnknownLocation))
+
+	_, err = New().ResolveLocation(LevelCity, "edge-site-42")
+	assert.Error(t, err)
+}
+
+func TestCatalogSitesParentLocations(t *testing.T) {
+	nodes := newTestCatalogNodes()
+
+	level, codes := nodes.GetParentLocations(LevelCity, []string{"PT-edge-site-42"})
+	assert.Equal(t, LevelCountry, level)
+	assert.Equal(t, []string{"PT"}, codes)
+
+	level, codes = nodes.GetParentLocations(LevelCountry, []string{"oil-rig-7"})
+	assert.Equal(t, LevelContinent, level)
+	assert.Equal(t, []string{"EU"}, codes)
+}
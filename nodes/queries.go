@@ -1,67 +1,158 @@
 package nodes
 
 import (
-	"k8s.io/klog/v2"
+	"fmt"
+	"github.com/geolocate-orchestration/scheduler/locations"
+	"strings"
 )
 
 // CountNodes returns the number of cluster nodes
 func (n *Nodes) CountNodes() int {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
 	return len(n.Nodes)
 }
 
 // GetAllNodes list all cluster nodes
 func (n *Nodes) GetAllNodes() []*Node {
-	return n.Nodes
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	return copyNodeList(n.Nodes)
 }
 
 // GetNodes list all cluster nodes matching filter
 func (n *Nodes) GetNodes(filter *NodeFilter) []*Node {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
 	return n.filterNodes(filter)
 }
 
+// GetNodesWithinRadius lists the nodes matching filter within km kilometres of the given coordinates,
+// nearest first
+// Only nodes with latitude and longitude labels are considered
+func (n *Nodes) GetNodesWithinRadius(latitude float64, longitude float64, km float64, filter *NodeFilter) ([]*Node, error) {
+	origin, err := locations.NewCoordinates(latitude, longitude)
+	if err != nil {
+		return nil, err
+	}
+
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	return n.nodesWithinRadius(origin, km, filter), nil
+}
+
+// GetNearestNodes lists the k nodes matching filter nearest to the given coordinates, nearest first
+// Only nodes with latitude and longitude labels are considered
+func (n *Nodes) GetNearestNodes(latitude float64, longitude float64, k int, filter *NodeFilter) ([]*Node, error) {
+	origin, err := locations.NewCoordinates(latitude, longitude)
+	if err != nil {
+		return nil, err
+	}
+
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	if k <= 0 {
+		return make([]*Node, 0), nil
+	}
+
+	for radius := nearestSearchRadius; ; radius *= 2 {
+		nearest := n.nodesWithinRadius(origin, radius, filter)
+
+		if len(nearest) >= k {
+			return nearest[:k], nil
+		}
+
+		if radius >= maxDistance {
+			return nearest, nil
+		}
+	}
+}
+
+// GetTopology returns the levels nodes are indexed by
+func (n *Nodes) GetTopology() *Topology {
+	return n.topology()
+}
+
+// GetCatalog returns the operator defined sites locations are resolved against along with gountries
+func (n *Nodes) GetCatalog() *locations.Catalog {
+	return n.catalog()
+}
+
+// ResolveLocation returns the index code of a location name at a topology level
+func (n *Nodes) ResolveLocation(level string, name string) (string, error) {
+	if _, ok := n.topology().Level(level); !ok {
+		return "", fmt.Errorf("%w: unknown topology level %q", locations.ErrInvalidLocation, level)
+	}
+
+	return n.resolve(strings.ToLower(level), name)
+}
+
+// GetParentLocations returns the parent level of a topology level and the codes of the locations
+// containing the given location codes, an empty level if the level has no parent
+// Parents are found through gountries for the built-in levels and through the labels of the indexed nodes
+func (n *Nodes) GetParentLocations(level string, codes []string) (string, []string) {
+	n.mutex.RLock()
+	defer n.mutex.RUnlock()
+
+	return n.parentLocations(level, codes)
+}
+
+// Snapshot returns an immutable copy of the cached nodes and location indexes
+// Expired assumed workloads are released before the copy is taken
+func (n *Nodes) Snapshot() NodeLister {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.expireWorkloads()
+	return n.copyNodes()
+}
+
 // AddNode add a new cluster node
 func (n *Nodes) AddNode(node *Node) {
-	if !nodeHasAnyLabel(node) {
-		// Don't add new node if it doesn't have the node.geolocate.io role
-		return
-	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
 
-	n.Nodes = append(n.Nodes, node)
-	n.addToCities(node)
-	n.addToCountries(node)
-	n.addToContinents(node)
-	klog.Infof("node added to cache: %s\n", node.Name)
+	n.addNode(node)
 }
 
 // UpdateNode updates a cluster node
 func (n *Nodes) UpdateNode(oldNode *Node, newNode *Node) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
 	savedNode, err := n.findNodeByName(oldNode.Name)
 	if err != nil {
 		// If node wasn't labeled but now it is, create it in cache
-		n.AddNode(newNode)
+		n.addNode(newNode)
 		return
 	}
 
-	oldHasNodeLabel := nodeHasAnyLabel(savedNode)
-	newHasNodeLabel := nodeHasAnyLabel(newNode)
+	oldHasNodeLabel := n.nodeHasAnyLabel(savedNode)
+	newHasNodeLabel := n.nodeHasAnyLabel(newNode)
 
 	if !oldHasNodeLabel && newHasNodeLabel {
 		// If node wasn't labeled but now it is, create it in cache
-		n.AddNode(newNode)
+		n.addNode(newNode)
 	} else if oldHasNodeLabel && newHasNodeLabel {
 		// If the node is labeled and has significant update it in cache
 		n.updateNodeData(savedNode, newNode)
 	} else if oldHasNodeLabel && !newHasNodeLabel {
-		// If node was labeled but now it isn't, remove it from cache
-		n.DeleteNode(savedNode)
+		// If node was labeled but now it isn't, remove it from cache along with the charges of workloads on it
+		n.deleteNode(savedNode)
+		n.forgetNodeWorkloads(savedNode.Name)
 	}
 }
 
-// DeleteNode deletes a cluster node
+// DeleteNode deletes a cluster node, releasing the charges of workloads on it
 func (n *Nodes) DeleteNode(node *Node) {
-	n.removeNodeFromNodes(node)
-	n.removeNodeFromCities(node)
-	n.removeNodeFromCountries(node)
-	n.removeNodeFromContinents(node)
-	klog.Infof("node deleted from cache: %s\n", node.Name)
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.deleteNode(node)
+	n.forgetNodeWorkloads(node.Name)
 }
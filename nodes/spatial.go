@@ -0,0 +1,185 @@
+package nodes
+
+import (
+	"fmt"
+	"github.com/geolocate-orchestration/scheduler/labels"
+	"github.com/geolocate-orchestration/scheduler/locations"
+	"k8s.io/klog/v2"
+	"math"
+	"sort"
+)
+
+// cellSize is the size in degrees of the latitude and longitude cells of the spatial index
+const cellSize = 1.0
+
+// earthRadius is the Earth radius in kilometres used by locations.Coordinates.DistanceTo
+const earthRadius = 6372.8
+
+// maxDistance is half of the Earth circumference, no two points are further apart
+const maxDistance = math.Pi * earthRadius
+
+// nearestSearchRadius is the radius of the first nearest nodes search in kilometres,
+// it is doubled until enough nodes are found
+const nearestSearchRadius = 100.0
+
+// nodeDistance is a node and its distance to a searched point
+type nodeDistance struct {
+	node     *Node
+	distance float64
+}
+
+func (n *Nodes) addToCells(node *Node) {
+	coordinates, ok := nodeCoordinates(node)
+	if !ok && hasCoordinatesLabels(node) {
+		klog.Errorf("node %s has invalid coordinates labels\n", node.Name)
+	}
+
+	if ok {
+		if n.Cells == nil {
+			n.Cells = make(map[string][]*Node)
+		}
+
+		key := cellKey(cellOf(coordinates))
+		n.Cells[key] = append(n.Cells[key], node)
+	}
+}
+
+func (n *Nodes) removeNodeFromCells(node *Node) {
+	if coordinates, ok := nodeCoordinates(node); ok {
+		key := cellKey(cellOf(coordinates))
+
+		for i, v := range n.Cells[key] {
+			if v.Name == node.Name {
+				n.Cells[key] = append(n.Cells[key][:i], n.Cells[key][i+1:]...)
+				break
+			}
+		}
+
+		if len(n.Cells[key]) == 0 {
+			delete(n.Cells, key)
+		}
+	}
+}
+
+// nodesWithinRadius lists the nodes matching filter within km of origin, nearest first
+func (n *Nodes) nodesWithinRadius(origin locations.Coordinates, km float64, filter *NodeFilter) []*Node {
+	found := make([]nodeDistance, 0)
+	allowed := n.filterLocations(filter)
+
+	for _, list := range n.cellsWithinRadius(origin, km) {
+		for _, node := range list {
+			if allowed != nil && !allowed[node.Name] || !nodeMatchesFilters(node, filter) {
+				continue
+			}
+
+			coordinates, _ := nodeCoordinates(node)
+			if distance := origin.DistanceTo(coordinates); distance <= km {
+				found = append(found, nodeDistance{node: node, distance: distance})
+			}
+		}
+	}
+
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].distance != found[j].distance {
+			return found[i].distance < found[j].distance
+		}
+
+		return found[i].node.Name < found[j].node.Name
+	})
+
+	nearest := make([]*Node, 0, len(found))
+	for _, item := range found {
+		nearest = append(nearest, item.node)
+	}
+
+	return nearest
+}
+
+// cellsWithinRadius lists the index cells that may hold nodes within km of origin
+func (n *Nodes) cellsWithinRadius(origin locations.Coordinates, km float64) [][]*Node {
+	angle := km / earthRadius
+	minLatitude := origin.Latitude - angle*180/math.Pi
+	maxLatitude := origin.Latitude + angle*180/math.Pi
+	minLongitude, maxLongitude := -180.0, 180.0
+
+	// the longitude range only narrows when the radius doesn't reach a pole
+	if minLatitude > -90 && maxLatitude < 90 {
+		delta := math.Asin(math.Sin(angle)/math.Cos(origin.Latitude*math.Pi/180)) * 180 / math.Pi
+		minLongitude, maxLongitude = origin.Longitude-delta, origin.Longitude+delta
+	}
+
+	minLatCell, maxLatCell := cellIndex(math.Max(minLatitude, -90)), cellIndex(math.Min(maxLatitude, 90))
+	minLonCell, maxLonCell := cellIndex(minLongitude), cellIndex(maxLongitude)
+
+	lonCells := int(360 / cellSize)
+	if maxLonCell-minLonCell+1 < lonCells {
+		lonCells = maxLonCell - minLonCell + 1
+	}
+
+	cells := make([][]*Node, 0)
+
+	// scanning the index is cheaper than looking up every cell of large areas
+	if (maxLatCell-minLatCell+1)*lonCells > len(n.Cells) {
+		for _, list := range n.Cells {
+			cells = append(cells, list)
+		}
+
+		return cells
+	}
+
+	for lat := minLatCell; lat <= maxLatCell; lat++ {
+		for lon := minLonCell; lon < minLonCell+lonCells; lon++ {
+			if list, ok := n.Cells[cellKey(lat, lon)]; ok {
+				cells = append(cells, list)
+			}
+		}
+	}
+
+	return cells
+}
+
+// filterLocations returns the names of the nodes in the filter locations, nil if the filter has no locations
+func (n *Nodes) filterLocations(filter *NodeFilter) map[string]bool {
+	if filter == nil || filter.Locations.isEmpty() {
+		return nil
+	}
+
+	allowed := make(map[string]bool)
+	for _, node := range n.buildFromLocations(filter.Locations) {
+		allowed[node.Name] = true
+	}
+
+	return allowed
+}
+
+// nodeCoordinates returns the node latitude and longitude labels coordinates
+func nodeCoordinates(node *Node) (locations.Coordinates, bool) {
+	if !hasCoordinatesLabels(node) {
+		return locations.Coordinates{}, false
+	}
+
+	latitude, longitude := node.Labels[labels.NodeLatitude], node.Labels[labels.NodeLongitude]
+
+	coordinates, err := locations.ParseLatLong(latitude, longitude)
+	return coordinates, err == nil
+}
+
+func hasCoordinatesLabels(node *Node) bool {
+	return node.Labels[labels.NodeLatitude] != "" || node.Labels[labels.NodeLongitude] != ""
+}
+
+func cellOf(coordinates locations.Coordinates) (int, int) {
+	return cellIndex(coordinates.Latitude), cellIndex(coordinates.Longitude)
+}
+
+func cellIndex(degrees float64) int {
+	return int(math.Floor(degrees / cellSize))
+}
+
+// cellKey formats the index key of a cell, wrapping longitudes around the antimeridian
+func cellKey(lat int, lon int) string {
+	lonCells := int(360 / cellSize)
+	lon = ((lon+lonCells/2)%lonCells+lonCells)%lonCells - lonCells/2
+
+	return fmt.Sprintf("%d:%d", lat, lon)
+}
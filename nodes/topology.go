@@ -0,0 +1,199 @@
+package nodes
+
+import (
+	"fmt"
+	"github.com/geolocate-orchestration/scheduler/labels"
+	"github.com/geolocate-orchestration/scheduler/locations"
+	"sort"
+	"strings"
+)
+
+// Built-in topology levels, their locations are resolved through gountries
+const (
+	// LevelCity indexes nodes by their city subdivision code, such as "PT-03"
+	LevelCity = string(locations.LevelCity)
+
+	// LevelCountry indexes nodes by their country alpha2 code, such as "PT"
+	LevelCountry = string(locations.LevelCountry)
+
+	// LevelContinent indexes nodes by their continent code, such as "EU"
+	LevelContinent = string(locations.LevelContinent)
+)
+
+// defaultTopology is used by caches built without a topology
+var defaultTopology = DefaultTopology()
+
+// TopologyLevel is a level of the location hierarchy nodes are indexed by
+type TopologyLevel struct {
+	// Name identifies the level in workload location expressions, such as "zone"
+	Name string
+
+	// Label is the node label holding the node location at this level
+	Label string
+
+	// Parent is the name of the level containing this level locations, empty for top levels
+	// Fallbacks walk from a level to its parent
+	Parent string
+}
+
+// Topology is the ordered list of levels nodes are indexed by, from the most specific to the broadest
+// Locations of built-in levels are resolved through gountries, other levels use the label values as is
+type Topology struct {
+	Levels []TopologyLevel
+}
+
+// DefaultTopology returns the city, country and continent topology
+func DefaultTopology() *Topology {
+	return &Topology{
+		Levels: []TopologyLevel{
+			{Name: LevelCity, Label: labels.NodeCity, Parent: LevelCountry},
+			{Name: LevelCountry, Label: labels.NodeCountry, Parent: LevelContinent},
+			{Name: LevelContinent, Label: labels.NodeContinent},
+		},
+	}
+}
+
+// NewTopology validates the given levels, ordered from the most specific to the broadest
+// Level names and labels must be unique and parents must be other levels listed after their children
+func NewTopology(levels ...TopologyLevel) (*Topology, error) {
+	if len(levels) == 0 {
+		return nil, fmt.Errorf("topology must have at least one level")
+	}
+
+	seen := make(map[string]bool, len(levels))
+	seenLabels := make(map[string]bool, len(levels))
+
+	for _, level := range levels {
+		switch {
+		case level.Name == "" || strings.ContainsAny(level.Name, " \t=!(),\""):
+			return nil, fmt.Errorf("topology level name %q must be a non empty word", level.Name)
+		case seen[strings.ToLower(level.Name)]:
+			return nil, fmt.Errorf("topology level %q is duplicated", level.Name)
+		case level.Label == "":
+			return nil, fmt.Errorf("topology level %q must have a label", level.Name)
+		case seenLabels[level.Label]:
+			return nil, fmt.Errorf("topology level %q label %q is already used", level.Name, level.Label)
+		case strings.EqualFold(level.Parent, level.Name):
+			return nil, fmt.Errorf("topology level %q can't be its own parent", level.Name)
+		case level.Parent != "" && seen[strings.ToLower(level.Parent)]:
+			return nil, fmt.Errorf("topology level %q parent %q must be listed after it", level.Name, level.Parent)
+		}
+
+		seen[strings.ToLower(level.Name)] = true
+		seenLabels[level.Label] = true
+	}
+
+	for _, level := range levels {
+		if level.Parent != "" && !seen[strings.ToLower(level.Parent)] {
+			return nil, fmt.Errorf("topology level %q parent %q is not a level", level.Name, level.Parent)
+		}
+	}
+
+	normalized := make([]TopologyLevel, 0, len(levels))
+	for _, level := range levels {
+		level.Name = strings.ToLower(level.Name)
+		level.Parent = strings.ToLower(level.Parent)
+		normalized = append(normalized, level)
+	}
+
+	return &Topology{Levels: normalized}, nil
+}
+
+// Level returns the level with the given name
+func (t *Topology) Level(name string) (TopologyLevel, bool) {
+	for _, level := range t.Levels {
+		if level.Name == strings.ToLower(name) {
+			return level, true
+		}
+	}
+
+	return TopologyLevel{}, false
+}
+
+// LevelNames lists the level names in order
+func (t *Topology) LevelNames() []string {
+	names := make([]string, 0, len(t.Levels))
+
+	for _, level := range t.Levels {
+		names = append(names, level.Name)
+	}
+
+	return names
+}
+
+// hasLabel reports whether the label holds the location of a topology level
+func (t *Topology) hasLabel(label string) bool {
+	for _, level := range t.Levels {
+		if level.Label == label {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (n *Nodes) parentLocations(name string, codes []string) (string, []string) {
+	topology := n.topology()
+
+	level, ok := topology.Level(name)
+	if !ok || level.Parent == "" {
+		return "", nil
+	}
+
+	parent, _ := topology.Level(level.Parent)
+	parents := make(map[string]bool)
+
+	for _, code := range codes {
+		if geographic, ok := n.geographicParent(level, code); ok {
+			parents[geographic] = true
+		}
+
+		for _, node := range n.index(level.Name, false)[code] {
+			if value := node.Labels[parent.Label]; value != "" {
+				if parentCode, err := n.resolve(parent.Name, value); err == nil {
+					parents[parentCode] = true
+				}
+			}
+		}
+	}
+
+	list := make([]string, 0, len(parents))
+	for code := range parents {
+		list = append(list, code)
+	}
+
+	sort.Strings(list)
+	return parent.Name, list
+}
+
+// geographicParent returns the parent of built-in levels locations known to gountries
+func (n *Nodes) geographicParent(level TopologyLevel, code string) (string, bool) {
+	switch {
+	case level.Name == LevelCity && level.Parent == LevelCountry:
+		// city codes are prefixed by their country code, such as "PT-03"
+		return strings.SplitN(code, "-", 2)[0], true
+	case level.Name == LevelCountry && level.Parent == LevelContinent:
+		country, err := n.Query.FindCountryByAlpha(code)
+		if err != nil {
+			// sites located at the country level declare their continent
+			site, ok := n.catalog().FindByCode(code)
+			return site.Continent, ok && site.Continent != ""
+		}
+
+		continent, err := n.continentCode(country.Continent)
+		return continent, err == nil
+	}
+
+	return "", false
+}
+
+// ExpressionLevels lists the level names boolean location expressions can match
+func (t *Topology) ExpressionLevels() []locations.Level {
+	levels := make([]locations.Level, 0, len(t.Levels))
+
+	for _, level := range t.Levels {
+		levels = append(levels, locations.Level(level.Name))
+	}
+
+	return levels
+}
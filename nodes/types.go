@@ -2,43 +2,118 @@ package nodes
 
 import (
 	"github.com/geolocate-orchestration/gountries"
+	"github.com/geolocate-orchestration/scheduler/locations"
+	"sync"
+	"time"
 )
 
-// INodes exports all node controller public methods
-type INodes interface {
+// NodeLister exports read-only node queries
+type NodeLister interface {
 	CountNodes() int
 	GetAllNodes() []*Node
 	GetNodes(filter *NodeFilter) []*Node
+	GetNodesMatching(condition locations.Condition) ([]*Node, error)
+	GetNodesWithinRadius(latitude float64, longitude float64, km float64, filter *NodeFilter) ([]*Node, error)
+	GetNearestNodes(latitude float64, longitude float64, k int, filter *NodeFilter) ([]*Node, error)
+
+	// GetTopology returns the levels nodes are indexed by
+	GetTopology() *Topology
+
+	// GetCatalog returns the operator defined sites locations are resolved against along with gountries
+	GetCatalog() *locations.Catalog
+
+	// ResolveLocation returns the index code of a location name at a topology level
+	ResolveLocation(level string, name string) (string, error)
+
+	// GetParentLocations returns the parent level of a topology level and the codes of the locations
+	// containing the given location codes, an empty level if the level has no parent
+	GetParentLocations(level string, codes []string) (string, []string)
+}
+
+// INodes exports all node controller public methods
+// It is safe for concurrent use
+type INodes interface {
+	NodeLister
+
+	// Snapshot returns an immutable view of the current nodes that is not affected by later changes
+	Snapshot() NodeLister
 
 	AddNode(node *Node)
 	UpdateNode(oldNode *Node, newNode *Node)
 	DeleteNode(node *Node)
+
+	SetAssumeTTL(ttl time.Duration)
+	AssumeWorkload(workload string, node string, resources Resources) error
+	BindWorkload(workload string, node string, resources Resources) error
+	ForgetWorkload(workload string)
+
+	// GetNodeWorkloads returns the workloads assumed or bound to the node, sorted by name
+	GetNodeWorkloads(node string) []NodeWorkload
 }
 
-// New create a new Nodes struct
+// New create a new Nodes struct indexed by the default city, country and continent topology
 func New() INodes {
+	return NewWithTopology(DefaultTopology())
+}
+
+// NewWithTopology create a new Nodes struct indexed by the given topology levels
+// Locations are resolved against locations.DefaultCatalog sites along with gountries
+func NewWithTopology(topology *Topology) INodes {
+	return NewWithCatalog(topology, locations.DefaultCatalog)
+}
+
+// NewWithCatalog create a new Nodes struct indexed by the given topology levels
+// resolving locations against the given catalog sites along with gountries
+func NewWithCatalog(topology *Topology, catalog *locations.Catalog) INodes {
 	nodes := Nodes{
 		Query:          gountries.New(),
 		ContinentsList: gountries.NewContinents(),
+		Topology:       topology,
+		Catalog:        catalog,
 
 		Nodes:      make([]*Node, 0),
 		Cities:     make(map[string][]*Node),
 		Countries:  make(map[string][]*Node),
 		Continents: make(map[string][]*Node),
+		Cells:      make(map[string][]*Node),
+		Levels:     make(map[string]map[string][]*Node),
+
+		workloads: make(map[string]*trackedWorkload),
+		assumeTTL: DefaultAssumeTTL,
 	}
 
 	return &nodes
 }
 
 // Nodes controls in-cache nodes
+// Cached nodes are never modified in place, updates replace them with a new copy,
+// so nodes returned by any query can be read without holding the cache lock
 type Nodes struct {
+	mutex sync.RWMutex
+
 	Query          *gountries.Query
 	ContinentsList gountries.Continents
 
+	// Topology lists the levels nodes are indexed by, the default topology is used when nil
+	Topology *Topology
+
+	// Catalog lists the operator defined sites, locations.DefaultCatalog is used when nil
+	Catalog *locations.Catalog
+
 	Nodes      []*Node
 	Cities     map[string][]*Node
 	Countries  map[string][]*Node
 	Continents map[string][]*Node
+
+	// Cells indexes nodes with coordinates labels by latitude and longitude cells
+	Cells map[string][]*Node
+
+	// Levels indexes nodes by location code for topology levels other than city, country and continent
+	Levels map[string]map[string][]*Node
+
+	workloads map[string]*trackedWorkload
+	assumeTTL time.Duration
+	now       func() time.Time
 }
 
 // Node represents a cluster Node
@@ -49,11 +124,30 @@ type Node struct {
 	// Labels represents all of Node labels
 	Labels map[string]string
 
-	// CPU represents Node available CPU resources in MilliValue
+	// CPU represents Node allocatable CPU resources in MilliValue
 	CPU int64
 
-	// Memory represents Node available Memory resources in MilliValue
+	// Memory represents Node allocatable Memory resources in MilliValue
 	Memory int64
+
+	// Requested represents resources charged by workloads assumed or bound to the Node,
+	// it is maintained by the nodes cache and ignored when adding or updating a Node
+	Requested Resources
+}
+
+// NodeWorkload is a workload whose resources are charged against a cached node
+type NodeWorkload struct {
+	// Name is the workload unique identifying name
+	Name string
+
+	// Node is the name of the node the workload is assumed or bound to
+	Node string
+
+	// Resources are the resources charged against the node
+	Resources Resources
+
+	// Bound is false while the workload is only assumed on the node
+	Bound bool
 }
 
 // NodeFilter states the params which nodes must match to be returned
@@ -74,4 +168,7 @@ type Locations struct {
 	Cities     []string
 	Countries  []string
 	Continents []string
+
+	// Levels lists location codes of topology levels other than city, country and continent
+	Levels map[string][]string
 }
@@ -1,24 +1,35 @@
 package nodes
 
 import (
-	"errors"
 	"github.com/geolocate-orchestration/scheduler/labels"
 	"math/rand"
 	"reflect"
 )
 
-func nodeHasSignificantChanges(oldNode *Node, newNode *Node) bool {
-	return oldNode.Name != newNode.Name ||
-		oldNode.Labels[labels.NodeCity] != newNode.Labels[labels.NodeCity] ||
-		oldNode.Labels[labels.NodeCountry] != newNode.Labels[labels.NodeCountry] ||
-		oldNode.Labels[labels.NodeContinent] != newNode.Labels[labels.NodeContinent]
+func (n *Nodes) nodeHasSignificantChanges(oldNode *Node, newNode *Node) bool {
+	if oldNode.Name != newNode.Name ||
+		oldNode.Labels[labels.NodeLatitude] != newNode.Labels[labels.NodeLatitude] ||
+		oldNode.Labels[labels.NodeLongitude] != newNode.Labels[labels.NodeLongitude] {
+		return true
+	}
+
+	for _, level := range n.topology().Levels {
+		if oldNode.Labels[level.Label] != newNode.Labels[level.Label] {
+			return true
+		}
+	}
+
+	return false
 }
 
-func nodeHasAnyLabel(node *Node) bool {
-	nodeLabels := [4]string{labels.Node, labels.NodeCity, labels.NodeCountry, labels.NodeContinent}
+func (n *Nodes) nodeHasAnyLabel(node *Node) bool {
+	for label := range node.Labels {
+		switch label {
+		case labels.Node, labels.NodeLatitude, labels.NodeLongitude:
+			return true
+		}
 
-	for _, label := range nodeLabels {
-		if _, ok := node.Labels[label]; ok {
+		if n.topology().hasLabel(label) {
 			return true
 		}
 	}
@@ -26,10 +37,55 @@ func nodeHasAnyLabel(node *Node) bool {
 	return false
 }
 
+func cloneNode(node *Node) *Node {
+	clone := *node
+	clone.Labels = make(map[string]string, len(node.Labels))
+
+	for key, value := range node.Labels {
+		clone.Labels[key] = value
+	}
+
+	return &clone
+}
+
+func copyNodeList(list []*Node) []*Node {
+	copied := make([]*Node, len(list))
+	copy(copied, list)
+	return copied
+}
+
+func copyNodeIndex(index map[string][]*Node) map[string][]*Node {
+	copied := make(map[string][]*Node, len(index))
+
+	for key, list := range index {
+		copied[key] = copyNodeList(list)
+	}
+
+	return copied
+}
+
+func copyLevelIndexes(indexes map[string]map[string][]*Node) map[string]map[string][]*Node {
+	copied := make(map[string]map[string][]*Node, len(indexes))
+
+	for level, index := range indexes {
+		copied[level] = copyNodeIndex(index)
+	}
+
+	return copied
+}
+
+func replaceInList(list []*Node, node *Node) {
+	for i, v := range list {
+		if v.Name == node.Name {
+			list[i] = node
+		}
+	}
+}
+
 // GetRandomFromList returns a random node from the list
 func GetRandomFromList(options []*Node) (*Node, error) {
 	if len(options) == 0 {
-		return nil, errors.New("no nodes available")
+		return nil, ErrNoNodes
 	}
 
 	return options[rand.Intn(len(options))], nil
@@ -38,7 +94,7 @@ func GetRandomFromList(options []*Node) (*Node, error) {
 // GetRandomFromMap returns a random node from the map
 func GetRandomFromMap(options map[string][]*Node) (*Node, error) {
 	if len(options) == 0 {
-		return nil, errors.New("no nodes available")
+		return nil, ErrNoNodes
 	}
 
 	keys := reflect.ValueOf(options).MapKeys()
@@ -0,0 +1,203 @@
+package nodes
+
+import (
+	"errors"
+	"fmt"
+	"k8s.io/klog/v2"
+	"sort"
+	"time"
+)
+
+// DefaultAssumeTTL is how long an assumed workload charge is kept before being released if it is never bound
+const DefaultAssumeTTL = 30 * time.Second
+
+// trackedWorkload is a workload whose resources are charged against a cached node
+type trackedWorkload struct {
+	node      string
+	resources Resources
+	bound     bool
+	deadline  time.Time
+}
+
+// SetAssumeTTL changes how long assumed workloads are charged before expiring, zero disables expiration
+func (n *Nodes) SetAssumeTTL(ttl time.Duration) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.assumeTTL = ttl
+}
+
+// AssumeWorkload charges the workload resources against the node until it is bound, forgotten or expires
+// It returns error if the node is not cached, doesn't have enough resources or the workload is already tracked
+func (n *Nodes) AssumeWorkload(workload string, node string, resources Resources) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.expireWorkloads()
+
+	if workload == "" {
+		return errors.New("workload name must not be empty")
+	}
+
+	if _, ok := n.workloads[workload]; ok {
+		return fmt.Errorf("workload %s is already assumed or bound", workload)
+	}
+
+	savedNode, err := n.findNodeByName(node)
+	if err != nil {
+		return err
+	}
+
+	if !matchesResources(savedNode, resources) {
+		return fmt.Errorf("%w: node %s cannot fit workload %s", ErrInsufficientResources, node, workload)
+	}
+
+	tracked := &trackedWorkload{node: node, resources: resources}
+	if n.assumeTTL > 0 {
+		tracked.deadline = n.currentTime().Add(n.assumeTTL)
+	}
+
+	n.trackWorkload(workload, tracked)
+	klog.Infof("workload %s assumed on node %s\n", workload, node)
+	return nil
+}
+
+// BindWorkload confirms the workload is running on the node so its charge no longer expires
+// Workloads that were not assumed, or were assumed on another node, are charged against the given node
+func (n *Nodes) BindWorkload(workload string, node string, resources Resources) error {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.expireWorkloads()
+
+	if workload == "" {
+		return errors.New("workload name must not be empty")
+	}
+
+	if tracked, ok := n.workloads[workload]; ok && tracked.node == node {
+		tracked.bound = true
+		tracked.deadline = time.Time{}
+		klog.Infof("workload %s bound to node %s\n", workload, node)
+		return nil
+	}
+
+	if _, err := n.findNodeByName(node); err != nil {
+		return err
+	}
+
+	n.untrackWorkload(workload)
+	n.trackWorkload(workload, &trackedWorkload{node: node, resources: resources, bound: true})
+	klog.Infof("workload %s bound to node %s\n", workload, node)
+	return nil
+}
+
+// ForgetWorkload releases the workload charge, when the workload is deleted or its binding failed
+func (n *Nodes) ForgetWorkload(workload string) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if n.untrackWorkload(workload) {
+		klog.Infof("workload %s forgotten\n", workload)
+	}
+}
+
+// GetNodeWorkloads returns the workloads assumed or bound to the node, sorted by name
+func (n *Nodes) GetNodeWorkloads(node string) []NodeWorkload {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.expireWorkloads()
+
+	workloads := make([]NodeWorkload, 0)
+	for workload, tracked := range n.workloads {
+		if tracked.node == node {
+			workloads = append(workloads, NodeWorkload{
+				Name:      workload,
+				Node:      node,
+				Resources: tracked.resources,
+				Bound:     tracked.bound,
+			})
+		}
+	}
+
+	sort.Slice(workloads, func(i, j int) bool {
+		return workloads[i].Name < workloads[j].Name
+	})
+
+	return workloads
+}
+
+// Unexported
+
+func (n *Nodes) currentTime() time.Time {
+	if n.now != nil {
+		return n.now()
+	}
+
+	return time.Now()
+}
+
+func (n *Nodes) trackWorkload(workload string, tracked *trackedWorkload) {
+	if n.workloads == nil {
+		n.workloads = make(map[string]*trackedWorkload)
+	}
+
+	n.workloads[workload] = tracked
+	n.chargeNode(tracked.node, tracked.resources, 1)
+}
+
+func (n *Nodes) untrackWorkload(workload string) bool {
+	tracked, ok := n.workloads[workload]
+	if !ok {
+		return false
+	}
+
+	delete(n.workloads, workload)
+	n.chargeNode(tracked.node, tracked.resources, -1)
+	return true
+}
+
+func (n *Nodes) expireWorkloads() {
+	now := n.currentTime()
+
+	for workload, tracked := range n.workloads {
+		if !tracked.bound && !tracked.deadline.IsZero() && now.After(tracked.deadline) {
+			n.untrackWorkload(workload)
+			klog.Infof("assumed workload %s expired on node %s\n", workload, tracked.node)
+		}
+	}
+}
+
+func (n *Nodes) forgetNodeWorkloads(node string) {
+	for workload, tracked := range n.workloads {
+		if tracked.node == node {
+			delete(n.workloads, workload)
+		}
+	}
+}
+
+// chargeNode replaces the cached node with a copy whose requested resources changed by sign * resources
+func (n *Nodes) chargeNode(name string, resources Resources, sign int64) {
+	savedNode, err := n.findNodeByName(name)
+	if err != nil {
+		return
+	}
+
+	updatedNode := cloneNode(savedNode)
+	updatedNode.Requested.CPU += sign * resources.CPU
+	updatedNode.Requested.Memory += sign * resources.Memory
+	n.replaceNode(updatedNode)
+}
+
+func (n *Nodes) requestedOn(node string) Resources {
+	requested := Resources{}
+
+	for _, tracked := range n.workloads {
+		if tracked.node == node {
+			requested.CPU += tracked.resources.CPU
+			requested.Memory += tracked.resources.Memory
+		}
+	}
+
+	return requested
+}
@@ -0,0 +1,267 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+	"math/bits"
+	"sort"
+)
+
+// ErrNoVictims is returned when evicting lower priority workloads can't make room for the workload
+var ErrNoVictims = errors.New("no lower priority workloads can be evicted to fit the workload")
+
+// maxExactVictims bounds the lower priority workloads of a node searched exhaustively for the smallest set of victims,
+// nodes running more of them are searched greedily
+const maxExactVictims = 16
+
+// Preemption is the node a workload fits on once the victims running there are evicted
+type Preemption struct {
+	// NominatedNode is the node to schedule the workload to once the victims are deleted
+	NominatedNode *nodes.Node
+
+	// Victims lists the lower priority workloads to evict by increasing priority, empty if the workload fits already
+	Victims []*algorithms.Workload
+}
+
+// Preempt finds the smallest set of lower priority workloads to evict so the workload fits on a node
+// Only the nodes the algorithm rejected for lacking cpu or memory are nominated, so a workload requiring a location
+// only preempts workloads running there. The node needing the fewest victims is nominated, then the one whose victims
+// have the lowest priorities
+// Nothing is evicted nor assumed, the victims must be evicted and deleted with DeleteWorkload before scheduling
+// the workload again. Errors other than lacking resources are returned as is, ErrNoVictims if evicting is not enough
+func (s *Scheduler) Preempt(workload *algorithms.Workload) (*Preemption, error) {
+	node, _, err := s.getNode(workload)
+	if err == nil {
+		return &Preemption{NominatedNode: node, Victims: []*algorithms.Workload{}}, nil
+	}
+
+	var insufficient *algorithms.InsufficientResourcesError
+	if !errors.As(err, &insufficient) {
+		return nil, err
+	}
+
+	cached := make(map[string]*nodes.Node)
+	for _, node := range s.inodes.GetAllNodes() {
+		cached[node.Name] = node
+	}
+
+	var best *preemptionCandidate
+	for _, rejected := range insufficient.Rejected {
+		node, ok := cached[rejected.Node]
+		if !ok || !lacksResourcesOnly(rejected.Reasons) {
+			continue
+		}
+
+		// nodes rejected at several fallback levels are only considered once
+		delete(cached, rejected.Node)
+
+		if candidate := s.selectVictims(workload, node); candidate != nil && (best == nil || candidate.betterThan(best)) {
+			best = candidate
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoVictims, err)
+	}
+
+	victims := make([]*algorithms.Workload, len(best.victims))
+	for i, victim := range best.victims {
+		victims[i] = victim.workload
+	}
+
+	return &Preemption{NominatedNode: best.node, Victims: victims}, nil
+}
+
+// Unexported
+
+// victim is a lower priority workload running on a node and the resources it is charged there
+type victim struct {
+	workload  *algorithms.Workload
+	resources nodes.Resources
+}
+
+// preemptionCandidate is a node the workload fits on once its victims are evicted
+type preemptionCandidate struct {
+	node    *nodes.Node
+	victims []victim
+}
+
+// betterThan prefers fewer victims, then victims of lower priority
+func (c *preemptionCandidate) betterThan(other *preemptionCandidate) bool {
+	return lessVictims(c.victims, other.victims)
+}
+
+// selectVictims returns the node along with the smallest set of its lower priority workloads to evict for the workload
+// to fit, nil if evicting them all is not enough
+func (s *Scheduler) selectVictims(workload *algorithms.Workload, node *nodes.Node) *preemptionCandidate {
+	requested := nodes.Resources{}
+	preemptible := make([]victim, 0)
+
+	s.mutex.Lock()
+	for _, charged := range s.inodes.GetNodeWorkloads(node.Name) {
+		requested.CPU += charged.Resources.CPU
+		requested.Memory += charged.Resources.Memory
+
+		// workloads charged but unknown to the scheduler have no known priority and are never preempted
+		if tracked, ok := s.workloads[charged.Name]; ok && tracked.Priority < workload.Priority {
+			preemptible = append(preemptible, victim{workload: tracked, resources: charged.Resources})
+		}
+	}
+	s.mutex.Unlock()
+
+	missing := nodes.Resources{
+		CPU:    missingResource(workload.CPU, node.CPU, requested.CPU),
+		Memory: missingResource(workload.Memory, node.Memory, requested.Memory),
+	}
+
+	sort.SliceStable(preemptible, func(i, j int) bool {
+		if preemptible[i].workload.Priority != preemptible[j].workload.Priority {
+			return preemptible[i].workload.Priority < preemptible[j].workload.Priority
+		}
+
+		return preemptible[i].workload.Name < preemptible[j].workload.Name
+	})
+
+	var victims []victim
+	if len(preemptible) <= maxExactVictims {
+		victims = smallestVictims(preemptible, missing)
+	} else {
+		victims = greedyVictims(preemptible, missing)
+	}
+
+	if victims == nil {
+		return nil
+	}
+
+	return &preemptionCandidate{node: node, victims: victims}
+}
+
+// smallestVictims tries every subset of the preemptible workloads, sorted by increasing priority,
+// and returns the smallest one freeing the missing resources, nil if none does
+func smallestVictims(preemptible []victim, missing nodes.Resources) []victim {
+	var best []victim
+
+	for mask := uint32(0); mask < 1<<uint(len(preemptible)); mask++ {
+		size := bits.OnesCount32(mask)
+		if best != nil && size > len(best) {
+			continue
+		}
+
+		freed := nodes.Resources{}
+		for i := range preemptible {
+			if mask&(1<<uint(i)) != 0 {
+				freed.CPU += preemptible[i].resources.CPU
+				freed.Memory += preemptible[i].resources.Memory
+			}
+		}
+
+		if freed.CPU < missing.CPU || freed.Memory < missing.Memory {
+			continue
+		}
+
+		subset := make([]victim, 0, size)
+		for i := range preemptible {
+			if mask&(1<<uint(i)) != 0 {
+				subset = append(subset, preemptible[i])
+			}
+		}
+
+		if best == nil || lessVictims(subset, best) {
+			best = subset
+		}
+	}
+
+	return best
+}
+
+// greedyVictims evicts the preemptible workloads, sorted by increasing priority, until the missing resources are freed,
+// then spares the highest priority ones that don't need to be evicted after all, nil if evicting all is not enough
+func greedyVictims(preemptible []victim, missing nodes.Resources) []victim {
+	victims := make([]victim, 0)
+	for _, candidate := range preemptible {
+		if frees(victims, missing) {
+			break
+		}
+
+		victims = append(victims, candidate)
+	}
+
+	if !frees(victims, missing) {
+		return nil
+	}
+
+	for i := len(victims) - 1; i >= 0; i-- {
+		spared := append(append(make([]victim, 0, len(victims)-1), victims[:i]...), victims[i+1:]...)
+		if frees(spared, missing) {
+			victims = spared
+		}
+	}
+
+	return victims
+}
+
+// frees reports whether evicting the victims frees the missing resources
+func frees(victims []victim, missing nodes.Resources) bool {
+	freed := nodes.Resources{}
+	for _, victim := range victims {
+		freed.CPU += victim.resources.CPU
+		freed.Memory += victim.resources.Memory
+	}
+
+	return freed.CPU >= missing.CPU && freed.Memory >= missing.Memory
+}
+
+// lessVictims prefers fewer victims, then a lower highest priority, then a lower sum of priorities
+func lessVictims(a []victim, b []victim) bool {
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+
+	highestA, sumA := victimPriorities(a)
+	highestB, sumB := victimPriorities(b)
+
+	if highestA != highestB {
+		return highestA < highestB
+	}
+
+	return sumA < sumB
+}
+
+func victimPriorities(victims []victim) (int32, int64) {
+	var highest int32
+	var sum int64
+
+	for i, victim := range victims {
+		if i == 0 || victim.workload.Priority > highest {
+			highest = victim.workload.Priority
+		}
+
+		sum += int64(victim.workload.Priority)
+	}
+
+	return highest, sum
+}
+
+// missingResource returns how much of a resource must be freed on the node for the request to fit,
+// nothing for resources the workload doesn't request
+func missingResource(request int64, capacity int64, requested int64) int64 {
+	if request == 0 {
+		return 0
+	}
+
+	return request + requested - capacity
+}
+
+// lacksResourcesOnly reports whether a node was only rejected for lacking cpu or memory,
+// so evicting workloads from it may make room
+func lacksResourcesOnly(reasons []string) bool {
+	for _, reason := range reasons {
+		if reason != "cpu" && reason != "memory" {
+			return false
+		}
+	}
+
+	return len(reasons) > 0
+}
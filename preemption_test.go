@@ -0,0 +1,155 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/labels"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newTestLocatedNode(name string, city string, country string) *nodes.Node {
+	node := newTestNode(name)
+	node.Labels[labels.NodeCity] = city
+	node.Labels[labels.NodeCountry] = country
+	node.Labels[labels.NodeContinent] = "Europe"
+	node.CPU = 1000
+	node.Memory = 1000
+	return node
+}
+
+func newTestPreemptionScheduler(t *testing.T) IScheduler {
+	s, err := NewScheduler("location")
+	assert.NoError(t, err)
+
+	s.AddNode(newTestLocatedNode("Lisbon0", "Lisboa", "Portugal"))
+	s.AddNode(newTestLocatedNode("Lisbon1", "Lisboa", "Portugal"))
+	s.AddNode(newTestLocatedNode("Porto0", "Porto", "Portugal"))
+
+	for _, bound := range []struct {
+		node     string
+		workload *algorithms.Workload
+	}{
+		{"Lisbon0", &algorithms.Workload{Name: "low0", Priority: 1, CPU: 400}},
+		{"Lisbon0", &algorithms.Workload{Name: "low1", Priority: 1, CPU: 400}},
+		{"Lisbon0", &algorithms.Workload{Name: "lowest", CPU: 200}},
+		{"Lisbon1", &algorithms.Workload{Name: "big", Priority: 5, CPU: 800}},
+		{"Lisbon1", &algorithms.Workload{Name: "small", CPU: 200}},
+	} {
+		assert.NoError(t, s.BindWorkload(bound.workload, bound.node))
+	}
+
+	return s
+}
+
+func newTestLisbonWorkload(priority int32) *algorithms.Workload {
+	return &algorithms.Workload{
+		Name:     "preemptor",
+		Labels:   map[string]string{labels.WorkloadRequiredLocation: "Lisboa--"},
+		CPU:      500,
+		Priority: priority,
+	}
+}
+
+func victimNames(preemption *Preemption) []string {
+	names := make([]string, 0, len(preemption.Victims))
+	for _, victim := range preemption.Victims {
+		names = append(names, victim.Name)
+	}
+
+	return names
+}
+
+func TestPreempt(t *testing.T) {
+	s := newTestPreemptionScheduler(t)
+	workload := newTestLisbonWorkload(10)
+
+	_, err := s.ScheduleWorkload(workload)
+	assert.True(t, errors.Is(err, algorithms.ErrRequiredLocationUnsatisfied))
+
+	// a single victim beats two of lower priority, Porto0 has room but is not in Lisbon
+	preemption, err := s.Preempt(workload)
+	assert.NoError(t, err)
+	assert.Equal(t, "Lisbon1", preemption.NominatedNode.Name)
+	assert.Equal(t, []string{"big"}, victimNames(preemption))
+
+	for _, victim := range preemption.Victims {
+		s.DeleteWorkload(victim)
+	}
+
+	node, err := s.ScheduleWorkload(workload)
+	assert.NoError(t, err)
+	assert.Equal(t, "Lisbon1", node.Name)
+}
+
+func TestPreemptLowerPriorityOnly(t *testing.T) {
+	s := newTestPreemptionScheduler(t)
+
+	// big has the same priority so Lisbon0 is nominated, evicting the lowest priority pair
+	preemption, err := s.Preempt(newTestLisbonWorkload(5))
+	assert.NoError(t, err)
+	assert.Equal(t, "Lisbon0", preemption.NominatedNode.Name)
+	assert.Equal(t, []string{"lowest", "low0"}, victimNames(preemption))
+
+	_, err = s.Preempt(newTestLisbonWorkload(0))
+	assert.True(t, errors.Is(err, ErrNoVictims))
+	assert.Contains(t, err.Error(), algorithms.ErrRequiredLocationUnsatisfied.Error())
+}
+
+func TestPreemptNotNeeded(t *testing.T) {
+	s := newTestPreemptionScheduler(t)
+
+	preemption, err := s.Preempt(&algorithms.Workload{Name: "preemptor", CPU: 500})
+	assert.NoError(t, err)
+	assert.Equal(t, "Porto0", preemption.NominatedNode.Name)
+	assert.Empty(t, preemption.Victims)
+}
+
+func TestPreemptOtherErrors(t *testing.T) {
+	s := newTestPreemptionScheduler(t)
+
+	workload := newTestLisbonWorkload(10)
+	workload.Labels[labels.WorkloadRequiredLocation] = "Atlantis--"
+
+	_, err := s.Preempt(workload)
+	assert.True(t, errors.Is(err, algorithms.ErrUnknownLocation))
+	assert.False(t, errors.Is(err, ErrNoVictims))
+
+	// no node is big enough, even once empty
+	_, err = s.Preempt(&algorithms.Workload{Name: "preemptor", CPU: 2000, Priority: 10})
+	assert.True(t, errors.Is(err, ErrNoVictims))
+
+	// deleted workloads release their resources and are no longer victims
+	s.DeleteWorkload(&algorithms.Workload{Name: "big"})
+	preemption, err := s.Preempt(newTestLisbonWorkload(10))
+	assert.NoError(t, err)
+	assert.Equal(t, "Lisbon1", preemption.NominatedNode.Name)
+	assert.Empty(t, preemption.Victims)
+}
+
+func TestVictimSearches(t *testing.T) {
+	preemptible := make([]victim, 0)
+	for i := 0; i < maxExactVictims+4; i++ {
+		preemptible = append(preemptible, victim{
+			workload:  &algorithms.Workload{Name: fmt.Sprintf("Workload%d", i), Priority: int32(i)},
+			resources: nodes.Resources{CPU: int64(100 * (i%4 + 1))},
+		})
+	}
+
+	missing := nodes.Resources{CPU: 700}
+	exact := smallestVictims(preemptible[:maxExactVictims], missing)
+	assert.Equal(t, 2, len(exact))
+	assert.Equal(t, "Workload2", exact[0].workload.Name)
+	assert.Equal(t, "Workload3", exact[1].workload.Name)
+
+	// the greedy search spares the victims it doesn't need
+	greedy := greedyVictims(preemptible, missing)
+	assert.True(t, frees(greedy, missing))
+	assert.False(t, frees(greedy[1:], missing))
+
+	assert.Nil(t, smallestVictims(preemptible[:2], missing))
+	assert.Nil(t, greedyVictims(preemptible[:2], missing))
+	assert.NotNil(t, smallestVictims(preemptible[:2], nodes.Resources{}))
+}
@@ -0,0 +1,365 @@
+package scheduler
+
+import (
+	"container/heap"
+	"errors"
+	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultInitialBackoff is how long a workload waits before its first retry after failing to schedule
+const DefaultInitialBackoff = time.Second
+
+// DefaultMaxBackoff caps the exponential backoff of workloads failing to schedule repeatedly
+const DefaultMaxBackoff = 10 * time.Second
+
+// DefaultUnschedulableTimeout is how long an unschedulable workload waits for a cluster change
+// before being retried anyway
+const DefaultUnschedulableTimeout = 5 * time.Minute
+
+// ErrQueueClosed is returned when popping from a closed scheduling queue
+var ErrQueueClosed = errors.New("scheduling queue is closed")
+
+// SchedulingQueue holds the workloads pending scheduling, it is safe for concurrent use
+//
+// Active workloads are popped by decreasing priority, then in the order they were added.
+// Workloads failing to schedule wait for an exponential backoff before being active again.
+// Workloads that no node can take wait in the unschedulable pool until a cluster change, such as a node added
+// or updated with more capacity or other labels, may make them schedulable, or until the unschedulable timeout
+// passes after their backoff.
+type SchedulingQueue struct {
+	mutex sync.Mutex
+	wake  chan struct{}
+
+	active        activeHeap
+	backoff       map[string]*queuedWorkload
+	unschedulable map[string]*queuedWorkload
+	inFlight      map[string]*queuedWorkload
+
+	initialBackoff       time.Duration
+	maxBackoff           time.Duration
+	unschedulableTimeout time.Duration
+
+	// cycle counts popped workloads, moveCycle is the cycle of the last cluster change
+	// so workloads failing while a change happened are retried after backoff instead of waiting for another one
+	cycle     int64
+	moveCycle int64
+	sequence  int64
+	closed    bool
+	now       func() time.Time
+}
+
+// queuedWorkload is a pending workload and its scheduling attempts
+type queuedWorkload struct {
+	workload *algorithms.Workload
+	attempts int
+	sequence int64     // orders workloads of the same priority by arrival
+	cycle    int64     // cycle the workload was last popped at
+	readyAt  time.Time // end of the backoff
+}
+
+// NewSchedulingQueue creates an empty queue with the default backoff and unschedulable timeout
+func NewSchedulingQueue() *SchedulingQueue {
+	return &SchedulingQueue{
+		wake:                 make(chan struct{}, 1),
+		backoff:              make(map[string]*queuedWorkload),
+		unschedulable:        make(map[string]*queuedWorkload),
+		inFlight:             make(map[string]*queuedWorkload),
+		initialBackoff:       DefaultInitialBackoff,
+		maxBackoff:           DefaultMaxBackoff,
+		unschedulableTimeout: DefaultUnschedulableTimeout,
+		now:                  time.Now,
+	}
+}
+
+// SetBackoff changes the backoff of the first retry and its maximum
+func (q *SchedulingQueue) SetBackoff(initial time.Duration, max time.Duration) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.initialBackoff = initial
+	q.maxBackoff = max
+}
+
+// SetUnschedulableTimeout changes how long unschedulable workloads wait for a cluster change, zero waits forever
+func (q *SchedulingQueue) SetUnschedulableTimeout(timeout time.Duration) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.unschedulableTimeout = timeout
+}
+
+// Add makes the workload active, replacing any pending workload with the same name and resetting its backoff
+func (q *SchedulingQueue) Add(workload *algorithms.Workload) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.remove(workload.Name)
+	q.sequence++
+	heap.Push(&q.active, &queuedWorkload{workload: workload, sequence: q.sequence})
+	q.signal()
+}
+
+// Delete removes the pending workload with the given name
+func (q *SchedulingQueue) Delete(name string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	q.remove(name)
+}
+
+// Pop returns the active workload with the highest priority, waiting for one to be active
+// It returns ErrQueueClosed once the queue is closed
+func (q *SchedulingQueue) Pop() (*algorithms.Workload, error) {
+	for {
+		q.mutex.Lock()
+
+		if q.closed {
+			q.mutex.Unlock()
+			return nil, ErrQueueClosed
+		}
+
+		wait := q.flush()
+
+		if q.active.Len() > 0 {
+			queued := heap.Pop(&q.active).(*queuedWorkload)
+			q.cycle++
+			queued.cycle = q.cycle
+			queued.attempts++
+			q.inFlight[queued.workload.Name] = queued
+
+			if q.active.Len() > 0 {
+				// other waiting Pop calls may take the next ones
+				q.signal()
+			}
+
+			q.mutex.Unlock()
+			return queued.workload, nil
+		}
+
+		q.mutex.Unlock()
+
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-q.wake:
+			case <-timer.C:
+			}
+			timer.Stop()
+		} else {
+			<-q.wake
+		}
+	}
+}
+
+// Done forgets the popped workload once it is scheduled
+func (q *SchedulingQueue) Done(name string) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	delete(q.inFlight, name)
+}
+
+// Requeue puts back a popped workload that failed to schedule
+// Workloads no node could take are unschedulable until a cluster change, unless one happened since they were popped,
+// other failures are retried after backoff
+func (q *SchedulingQueue) Requeue(workload *algorithms.Workload, err error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	queued, ok := q.inFlight[workload.Name]
+	if !ok {
+		// deleted while being scheduled
+		return
+	}
+
+	delete(q.inFlight, workload.Name)
+	queued.workload = workload
+	queued.readyAt = q.now().Add(q.backoffDuration(queued.attempts))
+
+	if isUnschedulable(err) && q.moveCycle < queued.cycle {
+		q.unschedulable[workload.Name] = queued
+	} else {
+		q.backoff[workload.Name] = queued
+	}
+
+	q.signal()
+}
+
+// MoveAllToActive retries the unschedulable workloads after a cluster change,
+// workloads still backing off become active once their backoff expires
+func (q *SchedulingQueue) MoveAllToActive() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for name, queued := range q.unschedulable {
+		delete(q.unschedulable, name)
+		q.backoff[name] = queued
+	}
+
+	q.moveCycle = q.cycle
+	q.signal()
+}
+
+// Close makes Pop return ErrQueueClosed
+func (q *SchedulingQueue) Close() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if !q.closed {
+		q.closed = true
+		close(q.wake)
+	}
+}
+
+// Len returns the number of pending workloads, active, backing off or unschedulable
+func (q *SchedulingQueue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	return q.active.Len() + len(q.backoff) + len(q.unschedulable)
+}
+
+// Unschedulable returns the sorted names of the workloads waiting for a cluster change
+func (q *SchedulingQueue) Unschedulable() []string {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	names := make([]string, 0, len(q.unschedulable))
+	for name := range q.unschedulable {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Unexported
+
+// flush makes active the workloads whose backoff or unschedulable timeout expired
+// and returns how long until the next one expires, zero if none will
+func (q *SchedulingQueue) flush() time.Duration {
+	now := q.now()
+	var next time.Duration
+
+	expire := func(at time.Time) bool {
+		if !at.After(now) {
+			return true
+		}
+
+		if wait := at.Sub(now); next == 0 || wait < next {
+			next = wait
+		}
+
+		return false
+	}
+
+	for name, queued := range q.backoff {
+		if expire(queued.readyAt) {
+			delete(q.backoff, name)
+			heap.Push(&q.active, queued)
+		}
+	}
+
+	if q.unschedulableTimeout > 0 {
+		for name, queued := range q.unschedulable {
+			if expire(queued.readyAt.Add(q.unschedulableTimeout)) {
+				delete(q.unschedulable, name)
+				heap.Push(&q.active, queued)
+			}
+		}
+	}
+
+	return next
+}
+
+func (q *SchedulingQueue) remove(name string) {
+	for i, queued := range q.active {
+		if queued.workload.Name == name {
+			heap.Remove(&q.active, i)
+			break
+		}
+	}
+
+	delete(q.backoff, name)
+	delete(q.unschedulable, name)
+	delete(q.inFlight, name)
+}
+
+// backoffDuration doubles the initial backoff for every failed attempt up to the maximum
+func (q *SchedulingQueue) backoffDuration(attempts int) time.Duration {
+	duration := q.initialBackoff
+
+	for i := 1; i < attempts && duration < q.maxBackoff; i++ {
+		duration *= 2
+	}
+
+	if duration > q.maxBackoff {
+		return q.maxBackoff
+	}
+
+	return duration
+}
+
+// signal wakes up a waiting Pop
+func (q *SchedulingQueue) signal() {
+	if q.closed {
+		return
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// isUnschedulable reports whether the error means no node could take the workload,
+// so retrying is pointless until the cluster changes
+func isUnschedulable(err error) bool {
+	for _, target := range []error{
+		algorithms.ErrNoNodes,
+		algorithms.ErrInsufficientResources,
+		algorithms.ErrForbiddenLocation,
+		algorithms.ErrMaxLatencyUnsatisfied,
+		algorithms.ErrRequiredLocationUnsatisfied,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// activeHeap orders workloads by decreasing priority, then by arrival
+type activeHeap []*queuedWorkload
+
+func (h activeHeap) Len() int {
+	return len(h)
+}
+
+func (h activeHeap) Less(i, j int) bool {
+	if h[i].workload.Priority != h[j].workload.Priority {
+		return h[i].workload.Priority > h[j].workload.Priority
+	}
+
+	return h[i].sequence < h[j].sequence
+}
+
+func (h activeHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *activeHeap) Push(x interface{}) {
+	*h = append(*h, x.(*queuedWorkload))
+}
+
+func (h *activeHeap) Pop() interface{} {
+	old := *h
+	queued := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return queued
+}
@@ -0,0 +1,212 @@
+package scheduler
+
+import (
+	"errors"
+	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func newTestQueue() *SchedulingQueue {
+	q := NewSchedulingQueue()
+	q.SetBackoff(time.Millisecond, 4*time.Millisecond)
+	return q
+}
+
+// popWithin pops a workload, failing the test if none is active within the timeout
+func popWithin(t *testing.T, q *SchedulingQueue, timeout time.Duration) *algorithms.Workload {
+	popped := make(chan *algorithms.Workload, 1)
+	go func() {
+		workload, _ := q.Pop()
+		popped <- workload
+	}()
+
+	select {
+	case workload := <-popped:
+		return workload
+	case <-time.After(timeout):
+		q.Close()
+		t.Fatal("no workload popped in time")
+		return nil
+	}
+}
+
+func TestQueuePriority(t *testing.T) {
+	q := newTestQueue()
+	q.Add(&algorithms.Workload{Name: "low", Priority: -1})
+	q.Add(&algorithms.Workload{Name: "first"})
+	q.Add(&algorithms.Workload{Name: "high", Priority: 10})
+	q.Add(&algorithms.Workload{Name: "second"})
+	assert.Equal(t, 4, q.Len())
+
+	for _, expected := range []string{"high", "first", "second", "low"} {
+		workload, err := q.Pop()
+		assert.NoError(t, err)
+		assert.Equal(t, expected, workload.Name)
+	}
+
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestQueueAddReplaces(t *testing.T) {
+	q := newTestQueue()
+	q.Add(&algorithms.Workload{Name: "Workload0"})
+	q.Add(&algorithms.Workload{Name: "Workload1"})
+	q.Add(&algorithms.Workload{Name: "Workload0", Priority: 1, CPU: 10})
+	assert.Equal(t, 2, q.Len())
+
+	workload, _ := q.Pop()
+	assert.Equal(t, int64(10), workload.CPU)
+
+	q.Delete("Workload1")
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestQueueBackoff(t *testing.T) {
+	q := newTestQueue()
+	assert.Equal(t, time.Millisecond, q.backoffDuration(1))
+	assert.Equal(t, 2*time.Millisecond, q.backoffDuration(2))
+	assert.Equal(t, 4*time.Millisecond, q.backoffDuration(3))
+	assert.Equal(t, 4*time.Millisecond, q.backoffDuration(10))
+
+	q.Add(&algorithms.Workload{Name: "Workload0"})
+	workload, _ := q.Pop()
+
+	// failures no cluster change can fix are retried after backoff
+	q.Requeue(workload, errors.New("assume failed"))
+	assert.Equal(t, 1, q.Len())
+	assert.Empty(t, q.Unschedulable())
+
+	assert.Equal(t, "Workload0", popWithin(t, q, time.Second).Name)
+}
+
+func TestQueueUnschedulable(t *testing.T) {
+	q := newTestQueue()
+	q.Add(&algorithms.Workload{Name: "Workload0"})
+	workload, _ := q.Pop()
+
+	q.Requeue(workload, algorithms.NewInsufficientResourcesError(algorithms.NewDecision("test")))
+	assert.Equal(t, []string{"Workload0"}, q.Unschedulable())
+
+	popped := make(chan *algorithms.Workload, 1)
+	go func() {
+		workload, _ := q.Pop()
+		popped <- workload
+	}()
+
+	select {
+	case <-popped:
+		t.Fatal("unschedulable workload popped without a cluster change")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	q.MoveAllToActive()
+	assert.Empty(t, q.Unschedulable())
+
+	select {
+	case workload := <-popped:
+		assert.Equal(t, "Workload0", workload.Name)
+	case <-time.After(time.Second):
+		t.Fatal("workload not retried after a cluster change")
+	}
+}
+
+func TestQueueMoveWhileScheduling(t *testing.T) {
+	q := newTestQueue()
+	q.Add(&algorithms.Workload{Name: "Workload0"})
+	workload, _ := q.Pop()
+
+	// the node added while scheduling may take the workload so it only backs off
+	q.MoveAllToActive()
+	q.Requeue(workload, algorithms.ErrNoNodes)
+	assert.Empty(t, q.Unschedulable())
+	assert.Equal(t, "Workload0", popWithin(t, q, time.Second).Name)
+}
+
+func TestQueueUnschedulableTimeout(t *testing.T) {
+	q := newTestQueue()
+	q.SetUnschedulableTimeout(5 * time.Millisecond)
+	q.Add(&algorithms.Workload{Name: "Workload0"})
+	workload, _ := q.Pop()
+
+	q.Requeue(workload, algorithms.ErrNoNodes)
+	assert.Equal(t, []string{"Workload0"}, q.Unschedulable())
+	assert.Equal(t, "Workload0", popWithin(t, q, time.Second).Name)
+}
+
+func TestQueueDeleteWhileScheduling(t *testing.T) {
+	q := newTestQueue()
+	q.Add(&algorithms.Workload{Name: "Workload0"})
+	workload, _ := q.Pop()
+
+	q.Delete(workload.Name)
+	q.Requeue(workload, algorithms.ErrNoNodes)
+	assert.Equal(t, 0, q.Len())
+}
+
+func TestQueueClose(t *testing.T) {
+	q := newTestQueue()
+
+	closed := make(chan error, 1)
+	go func() {
+		_, err := q.Pop()
+		closed <- err
+	}()
+
+	q.Close()
+	q.Close()
+
+	select {
+	case err := <-closed:
+		assert.True(t, errors.Is(err, ErrQueueClosed))
+	case <-time.After(time.Second):
+		t.Fatal("Pop not unblocked by Close")
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	s, err := NewScheduler("location")
+	assert.NoError(t, err)
+	s.GetQueue().SetBackoff(time.Millisecond, time.Millisecond)
+
+	s.EnqueueWorkload(&algorithms.Workload{Name: "Workload0", CPU: 100})
+	workload, _, err := s.ScheduleNext()
+	assert.Equal(t, "Workload0", workload.Name)
+	assert.True(t, errors.Is(err, algorithms.ErrNoNodes))
+	assert.Equal(t, []string{"Workload0"}, s.GetQueue().Unschedulable())
+
+	node := newTestNode("Node0")
+	node.CPU = 50
+	s.AddNode(node)
+
+	_, _, err = s.ScheduleNext()
+	assert.True(t, errors.Is(err, algorithms.ErrInsufficientResources))
+
+	// an update not adding capacity nor changing labels doesn't retry it
+	s.UpdateNode(node, node)
+	assert.Equal(t, []string{"Workload0"}, s.GetQueue().Unschedulable())
+
+	bigger := newTestNode("Node0")
+	bigger.CPU = 200
+	s.UpdateNode(node, bigger)
+
+	workload, scheduled, err := s.ScheduleNext()
+	assert.NoError(t, err)
+	assert.Equal(t, "Workload0", workload.Name)
+	assert.Equal(t, "Node0", scheduled.Name)
+	assert.Equal(t, 0, s.GetQueue().Len())
+
+	s.GetQueue().Close()
+	_, _, err = s.ScheduleNext()
+	assert.True(t, errors.Is(err, ErrQueueClosed))
+}
+
+func TestMayScheduleMore(t *testing.T) {
+	node := &nodes.Node{Name: "Node0", CPU: 10, Memory: 10, Labels: map[string]string{"a": "1"}}
+
+	assert.False(t, mayScheduleMore(node, &nodes.Node{Name: "Node0", CPU: 5, Memory: 10, Labels: map[string]string{"a": "1"}}))
+	assert.True(t, mayScheduleMore(node, &nodes.Node{Name: "Node0", CPU: 10, Memory: 20, Labels: map[string]string{"a": "1"}}))
+	assert.True(t, mayScheduleMore(node, &nodes.Node{Name: "Node0", CPU: 10, Memory: 10, Labels: map[string]string{"a": "2"}}))
+}
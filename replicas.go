@@ -0,0 +1,166 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+)
+
+// ErrSpreadUnsatisfied is returned when no feasible node keeps the replicas within the spread policy maximum skew
+var ErrSpreadUnsatisfied = errors.New("no node satisfies the spread policy")
+
+// SpreadPolicy spreads the replicas of a workload across the locations of a topology level
+type SpreadPolicy struct {
+	// Level is the topology level replicas are spread over, such as nodes.LevelCity, nodes.LevelCountry
+	// or nodes.LevelContinent
+	Level string
+
+	// MaxSkew is the largest difference allowed between the number of replicas in any two eligible locations,
+	// at least 1
+	MaxSkew int
+}
+
+// Placement is a workload scheduled to a node
+type Placement struct {
+	Workload *algorithms.Workload
+	Node     *nodes.Node
+}
+
+// Validate checks the policy level exists in the given topology and its maximum skew is positive
+func (p SpreadPolicy) Validate(topology *nodes.Topology) error {
+	if p.MaxSkew < 1 {
+		return fmt.Errorf("spread policy maximum skew must be at least 1, got %d", p.MaxSkew)
+	}
+
+	if _, ok := topology.Level(p.Level); !ok {
+		return fmt.Errorf("spread policy level %q is not a topology level, must be one of %v", p.Level, topology.LevelNames())
+	}
+
+	return nil
+}
+
+// ScheduleReplicas schedules n replicas of the workload, named after it with their index as in "web-0",
+// and assumes their resources on the selected nodes
+// The locations eligible for replicas are the ones of the nodes matching the workload location labels, regardless
+// of their resources. Every replica is scheduled to the best ranked node with enough resources whose location keeps
+// its replicas count within MaxSkew of the least used eligible location, as Kubernetes topology spread constraints
+// do. Nodes without a location at the policy level never get replicas
+// It stops at the first replica that can't be placed and returns the replicas placed so far along with the error,
+// their resources stay assumed until they are bound or deleted
+func (s *Scheduler) ScheduleReplicas(workload *algorithms.Workload, n int, policy SpreadPolicy) ([]Placement, error) {
+	if n <= 0 {
+		return nil, fmt.Errorf("number of replicas must be positive, got %d", n)
+	}
+
+	if err := policy.Validate(s.inodes.GetTopology()); err != nil {
+		return nil, err
+	}
+
+	s.placing.RLock()
+	defer s.placing.RUnlock()
+
+	locations, err := s.spreadLocations(workload, policy)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, location := range locations {
+		counts[location] = 0
+	}
+
+	placements := make([]Placement, 0, n)
+	for i := 0; i < n; i++ {
+		replica := newReplica(workload, i)
+
+		node, err := s.placeReplica(replica, policy, locations, counts)
+		if err != nil {
+			return placements, fmt.Errorf("replica %s: %w", replica.Name, err)
+		}
+
+		counts[locations[node.Name]]++
+		placements = append(placements, Placement{Workload: replica, Node: node})
+	}
+
+	return placements, nil
+}
+
+// Unexported
+
+// spreadLocations returns the location at the policy level of every node matching the workload location labels
+func (s *Scheduler) spreadLocations(workload *algorithms.Workload, policy SpreadPolicy) (map[string]string, error) {
+	// resources are left out so full nodes still make their location eligible
+	eligible, err := s.rankAllNodes(&algorithms.Workload{Name: workload.Name, Labels: workload.Labels})
+	if err != nil {
+		return nil, err
+	}
+
+	level, _ := s.inodes.GetTopology().Level(policy.Level)
+	locations := make(map[string]string, len(eligible))
+
+	for _, ranked := range eligible {
+		value := ranked.Node.Labels[level.Label]
+		if value == "" {
+			continue
+		}
+
+		if code, err := s.inodes.ResolveLocation(level.Name, value); err == nil {
+			locations[ranked.Node.Name] = code
+		}
+	}
+
+	return locations, nil
+}
+
+// placeReplica assumes the replica on the best ranked node whose location stays within the maximum skew
+func (s *Scheduler) placeReplica(
+	replica *algorithms.Workload, policy SpreadPolicy, locations map[string]string, counts map[string]int,
+) (*nodes.Node, error) {
+	ranked, err := s.rankAllNodes(replica)
+	if err != nil {
+		return nil, err
+	}
+
+	minimum := -1
+	for _, count := range counts {
+		if minimum == -1 || count < minimum {
+			minimum = count
+		}
+	}
+
+	for _, candidate := range ranked {
+		location, ok := locations[candidate.Node.Name]
+		if !ok || counts[location]+1-minimum > policy.MaxSkew {
+			continue
+		}
+
+		// nodes may have been filled since they were ranked
+		if err := s.inodes.AssumeWorkload(replica.Name, candidate.Node.Name, workloadResources(replica)); err != nil {
+			continue
+		}
+
+		s.trackWorkload(replica)
+		return candidate.Node, nil
+	}
+
+	return nil, fmt.Errorf("%w: %d %s locations eligible with a maximum skew of %d",
+		ErrSpreadUnsatisfied, len(counts), policy.Level, policy.MaxSkew)
+}
+
+// rankAllNodes ranks every feasible node for the workload
+func (s *Scheduler) rankAllNodes(workload *algorithms.Workload) ([]algorithms.RankedNode, error) {
+	count := s.inodes.CountNodes()
+	if count == 0 {
+		return nil, algorithms.ErrNoNodes
+	}
+
+	return s.RankNodes(workload, count)
+}
+
+// newReplica copies the workload with the replica index appended to its name
+func newReplica(workload *algorithms.Workload, index int) *algorithms.Workload {
+	replica := *workload
+	replica.Name = fmt.Sprintf("%s-%d", workload.Name, index)
+	return &replica
+}
@@ -0,0 +1,110 @@
+package scheduler
+
+import (
+	"errors"
+	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/labels"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newTestReplicaScheduler(t *testing.T) IScheduler {
+	s, err := NewScheduler("location")
+	assert.NoError(t, err)
+
+	s.AddNode(newTestLocatedNode("Braga0", "Braga", "Portugal"))
+	s.AddNode(newTestLocatedNode("Porto0", "Porto", "Portugal"))
+	s.AddNode(newTestLocatedNode("Lisbon0", "Lisboa", "Portugal"))
+	s.AddNode(newTestLocatedNode("Madrid0", "Madrid", "Spain"))
+
+	return s
+}
+
+// placementCounts counts the placements by the value of the given node label
+func placementCounts(placements []Placement, label string) map[string]int {
+	counts := make(map[string]int)
+	for _, placement := range placements {
+		counts[placement.Node.Labels[label]]++
+	}
+
+	return counts
+}
+
+func TestScheduleReplicas(t *testing.T) {
+	s := newTestReplicaScheduler(t)
+	workload := &algorithms.Workload{Name: "web", CPU: 400}
+
+	placements, err := s.ScheduleReplicas(workload, 4, SpreadPolicy{Level: nodes.LevelCountry, MaxSkew: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"Portugal": 2, "Spain": 2}, placementCounts(placements, labels.NodeCountry))
+
+	names := make([]string, 0)
+	for _, placement := range placements {
+		names = append(names, placement.Workload.Name)
+	}
+	assert.Equal(t, []string{"web-0", "web-1", "web-2", "web-3"}, names)
+
+	// replicas resources are assumed, Madrid0 has no room left
+	assert.Equal(t, 2, len(s.GetNodeWorkloads("Madrid0")))
+	_, err = s.ScheduleWorkload(&algorithms.Workload{Name: "other", CPU: 400, Labels: map[string]string{
+		labels.WorkloadRequiredLocation: "-ES-",
+	}})
+	assert.True(t, errors.Is(err, algorithms.ErrInsufficientResources))
+}
+
+func TestScheduleReplicasCities(t *testing.T) {
+	s := newTestReplicaScheduler(t)
+
+	placements, err := s.ScheduleReplicas(&algorithms.Workload{Name: "web", CPU: 100}, 5, SpreadPolicy{Level: "City", MaxSkew: 1})
+	assert.NoError(t, err)
+
+	counts := placementCounts(placements, labels.NodeCity)
+	assert.Equal(t, 4, len(counts))
+	for _, count := range counts {
+		assert.True(t, count == 1 || count == 2)
+	}
+
+	// only the cities of the required country are eligible
+	placements, err = s.ScheduleReplicas(&algorithms.Workload{Name: "api", CPU: 100, Labels: map[string]string{
+		labels.WorkloadRequiredLocation: "-PT-",
+	}}, 3, SpreadPolicy{Level: nodes.LevelCity, MaxSkew: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"Braga": 1, "Porto": 1, "Lisboa": 1}, placementCounts(placements, labels.NodeCity))
+}
+
+func TestScheduleReplicasUnsatisfied(t *testing.T) {
+	s := newTestReplicaScheduler(t)
+
+	// Madrid0 fits a single replica so the fourth one would skew Portugal too much
+	placements, err := s.ScheduleReplicas(&algorithms.Workload{Name: "web", CPU: 600}, 4, SpreadPolicy{Level: nodes.LevelCountry, MaxSkew: 1})
+	assert.True(t, errors.Is(err, ErrSpreadUnsatisfied))
+	assert.Contains(t, err.Error(), "web-3")
+	assert.Equal(t, 3, len(placements))
+	assert.Equal(t, map[string]int{"Portugal": 2, "Spain": 1}, placementCounts(placements, labels.NodeCountry))
+
+	// a larger skew allows it
+	s = newTestReplicaScheduler(t)
+	placements, err = s.ScheduleReplicas(&algorithms.Workload{Name: "web", CPU: 600}, 4, SpreadPolicy{Level: nodes.LevelCountry, MaxSkew: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{"Portugal": 3, "Spain": 1}, placementCounts(placements, labels.NodeCountry))
+}
+
+func TestScheduleReplicasErrors(t *testing.T) {
+	s := newTestReplicaScheduler(t)
+	workload := &algorithms.Workload{Name: "web"}
+
+	_, err := s.ScheduleReplicas(workload, 0, SpreadPolicy{Level: nodes.LevelCity, MaxSkew: 1})
+	assert.Error(t, err)
+
+	_, err = s.ScheduleReplicas(workload, 1, SpreadPolicy{Level: nodes.LevelCity})
+	assert.Error(t, err)
+
+	_, err = s.ScheduleReplicas(workload, 1, SpreadPolicy{Level: "zone", MaxSkew: 1})
+	assert.Error(t, err)
+
+	empty, err := NewScheduler("location")
+	assert.NoError(t, err)
+	_, err = empty.ScheduleReplicas(workload, 1, SpreadPolicy{Level: nodes.LevelCity, MaxSkew: 1})
+	assert.True(t, errors.Is(err, algorithms.ErrNoNodes))
+}
@@ -1,53 +1,259 @@
 package scheduler
 
 import (
-	"errors"
 	"github.com/geolocate-orchestration/scheduler/algorithms"
-	"github.com/geolocate-orchestration/scheduler/algorithms/location"
-	"github.com/geolocate-orchestration/scheduler/algorithms/naivelocation"
-	"github.com/geolocate-orchestration/scheduler/algorithms/random"
+	// Built-in algorithms register themselves in the algorithms registry
+	_ "github.com/geolocate-orchestration/scheduler/algorithms/distance"
+	_ "github.com/geolocate-orchestration/scheduler/algorithms/latency"
+	_ "github.com/geolocate-orchestration/scheduler/algorithms/location"
+	_ "github.com/geolocate-orchestration/scheduler/algorithms/naivelocation"
+	_ "github.com/geolocate-orchestration/scheduler/algorithms/random"
+	"github.com/geolocate-orchestration/scheduler/labels"
 	"github.com/geolocate-orchestration/scheduler/nodes"
+	"reflect"
+	"time"
 )
 
 // NewScheduler create a new instance of the IScheduler interface
 func NewScheduler(algorithm string) (IScheduler, error) {
-	s := &Scheduler{}
+	return NewSchedulerWithOptions(algorithm, nil)
+}
+
+// NewSchedulerWithOptions create a new instance of the IScheduler interface
+// passing the given options to the registered algorithm factory
+func NewSchedulerWithOptions(algorithm string, options algorithms.Options) (IScheduler, error) {
+	return NewSchedulerWithTopology(algorithm, options, nodes.DefaultTopology())
+}
 
-	if !algorithmExists(algorithm) {
-		return nil, errors.New("selected algorithm does not exist")
+// NewSchedulerWithTopology works as NewSchedulerWithOptions but indexes nodes by the given topology levels
+func NewSchedulerWithTopology(algorithm string, options algorithms.Options, topology *nodes.Topology) (IScheduler, error) {
+	s := &Scheduler{
+		queue:     NewSchedulingQueue(),
+		workloads: make(map[string]*algorithms.Workload),
 	}
 
-	s.inodes = nodes.New()
-	s.algorithm = s.initAlgorithm(algorithm)
+	s.inodes = nodes.NewWithTopology(topology)
 
+	instance, err := algorithms.New(algorithm, s.inodes, options)
+	if err != nil {
+		return nil, err
+	}
+
+	s.algorithm = instance
 	return s, nil
 }
 
+// AvailableAlgorithms list all registered algorithms that can be used
+func AvailableAlgorithms() []string {
+	return algorithms.Registered()
+}
+
 // ScheduleWorkload select a node based on used algorithm for the given workload
+// The workload resources are assumed on the selected node until it is bound, deleted or the assumption expires,
+// workloads without a name can't be bound nor deleted so their resources are never charged
 func (s *Scheduler) ScheduleWorkload(workload *algorithms.Workload) (*nodes.Node, error) {
-	return s.algorithm.GetNode(workload)
+	node, _, err := s.ScheduleWorkloadWithDecision(workload)
+	return node, err
+}
+
+// ScheduleWorkloadWithDecision works as ScheduleWorkload but also returns how the node was selected
+// Algorithms that don't implement algorithms.Explainer only report the selected node
+func (s *Scheduler) ScheduleWorkloadWithDecision(workload *algorithms.Workload) (*nodes.Node, *algorithms.Decision, error) {
+	s.placing.RLock()
+	defer s.placing.RUnlock()
+
+	node, decision, err := s.getNode(workload)
+	if err != nil {
+		return nil, decision, err
+	}
+
+	if workload.Name == "" {
+		return node, decision, nil
+	}
+
+	if err := s.inodes.AssumeWorkload(workload.Name, node.Name, workloadResources(workload)); err != nil {
+		decision.Fail(err)
+		return nil, decision, err
+	}
+
+	s.trackWorkload(workload)
+	return node, decision, nil
+}
+
+// RankNodes returns up to n feasible nodes for the workload ordered from best to worst
+// Algorithms that don't implement algorithms.Ranker only return the node they select
+func (s *Scheduler) RankNodes(workload *algorithms.Workload, n int) ([]algorithms.RankedNode, error) {
+	if ranker, ok := s.algorithm.(algorithms.Ranker); ok {
+		return ranker.GetRankedNodes(workload, n)
+	}
+
+	node, decision, err := s.getNode(workload)
+	if err != nil {
+		return nil, err
+	}
+
+	return []algorithms.RankedNode{{Node: node, Level: decision.Level, Reason: decision.Reason}}, nil
+}
+
+// EnqueueWorkload adds the workload to the scheduling queue
+func (s *Scheduler) EnqueueWorkload(workload *algorithms.Workload) {
+	s.queue.Add(workload)
+}
+
+// ScheduleNext schedules the pending workload with the highest priority, waiting for one to be active
+// The workload is returned along with the scheduling error so the caller knows which one failed,
+// failed workloads are requeued and retried after backoff, or after a cluster change if no node could take them
+// Workloads whose binding fails should be deleted and enqueued again
+func (s *Scheduler) ScheduleNext() (*algorithms.Workload, *nodes.Node, error) {
+	workload, err := s.queue.Pop()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	node, err := s.ScheduleWorkload(workload)
+	if err != nil {
+		s.queue.Requeue(workload, err)
+		return workload, nil, err
+	}
+
+	s.queue.Done(workload.Name)
+	return workload, node, nil
+}
+
+// GetQueue returns the scheduling queue
+func (s *Scheduler) GetQueue() *SchedulingQueue {
+	return s.queue
+}
+
+// BindWorkload confirms the workload was bound to the given node by the orchestrator
+// Workloads without a name are not charged so there is nothing to confirm
+func (s *Scheduler) BindWorkload(workload *algorithms.Workload, nodeName string) error {
+	if workload.Name == "" {
+		return nil
+	}
+
+	if err := s.inodes.BindWorkload(workload.Name, nodeName, workloadResources(workload)); err != nil {
+		return err
+	}
+
+	s.trackWorkload(workload)
+	return nil
+}
+
+// UnbindWorkload releases the resources charged for the workload on the node it is assumed or bound to,
+// keeping it in the scheduling queue
+// Unschedulable workloads are retried as the released resources may fit them
+func (s *Scheduler) UnbindWorkload(workload *algorithms.Workload) {
+	s.inodes.ForgetWorkload(workload.Name)
+	s.untrackWorkload(workload.Name)
+	s.queue.MoveAllToActive()
+}
+
+// DeleteWorkload releases the resources charged for the workload and removes it from the scheduling queue
+func (s *Scheduler) DeleteWorkload(workload *algorithms.Workload) {
+	s.queue.Delete(workload.Name)
+	s.UnbindWorkload(workload)
+}
+
+// GetNodeWorkloads returns the workloads assumed or bound to the node through the scheduler, sorted by name
+func (s *Scheduler) GetNodeWorkloads(nodeName string) []*algorithms.Workload {
+	charged := s.inodes.GetNodeWorkloads(nodeName)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	workloads := make([]*algorithms.Workload, 0, len(charged))
+	for _, workload := range charged {
+		if tracked, ok := s.workloads[workload.Name]; ok {
+			workloads = append(workloads, tracked)
+		}
+	}
+
+	return workloads
+}
+
+// SetAssumeTTL changes how long a scheduled workload is charged against its node before being bound
+func (s *Scheduler) SetAssumeTTL(ttl time.Duration) {
+	s.inodes.SetAssumeTTL(ttl)
 }
 
 // AddNode adds information about a new cluster node to the algorithm
+// Unschedulable workloads are retried as the node may take them
 func (s *Scheduler) AddNode(node *nodes.Node) {
 	s.inodes.AddNode(node)
+	s.queue.MoveAllToActive()
 }
 
 // UpdateNode updates information about a cluster node in the algorithm
-func (s *Scheduler) UpdateNode(oldNode *nodes.Node, newNode *nodes.Node) {
+// Unschedulable workloads are retried if the node gained capacity or its labels changed
+// It returns the workloads displaced from the node, all of them if it moved to another country, otherwise the ones
+// whose required location it no longer satisfies, or all of them if it lost its labels and left the cache.
+// Displaced workloads are released so they can be scheduled again
+func (s *Scheduler) UpdateNode(oldNode *nodes.Node, newNode *nodes.Node) []*algorithms.Workload {
+	charged := s.GetNodeWorkloads(oldNode.Name)
 	s.inodes.UpdateNode(oldNode, newNode)
+
+	displaced := charged
+	if s.isCached(newNode.Name) {
+		displaced = s.displacedWorkloads(oldNode, newNode)
+	}
+
+	s.releaseWorkloads(displaced)
+
+	if len(displaced) > 0 || mayScheduleMore(oldNode, newNode) {
+		s.queue.MoveAllToActive()
+	}
+
+	return displaced
 }
 
 // DeleteNode deletes information about a cluster node from the algorithm
-func (s *Scheduler) DeleteNode(node *nodes.Node) {
+// It returns the workloads that were assumed or bound to the node, released so they can be scheduled again
+func (s *Scheduler) DeleteNode(node *nodes.Node) []*algorithms.Workload {
+	displaced := s.GetNodeWorkloads(node.Name)
+
 	s.inodes.DeleteNode(node)
+	s.releaseWorkloads(displaced)
+
+	return displaced
 }
 
 // Unexported
 
-func algorithmExists(algorithmName string) bool {
-	for _, algorithm := range AvailableAlgorithms {
-		if algorithm == algorithmName {
+func (s *Scheduler) getNode(workload *algorithms.Workload) (*nodes.Node, *algorithms.Decision, error) {
+	if explainer, ok := s.algorithm.(algorithms.Explainer); ok {
+		return explainer.GetNodeWithDecision(workload)
+	}
+
+	decision := algorithms.NewDecision(s.algorithm.GetName())
+	node, err := s.algorithm.GetNode(workload)
+	if err != nil {
+		decision.Fail(err)
+		return nil, decision, err
+	}
+
+	decision.Select(node, "", "algorithm does not explain its decisions")
+	return node, decision, nil
+}
+
+func (s *Scheduler) trackWorkload(workload *algorithms.Workload) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.workloads[workload.Name] = workload
+}
+
+func (s *Scheduler) untrackWorkload(name string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.workloads, name)
+}
+
+// isCached reports whether a node with the given name is in the cache
+func (s *Scheduler) isCached(nodeName string) bool {
+	for _, node := range s.inodes.GetAllNodes() {
+		if node.Name == nodeName {
 			return true
 		}
 	}
@@ -55,19 +261,59 @@ func algorithmExists(algorithmName string) bool {
 	return false
 }
 
-func (s *Scheduler) initAlgorithm(algorithmName string) algorithms.Algorithm {
-	var algorithm algorithms.Algorithm
+// displacedWorkloads returns the workloads on the updated node that must be rescheduled
+// Workloads whose required location can't be evaluated are kept, as they can't be placed elsewhere either
+func (s *Scheduler) displacedWorkloads(oldNode *nodes.Node, newNode *nodes.Node) []*algorithms.Workload {
+	if reflect.DeepEqual(oldNode.Labels, newNode.Labels) {
+		return []*algorithms.Workload{}
+	}
+
+	workloads := s.GetNodeWorkloads(newNode.Name)
+	if s.countryChanged(oldNode, newNode) {
+		return workloads
+	}
+
+	displaced := make([]*algorithms.Workload, 0)
+	for _, workload := range workloads {
+		required, err := algorithms.RequiredNodes(s.inodes, workload)
+		if err == nil && required != nil && !required[newNode.Name] {
+			displaced = append(displaced, workload)
+		}
+	}
+
+	return displaced
+}
+
+// countryChanged reports whether the node country label changed, comparing country codes when they resolve
+func (s *Scheduler) countryChanged(oldNode *nodes.Node, newNode *nodes.Node) bool {
+	oldCountry := oldNode.Labels[labels.NodeCountry]
+	newCountry := newNode.Labels[labels.NodeCountry]
 
-	switch algorithmName {
-	case "random":
-		algorithm = random.New(s.inodes)
-	case "naivelocation":
-		algorithm = naivelocation.New(s.inodes)
-	case "location":
-		algorithm = location.New(s.inodes)
-	default:
-		algorithm = random.New(s.inodes)
+	if oldCountry == newCountry {
+		return false
 	}
 
-	return algorithm
+	oldCode, oldErr := s.inodes.ResolveLocation(nodes.LevelCountry, oldCountry)
+	newCode, newErr := s.inodes.ResolveLocation(nodes.LevelCountry, newCountry)
+	return oldErr != nil || newErr != nil || oldCode != newCode
+}
+
+// releaseWorkloads forgets the workloads and their charges
+func (s *Scheduler) releaseWorkloads(workloads []*algorithms.Workload) {
+	for _, workload := range workloads {
+		s.inodes.ForgetWorkload(workload.Name)
+		s.untrackWorkload(workload.Name)
+	}
+}
+
+// mayScheduleMore reports whether the updated node may take workloads it couldn't take before
+func mayScheduleMore(oldNode *nodes.Node, newNode *nodes.Node) bool {
+	return newNode.CPU > oldNode.CPU || newNode.Memory > oldNode.Memory || !reflect.DeepEqual(oldNode.Labels, newNode.Labels)
+}
+
+func workloadResources(workload *algorithms.Workload) nodes.Resources {
+	return nodes.Resources{
+		CPU:    workload.CPU,
+		Memory: workload.Memory,
+	}
 }
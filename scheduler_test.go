@@ -0,0 +1,425 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"github.com/geolocate-orchestration/scheduler/algorithms"
+	"github.com/geolocate-orchestration/scheduler/labels"
+	"github.com/geolocate-orchestration/scheduler/nodes"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fixedAlgorithm struct {
+	inodes nodes.INodes
+	name   string
+}
+
+func (f *fixedAlgorithm) GetName() string {
+	return "fixed"
+}
+
+func (f *fixedAlgorithm) GetNode(*algorithms.Workload) (*nodes.Node, error) {
+	for _, node := range f.inodes.GetAllNodes() {
+		if node.Name == f.name {
+			return node, nil
+		}
+	}
+
+	return nil, errors.New("fixed node not found")
+}
+
+func init() {
+	algorithms.MustRegister("fixed", func(inodes nodes.INodes, options algorithms.Options) (algorithms.Algorithm, error) {
+		name, ok := options["node"].(string)
+		if !ok {
+			return nil, errors.New("fixed algorithm requires a 'node' option")
+		}
+
+		return &fixedAlgorithm{inodes: inodes, name: name}, nil
+	})
+}
+
+func newTestNode(name string) *nodes.Node {
+	return &nodes.Node{
+		Name:   name,
+		Labels: map[string]string{labels.Node: ""},
+	}
+}
+
+func TestAvailableAlgorithms(t *testing.T) {
+	available := AvailableAlgorithms()
+	assert.Contains(t, available, "location")
+	assert.Contains(t, available, "naivelocation")
+	assert.Contains(t, available, "random")
+	assert.Contains(t, available, "distance")
+	assert.Contains(t, available, "latency")
+	assert.Contains(t, available, "fixed")
+}
+
+func TestNewSchedulerBuiltIn(t *testing.T) {
+	for _, name := range []string{"location", "naivelocation", "random", "distance", "latency"} {
+		s, err := NewScheduler(name)
+		assert.NoError(t, err)
+		assert.Equal(t, name, s.(*Scheduler).algorithm.GetName())
+	}
+}
+
+func TestNewSchedulerUnknown(t *testing.T) {
+	_, err := NewScheduler("unknown")
+	assert.True(t, errors.Is(err, algorithms.ErrAlgorithmNotRegistered))
+}
+
+func TestNewSchedulerFactoryError(t *testing.T) {
+	_, err := NewScheduler("fixed")
+	assert.Error(t, err)
+}
+
+func TestNewSchedulerWithOptions(t *testing.T) {
+	s, err := NewSchedulerWithOptions("fixed", algorithms.Options{"node": "Node1"})
+	assert.NoError(t, err)
+
+	s.AddNode(newTestNode("Node0"))
+	s.AddNode(newTestNode("Node1"))
+
+	node, err := s.ScheduleWorkload(&algorithms.Workload{Name: "Workload0"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Node1", node.Name)
+}
+
+// TestConcurrentScheduling is meant to be run with the race detector enabled
+func TestConcurrentScheduling(t *testing.T) {
+	for _, name := range []string{"location", "naivelocation", "random", "distance", "latency"} {
+		s, err := NewScheduler(name)
+		assert.NoError(t, err)
+
+		var wg sync.WaitGroup
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := 0; i < 100; i++ {
+				node := newTestNode(fmt.Sprintf("Node%d", i%5))
+				node.Labels[labels.NodeCity] = "Braga"
+				node.Labels[labels.NodeCountry] = "Portugal"
+				node.CPU = int64(i)
+
+				moved := newTestNode(node.Name)
+				moved.Labels[labels.NodeCountry] = "Spain"
+				moved.CPU = int64(i)
+
+				s.AddNode(node)
+				s.UpdateNode(node, moved)
+				s.DeleteNode(moved)
+				s.AddNode(node)
+			}
+		}()
+
+		for r := 0; r < 4; r++ {
+			wg.Add(1)
+			go func(r int) {
+				defer wg.Done()
+
+				for i := 0; i < 100; i++ {
+					workload := &algorithms.Workload{
+						Name:   fmt.Sprintf("Workload%d-%d", r, i),
+						Labels: map[string]string{labels.WorkloadPreferredLocation: "Braga-PT-Europe"},
+						CPU:    1,
+					}
+
+					if node, err := s.ScheduleWorkload(workload); err == nil {
+						assert.NotEmpty(t, node.Name)
+						s.DeleteWorkload(workload)
+					}
+				}
+			}(r)
+		}
+
+		wg.Wait()
+	}
+}
+
+func newTestResourceScheduler(t *testing.T) IScheduler {
+	s, err := NewScheduler("location")
+	assert.NoError(t, err)
+
+	for _, name := range []string{"Node0", "Node1"} {
+		node := newTestNode(name)
+		node.CPU = 1000
+		node.Memory = 1000
+		s.AddNode(node)
+	}
+
+	return s
+}
+
+func TestScheduleWorkloadChargesResources(t *testing.T) {
+	s := newTestResourceScheduler(t)
+
+	first, err := s.ScheduleWorkload(&algorithms.Workload{Name: "Workload0", CPU: 600})
+	assert.NoError(t, err)
+
+	second, err := s.ScheduleWorkload(&algorithms.Workload{Name: "Workload1", CPU: 600})
+	assert.NoError(t, err)
+	assert.NotEqual(t, first.Name, second.Name)
+
+	_, err = s.ScheduleWorkload(&algorithms.Workload{Name: "Workload2", CPU: 600})
+	assert.Error(t, err)
+}
+
+func TestScheduleWorkloadFillsCluster(t *testing.T) {
+	for _, algorithm := range []string{"location", "naivelocation", "random"} {
+		// nodes are selected randomly so a full node would be selected half of the time
+		for i := 0; i < 20; i++ {
+			s, err := NewScheduler(algorithm)
+			assert.NoError(t, err)
+
+			for _, name := range []string{"Node0", "Node1"} {
+				node := newTestNode(name)
+				node.CPU = 1000
+				s.AddNode(node)
+			}
+
+			first, err := s.ScheduleWorkload(&algorithms.Workload{Name: "Workload0", CPU: 600})
+			assert.NoError(t, err, algorithm)
+
+			second, err := s.ScheduleWorkload(&algorithms.Workload{Name: "Workload1", CPU: 600})
+			assert.NoError(t, err, algorithm)
+
+			if first != nil && second != nil {
+				assert.NotEqual(t, first.Name, second.Name, algorithm)
+			}
+		}
+	}
+}
+
+func TestScheduleUnnamedWorkload(t *testing.T) {
+	s := newTestResourceScheduler(t)
+
+	// unnamed workloads are scheduled without being charged
+	for i := 0; i < 3; i++ {
+		node, err := s.ScheduleWorkload(&algorithms.Workload{CPU: 600})
+		assert.NoError(t, err)
+		assert.NoError(t, s.BindWorkload(&algorithms.Workload{CPU: 600}, node.Name))
+	}
+
+	assert.Empty(t, s.GetNodeWorkloads("Node0"))
+	assert.Empty(t, s.GetNodeWorkloads("Node1"))
+}
+
+func TestDeleteWorkloadReleasesResources(t *testing.T) {
+	s := newTestResourceScheduler(t)
+	workload := &algorithms.Workload{Name: "Workload0", CPU: 600}
+
+	node, err := s.ScheduleWorkload(workload)
+	assert.NoError(t, err)
+	assert.NoError(t, s.BindWorkload(workload, node.Name))
+	_, err = s.ScheduleWorkload(&algorithms.Workload{Name: "Workload1", CPU: 600})
+	assert.NoError(t, err)
+
+	s.DeleteWorkload(workload)
+
+	_, err = s.ScheduleWorkload(&algorithms.Workload{Name: "Workload2", CPU: 600})
+	assert.NoError(t, err)
+}
+
+func TestScheduleWorkloadAssumptionExpires(t *testing.T) {
+	s := newTestResourceScheduler(t)
+	s.SetAssumeTTL(time.Nanosecond)
+
+	for i := 0; i < 3; i++ {
+		_, err := s.ScheduleWorkload(&algorithms.Workload{Name: fmt.Sprintf("Workload%d", i), CPU: 600})
+		assert.NoError(t, err)
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestScheduleWorkloadWithDecision(t *testing.T) {
+	s := newTestResourceScheduler(t)
+
+	node, decision, err := s.ScheduleWorkloadWithDecision(&algorithms.Workload{Name: "Workload0", CPU: 600})
+	assert.NoError(t, err)
+	assert.Equal(t, "location", decision.Algorithm)
+	assert.Equal(t, node.Name, decision.Node)
+	assert.Equal(t, algorithms.LevelAny, decision.Level)
+
+	_, decision, err = s.ScheduleWorkloadWithDecision(&algorithms.Workload{Name: "Workload0", CPU: 1})
+	assert.Error(t, err)
+	assert.Equal(t, "", decision.Node)
+	assert.Equal(t, err.Error(), decision.Reason)
+}
+
+func TestScheduleWorkloadWithDecisionNotExplainer(t *testing.T) {
+	s, err := NewSchedulerWithOptions("fixed", algorithms.Options{"node": "Node0"})
+	assert.NoError(t, err)
+	s.AddNode(newTestNode("Node0"))
+
+	_, decision, err := s.ScheduleWorkloadWithDecision(&algorithms.Workload{Name: "Workload0"})
+	assert.NoError(t, err)
+	assert.Equal(t, "fixed", decision.Algorithm)
+	assert.Equal(t, "Node0", decision.Node)
+
+	s, err = NewSchedulerWithOptions("fixed", algorithms.Options{"node": "Node1"})
+	assert.NoError(t, err)
+
+	_, decision, err = s.ScheduleWorkloadWithDecision(&algorithms.Workload{Name: "Workload0"})
+	assert.Error(t, err)
+	assert.Equal(t, err.Error(), decision.Reason)
+}
+
+func TestRankNodes(t *testing.T) {
+	s := newTestResourceScheduler(t)
+
+	ranked, err := s.RankNodes(&algorithms.Workload{Name: "Workload0", CPU: 600}, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(ranked))
+	assert.NotEqual(t, ranked[0].Node.Name, ranked[1].Node.Name)
+	assert.Equal(t, algorithms.LevelAny, ranked[0].Level)
+
+	// ranking assumes no resources so the workload still fits both nodes
+	ranked, err = s.RankNodes(&algorithms.Workload{Name: "Workload0", CPU: 600}, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(ranked))
+
+	_, err = s.RankNodes(&algorithms.Workload{Name: "Workload0", CPU: 2000}, 1)
+	assert.True(t, errors.Is(err, algorithms.ErrInsufficientResources))
+}
+
+func TestRankNodesNotRanker(t *testing.T) {
+	s, err := NewSchedulerWithOptions("fixed", algorithms.Options{"node": "Node0"})
+	assert.NoError(t, err)
+	s.AddNode(newTestNode("Node0"))
+	s.AddNode(newTestNode("Node1"))
+
+	ranked, err := s.RankNodes(&algorithms.Workload{Name: "Workload0"}, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(ranked))
+	assert.Equal(t, "Node0", ranked[0].Node.Name)
+}
+
+func TestNewSchedulerWithTopology(t *testing.T) {
+	topology, err := nodes.NewTopology(
+		nodes.TopologyLevel{Name: "zone", Label: "node.geolocate.io/zone", Parent: "datacenter"},
+		nodes.TopologyLevel{Name: "datacenter", Label: "node.geolocate.io/datacenter"},
+	)
+	assert.NoError(t, err)
+
+	s, err := NewSchedulerWithTopology("location", nil, topology)
+	assert.NoError(t, err)
+
+	for i, zone := range []string{"z1", "z2", "z3"} {
+		node := newTestNode(fmt.Sprintf("Node%d", i))
+		node.Labels["node.geolocate.io/zone"] = zone
+		node.Labels["node.geolocate.io/datacenter"] = map[bool]string{true: "dc1", false: "dc2"}[i < 2]
+		s.AddNode(node)
+	}
+
+	workload := &algorithms.Workload{
+		Name:   "Workload0",
+		Labels: map[string]string{labels.WorkloadRequiredLocation: "datacenter = dc1 and zone != z1"},
+	}
+
+	node, err := s.ScheduleWorkload(workload)
+	assert.NoError(t, err)
+	assert.Equal(t, "Node1", node.Name)
+}
+
+func workloadNames(workloads []*algorithms.Workload) []string {
+	names := make([]string, 0, len(workloads))
+	for _, workload := range workloads {
+		names = append(names, workload.Name)
+	}
+
+	return names
+}
+
+func newTestRequiredWorkload(name string, required string) *algorithms.Workload {
+	workload := &algorithms.Workload{Name: name, CPU: 100}
+	if required != "" {
+		workload.Labels = map[string]string{labels.WorkloadRequiredLocation: required}
+	}
+
+	return workload
+}
+
+func TestUnbindWorkload(t *testing.T) {
+	s := newTestResourceScheduler(t)
+	workload := &algorithms.Workload{Name: "Workload0", CPU: 600}
+
+	assert.NoError(t, s.BindWorkload(workload, "Node0"))
+	assert.Equal(t, []string{"Workload0"}, workloadNames(s.GetNodeWorkloads("Node0")))
+	assert.Empty(t, s.GetNodeWorkloads("Node1"))
+
+	s.UnbindWorkload(workload)
+	assert.Empty(t, s.GetNodeWorkloads("Node0"))
+
+	node, err := s.ScheduleWorkload(workload)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Workload0"}, workloadNames(s.GetNodeWorkloads(node.Name)))
+}
+
+func TestUpdateNodeDisplacesWorkloads(t *testing.T) {
+	s, err := NewScheduler("location")
+	assert.NoError(t, err)
+
+	braga := newTestLocatedNode("Node0", "Braga", "Portugal")
+	s.AddNode(braga)
+
+	for _, workload := range []*algorithms.Workload{
+		newTestRequiredWorkload("Workload0", ""),
+		newTestRequiredWorkload("Workload1", "-PT-"),
+		newTestRequiredWorkload("Workload2", "Braga--"),
+		newTestRequiredWorkload("Workload3", "Atlantis--"),
+	} {
+		assert.NoError(t, s.BindWorkload(workload, "Node0"))
+	}
+
+	// neither labels nor country changes
+	bigger := newTestLocatedNode("Node0", "Braga", "Portugal")
+	bigger.CPU = 2000
+	assert.Empty(t, s.UpdateNode(braga, bigger))
+
+	relabeled := newTestLocatedNode("Node0", "Braga", "PT")
+	assert.Empty(t, s.UpdateNode(bigger, relabeled))
+
+	// the node no longer satisfies Braga, unknown locations can't be evaluated so they are kept
+	porto := newTestLocatedNode("Node0", "Porto", "Portugal")
+	assert.Equal(t, []string{"Workload2"}, workloadNames(s.UpdateNode(relabeled, porto)))
+	assert.Equal(t, []string{"Workload0", "Workload1", "Workload3"}, workloadNames(s.GetNodeWorkloads("Node0")))
+
+	// every workload is displaced when the node moves to another country
+	madrid := newTestLocatedNode("Node0", "Madrid", "Spain")
+	assert.Equal(t, []string{"Workload0", "Workload1", "Workload3"}, workloadNames(s.UpdateNode(porto, madrid)))
+	assert.Empty(t, s.GetNodeWorkloads("Node0"))
+	assert.Equal(t, nodes.Resources{}, s.(*Scheduler).inodes.GetAllNodes()[0].Requested)
+
+	// every workload is displaced when the node loses its labels and leaves the cache
+	assert.NoError(t, s.BindWorkload(newTestRequiredWorkload("Workload4", ""), "Node0"))
+	unlabeled := &nodes.Node{Name: "Node0", Labels: map[string]string{}}
+	assert.Equal(t, []string{"Workload4"}, workloadNames(s.UpdateNode(madrid, unlabeled)))
+	assert.Empty(t, s.UpdateNode(unlabeled, madrid))
+	assert.Empty(t, s.GetNodeWorkloads("Node0"))
+}
+
+func TestDeleteNodeDisplacesWorkloads(t *testing.T) {
+	s := newTestResourceScheduler(t)
+	workload := &algorithms.Workload{Name: "Workload0", CPU: 600}
+	assert.NoError(t, s.BindWorkload(workload, "Node0"))
+	assert.NoError(t, s.BindWorkload(&algorithms.Workload{Name: "Workload1", CPU: 600}, "Node1"))
+
+	assert.Equal(t, []string{"Workload0"}, workloadNames(s.DeleteNode(newTestNode("Node0"))))
+	assert.Empty(t, s.GetNodeWorkloads("Node0"))
+	assert.Empty(t, s.DeleteNode(newTestNode("Node2")))
+
+	// the displaced workload is released so it is rescheduled once room is made for it
+	_, err := s.ScheduleWorkload(workload)
+	assert.True(t, errors.Is(err, algorithms.ErrInsufficientResources))
+
+	s.UnbindWorkload(&algorithms.Workload{Name: "Workload1"})
+	node, err := s.ScheduleWorkload(workload)
+	assert.NoError(t, err)
+	assert.Equal(t, "Node1", node.Name)
+}
@@ -3,28 +3,84 @@ package scheduler
 import (
 	"github.com/geolocate-orchestration/scheduler/algorithms"
 	"github.com/geolocate-orchestration/scheduler/nodes"
+	"sync"
+	"time"
 )
 
-// AvailableAlgorithms list all package algorithms that can be used
-var AvailableAlgorithms = []string{"location", "naivelocation", "random"}
-
 // IScheduler exports all scheduler public methods
 type IScheduler interface {
 	// ScheduleWorkload returns a node selected from the chosen algorithm to bind the workload
+	// and assumes the workload resources on it
 	ScheduleWorkload(workload *algorithms.Workload) (*nodes.Node, error)
 
+	// ScheduleWorkloadWithDecision works as ScheduleWorkload but also returns how the node was selected
+	ScheduleWorkloadWithDecision(workload *algorithms.Workload) (*nodes.Node, *algorithms.Decision, error)
+
+	// RankNodes returns up to n feasible nodes for the workload ordered from best to worst, with their score and
+	// the fallback level they matched, no resources are assumed on them
+	RankNodes(workload *algorithms.Workload, n int) ([]algorithms.RankedNode, error)
+
+	// ScheduleReplicas schedules n replicas of the workload spread across the locations of a topology level
+	// and assumes their resources on the selected nodes
+	ScheduleReplicas(workload *algorithms.Workload, n int, policy SpreadPolicy) ([]Placement, error)
+
+	// ScheduleGroup schedules every workload of the group or none of them, assuming their resources on the
+	// selected nodes, a *GroupError names the member that could not be placed
+	ScheduleGroup(workloads []*algorithms.Workload) ([]Placement, error)
+
+	// Preempt finds the smallest set of lower priority workloads to evict so the workload fits on a node it was
+	// rejected from for lacking resources, and returns them along with that nominated node
+	Preempt(workload *algorithms.Workload) (*Preemption, error)
+
+	// EnqueueWorkload adds the workload to the scheduling queue, replacing any pending workload with the same name
+	EnqueueWorkload(workload *algorithms.Workload)
+
+	// ScheduleNext pops the pending workload with the highest priority, waiting for one, and schedules it
+	// Workloads failing to schedule are requeued, it returns ErrQueueClosed once the queue is closed
+	ScheduleNext() (*algorithms.Workload, *nodes.Node, error)
+
+	// GetQueue returns the scheduling queue, to tune its backoff or close it
+	GetQueue() *SchedulingQueue
+
+	// BindWorkload confirms the workload is bound to the Node so its resources stay charged
+	BindWorkload(workload *algorithms.Workload, nodeName string) error
+
+	// UnbindWorkload releases the workload resources from the Node it was scheduled or bound to
+	UnbindWorkload(workload *algorithms.Workload)
+
+	// DeleteWorkload releases the workload resources from the Node it was scheduled to
+	// and removes it from the scheduling queue
+	DeleteWorkload(workload *algorithms.Workload)
+
+	// GetNodeWorkloads returns the workloads scheduled or bound to the Node
+	GetNodeWorkloads(nodeName string) []*algorithms.Workload
+
+	// SetAssumeTTL sets how long scheduled but not yet bound workloads keep their resources charged
+	SetAssumeTTL(ttl time.Duration)
+
 	// AddNode inserts new possible Node in the algorithm
 	AddNode(node *nodes.Node)
 
-	// UpdateNode replaces Node information in the algorithm
-	UpdateNode(oldNode *nodes.Node, newNode *nodes.Node)
+	// UpdateNode replaces Node information in the algorithm and returns the workloads to reschedule,
+	// all of the Node workloads if it moved to another country, else the ones whose required location it no longer
+	// satisfies
+	UpdateNode(oldNode *nodes.Node, newNode *nodes.Node) []*algorithms.Workload
 
-	// DeleteNode removes Node from the algorithm
-	DeleteNode(node *nodes.Node)
+	// DeleteNode removes Node from the algorithm and returns its workloads to reschedule
+	DeleteNode(node *nodes.Node) []*algorithms.Workload
 }
 
 // Scheduler has algorithm information
 type Scheduler struct {
 	inodes    nodes.INodes
 	algorithm algorithms.Algorithm
+	queue     *SchedulingQueue
+
+	// workloads holds the scheduled or bound workloads by name, to list the workloads of each node
+	mutex     sync.Mutex
+	workloads map[string]*algorithms.Workload
+
+	// placing is held for reading while placing workloads and for writing while placing a group,
+	// so no placement sees the capacity of a group that is partially placed
+	placing sync.RWMutex
 }